@@ -0,0 +1,96 @@
+package env
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sbreitf1/go-jcrypt"
+)
+
+const (
+	bundleVersion = 1
+)
+
+// bundle is the file format to share environments across machines. All environments including their passwords are encrypted with the passphrase of the bundle.
+type bundle struct {
+	Version      int                 `json:"version"`
+	Environments []bundleEnvironment `json:"environments" jcrypt:"aes"`
+}
+
+type bundleEnvironment struct {
+	Name        string      `json:"name"`
+	Environment Environment `json:"environment"`
+}
+
+// ExportEnvironments writes the given environments or all environments if envNames is empty to a bundle file encrypted with passphrase.
+func (e *Reader) ExportEnvironments(file string, passphrase []byte, envNames ...string) error {
+	if len(envNames) == 0 {
+		envFiles, err := e.GetEnvironmentFiles()
+		if err != nil {
+			return err
+		}
+		for _, fi := range envFiles {
+			envNames = append(envNames, envNameFromFile(fi.Name()))
+		}
+	}
+	if len(envNames) == 0 {
+		return fmt.Errorf("no environments specified")
+	}
+
+	b := bundle{Version: bundleVersion}
+	for _, envName := range envNames {
+		var env Environment
+		if err := e.readEnvironmentFile(e.getEnvFilePath(envName), &env); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("environment %q does not exist", envName)
+			}
+			return fmt.Errorf("failed to read environment %q: %w", envName, err)
+		}
+		b.Environments = append(b.Environments, bundleEnvironment{Name: envName, Environment: env})
+	}
+
+	data, err := jcrypt.Marshal(&b, &jcrypt.Options{GetKeyHandler: jcrypt.StaticKey(passphrase)})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, data, 0600)
+}
+
+// ImportEnvironments reads a bundle file encrypted with passphrase and creates all contained environments. Environments that already exist are skipped and returned as skipped.
+func (e *Reader) ImportEnvironments(file string, passphrase []byte) (imported, skipped []string, err error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var b bundle
+	if err := jcrypt.Unmarshal(data, &b, &jcrypt.Options{GetKeyHandler: jcrypt.StaticKey(passphrase)}); err != nil {
+		if jcrypt.IsWrongPassword(err) {
+			return nil, nil, fmt.Errorf("wrong passphrase for %s", file)
+		}
+		return nil, nil, err
+	}
+	if b.Version != bundleVersion {
+		return nil, nil, fmt.Errorf("unsupported environment bundle version %d", b.Version)
+	}
+
+	for _, be := range b.Environments {
+		envFile := e.getEnvFilePath(be.Name)
+		exists, err := isFile(envFile)
+		if err != nil {
+			return imported, skipped, err
+		}
+		if exists {
+			skipped = append(skipped, be.Name)
+			continue
+		}
+
+		if err := e.writeEnvironmentFile(envFile, &be.Environment); err != nil {
+			return imported, skipped, fmt.Errorf("failed to save environment %q: %w", be.Name, err)
+		}
+		imported = append(imported, be.Name)
+	}
+
+	return imported, skipped, nil
+}
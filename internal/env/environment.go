@@ -15,10 +15,22 @@ const (
 
 // Environment represents an environment with address, user, password, and insecure flag.
 type Environment struct {
+	// Name denotes the name of the environment file. It is not stored in the file itself.
+	Name     string `json:"-"`
 	Address  string `json:"address"`
 	User     string `json:"user"`
 	Password string `json:"pass" jcrypt:"aes"`
 	Insecure bool   `json:"insecure"`
+	// ClientCert denotes a PEM file with the client certificate presented to the RRI server.
+	ClientCert string `json:"client-cert,omitempty"`
+	// ClientKey denotes a PEM file with the private key of ClientCert. The key is read from ClientCert if empty.
+	ClientKey string `json:"client-key,omitempty"`
+	// Output denotes the output mode used unless --output is set.
+	Output string `json:"output,omitempty"`
+	// ColorTheme denotes the syntax highlighting style or none to disable colors.
+	ColorTheme string `json:"color-theme,omitempty"`
+	// Protected requires confirmation before destructive queries are sent, e.g. for the production registry.
+	Protected bool `json:"protected,omitempty"`
 }
 
 func (e Environment) HasCredentials() bool {
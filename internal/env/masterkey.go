@@ -0,0 +1,343 @@
+package env
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/sbreitf1/go-jcrypt"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	keyFileName      = "env-key"
+	keyCheckFileName = "env-key-check"
+	keyCheckValue    = "rri-client"
+	keyCacheFileName = "rri-client-master-key"
+
+	passphraseIterations = 200000
+	passphraseAttempts   = 3
+)
+
+// Protection denotes how environment passwords are encrypted.
+type Protection string
+
+const (
+	// ProtectionNone encrypts passwords with an empty key.
+	ProtectionNone Protection = "none"
+	// ProtectionPassphrase encrypts passwords with a key derived from a master passphrase.
+	ProtectionPassphrase Protection = "passphrase"
+	// ProtectionKeyFile encrypts passwords with a random key stored in a file that is only readable by the user.
+	ProtectionKeyFile Protection = "key-file"
+)
+
+// keyCheck is stored for passphrase protection to detect wrong passphrases before decrypting environments.
+type keyCheck struct {
+	Salt  string `json:"salt"`
+	Check string `json:"check" jcrypt:"aes"`
+}
+
+type keyCache struct {
+	Key     string    `json:"key"`
+	Expires time.Time `json:"expires"`
+}
+
+// MasterKey provides the key to encrypt environment passwords. The key is determined once per session, use Key as Reader.KeySource.
+type MasterKey struct {
+	dir string
+	// KeyFile denotes the file the key is read from for key file protection.
+	KeyFile string
+	// ReadPassphrase prompts for the master passphrase.
+	ReadPassphrase func(prompt string) (string, error)
+	// CacheTTL enables caching the key derived from the master passphrase for the given duration, so subsequent sessions do not prompt again. Disabled if zero.
+	CacheTTL time.Duration
+	key      []byte
+}
+
+// NewMasterKey returns a MasterKey for the configuration directory of reader.
+func NewMasterKey(reader *Reader) *MasterKey {
+	return &MasterKey{
+		dir:     reader.Dir(),
+		KeyFile: filepath.Join(reader.Dir(), keyFileName),
+	}
+}
+
+// Protection returns the configured protection of environment passwords.
+func (m *MasterKey) Protection() (Protection, error) {
+	if exists, err := isFile(m.KeyFile); err != nil || exists {
+		return ProtectionKeyFile, err
+	}
+	if exists, err := isFile(m.keyCheckFile()); err != nil || exists {
+		return ProtectionPassphrase, err
+	}
+	return ProtectionNone, nil
+}
+
+// Key returns the key to encrypt environment passwords and prompts for the master passphrase if required.
+func (m *MasterKey) Key() ([]byte, error) {
+	if m.key != nil {
+		return m.key, nil
+	}
+
+	protection, err := m.Protection()
+	if err != nil {
+		return nil, err
+	}
+
+	switch protection {
+	case ProtectionKeyFile:
+		m.key, err = readKeyFile(m.KeyFile)
+
+	case ProtectionPassphrase:
+		m.key, err = m.unlock()
+
+	default:
+		m.key = []byte{}
+	}
+
+	return m.key, err
+}
+
+func (m *MasterKey) unlock() ([]byte, error) {
+	data, err := os.ReadFile(m.keyCheckFile())
+	if err != nil {
+		return nil, err
+	}
+	salt, err := readKeyCheckSalt(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if key := m.readCache(); key != nil && isValidKey(data, key) {
+		return key, nil
+	}
+
+	if m.ReadPassphrase == nil {
+		return nil, fmt.Errorf("environment passwords are protected by a master passphrase")
+	}
+
+	for i := 0; i < passphraseAttempts; i++ {
+		passphrase, err := m.ReadPassphrase("Master passphrase> ")
+		if err != nil {
+			return nil, err
+		}
+
+		key := derivePassphraseKey(passphrase, salt)
+		if isValidKey(data, key) {
+			if err := m.writeCache(key); err != nil {
+				// the cache is optional, the passphrase is prompted again in the next session
+				console.Printlnf("WARNING: failed to cache master key: %s", err.Error()) //nolint
+			}
+			return key, nil
+		}
+		console.Printlnf("wrong master passphrase") //nolint
+	}
+
+	return nil, fmt.Errorf("wrong master passphrase")
+}
+
+// Change prepares a new protection for environment passwords. It returns the new key and a function that persists the protection after all environments have been encrypted with the new key.
+func (m *MasterKey) Change(protection Protection, passphrase string) ([]byte, func() error, error) {
+	var key []byte
+	var persist func() error
+
+	switch protection {
+	case ProtectionNone:
+		key = []byte{}
+		persist = func() error { return nil }
+
+	case ProtectionKeyFile:
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		key = []byte(hex.EncodeToString(raw))
+		persist = func() error {
+			return writePrivateFile(m.KeyFile, key)
+		}
+
+	case ProtectionPassphrase:
+		if len(passphrase) == 0 {
+			return nil, nil, fmt.Errorf("master passphrase must not be empty")
+		}
+
+		salt := make([]byte, 32)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, nil, err
+		}
+		key = derivePassphraseKey(passphrase, salt)
+
+		data, err := jcrypt.Marshal(&keyCheck{Salt: hex.EncodeToString(salt), Check: keyCheckValue}, &jcrypt.Options{GetKeyHandler: jcrypt.StaticKey(key)})
+		if err != nil {
+			return nil, nil, err
+		}
+		persist = func() error {
+			return writePrivateFile(m.keyCheckFile(), data)
+		}
+
+	default:
+		return nil, nil, fmt.Errorf("unknown protection %q", protection)
+	}
+
+	return key, func() error {
+		// remove files of the previous protection, custom key files are kept
+		for _, file := range []string{filepath.Join(m.dir, keyFileName), m.keyCheckFile()} {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := m.removeCache(); err != nil {
+			return err
+		}
+
+		if err := persist(); err != nil {
+			return err
+		}
+
+		m.key = key
+		return nil
+	}, nil
+}
+
+func (m *MasterKey) keyCheckFile() string {
+	return filepath.Join(m.dir, keyCheckFileName)
+}
+
+// cacheFile returns the file the derived key is cached in. It is only stored in a directory that is private to the user, preferably the runtime directory which is cleared on logout.
+func (m *MasterKey) cacheFile() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if len(dir) == 0 {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("rri-client-%d", os.Getuid()))
+		if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+			return "", err
+		}
+	}
+
+	if err := checkPrivateDir(dir); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, keyCacheFileName), nil
+}
+
+func (m *MasterKey) readCache() []byte {
+	if m.CacheTTL <= 0 {
+		return nil
+	}
+
+	file, err := m.cacheFile()
+	if err != nil {
+		return nil
+	}
+	data, err := readPrivateCacheFile(file)
+	if err != nil {
+		return nil
+	}
+
+	var cache keyCache
+	if err := json.Unmarshal(data, &cache); err != nil || time.Now().After(cache.Expires) {
+		return nil
+	}
+
+	key, err := hex.DecodeString(cache.Key)
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+func (m *MasterKey) writeCache(key []byte) error {
+	if m.CacheTTL <= 0 {
+		return nil
+	}
+
+	file, err := m.cacheFile()
+	if err != nil {
+		// do not fall back to shared directories
+		m.CacheTTL = 0
+		return fmt.Errorf("no private directory for the key cache, caching is disabled: %w", err)
+	}
+
+	data, err := json.Marshal(keyCache{Key: hex.EncodeToString(key), Expires: time.Now().Add(m.CacheTTL)})
+	if err != nil {
+		return err
+	}
+	return writePrivateCacheFile(file, data)
+}
+
+func (m *MasterKey) removeCache() error {
+	file, err := m.cacheFile()
+	if err != nil {
+		// nothing can have been cached without a private directory
+		return nil
+	}
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// readKeyCheckSalt returns the salt of a key check file without decrypting it.
+func readKeyCheckSalt(data []byte) ([]byte, error) {
+	var check struct {
+		Salt string `json:"salt"`
+	}
+	if err := json.Unmarshal(data, &check); err != nil {
+		return nil, fmt.Errorf("invalid %s file: %w", keyCheckFileName, err)
+	}
+	return hex.DecodeString(check.Salt)
+}
+
+// isValidKey returns whether key decrypts the check value of a key check file.
+func isValidKey(data, key []byte) bool {
+	var check keyCheck
+	if err := jcrypt.Unmarshal(data, &check, &jcrypt.Options{GetKeyHandler: jcrypt.StaticKey(key)}); err != nil {
+		return false
+	}
+	return check.Check == keyCheckValue
+}
+
+func derivePassphraseKey(passphrase string, salt []byte) []byte {
+	return []byte(hex.EncodeToString(pbkdf2.Key([]byte(passphrase), salt, passphraseIterations, 32, sha256.New)))
+}
+
+func readKeyFile(file string) ([]byte, error) {
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		if fi.Mode().Perm()&0077 != 0 {
+			return nil, fmt.Errorf("key file %s must only be accessible by its owner, run chmod 600 %s", file, file)
+		}
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	key := strings.TrimSpace(string(data))
+	if len(key) == 0 {
+		return nil, fmt.Errorf("key file %s is empty", file)
+	}
+	return []byte(key), nil
+}
+
+func writePrivateFile(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile does not change the permissions of existing files
+	return os.Chmod(file, 0600)
+}
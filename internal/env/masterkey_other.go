@@ -0,0 +1,20 @@
+//go:build !unix
+
+package env
+
+import (
+	"fmt"
+)
+
+// checkPrivateDir always fails, because the ownership of directories cannot be verified on this platform.
+func checkPrivateDir(dir string) error {
+	return fmt.Errorf("the key cache is not supported on this platform")
+}
+
+func readPrivateCacheFile(file string) ([]byte, error) {
+	return nil, fmt.Errorf("the key cache is not supported on this platform")
+}
+
+func writePrivateCacheFile(file string, data []byte) error {
+	return fmt.Errorf("the key cache is not supported on this platform")
+}
@@ -0,0 +1,107 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sbreitf1/go-jcrypt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMasterKey(r *Reader, passphrases ...string) *MasterKey {
+	m := NewMasterKey(r)
+	m.ReadPassphrase = func(string) (string, error) {
+		if len(passphrases) == 0 {
+			return "", os.ErrClosed
+		}
+		passphrase := passphrases[0]
+		passphrases = passphrases[1:]
+		return passphrase, nil
+	}
+	return m
+}
+
+func rekey(t *testing.T, r *Reader, m *MasterKey, protection Protection, passphrase string) {
+	key, persist, err := m.Change(protection, passphrase)
+	require.NoError(t, err)
+	require.NoError(t, r.Rekey(key, persist))
+}
+
+func TestMasterKeyPassphrase(t *testing.T) {
+	r := newTestReader(t)
+	writeTestEnvironment(t, r, "test", Environment{Address: "test:51131", User: "DENIC-1000011-TEST", Password: "secret"})
+
+	m := newTestMasterKey(r)
+	r.KeySource = GetKeyHandler(m.Key)
+	rekey(t, r, m, ProtectionPassphrase, "master")
+
+	protection, err := m.Protection()
+	require.NoError(t, err)
+	assert.Equal(t, ProtectionPassphrase, protection)
+
+	// a new session prompts for the passphrase until it is correct
+	other := &Reader{dir: r.dir}
+	otherKey := newTestMasterKey(other, "wrong", "master")
+	other.KeySource = GetKeyHandler(otherKey.Key)
+
+	var env Environment
+	require.NoError(t, other.ReadEnvironment("test", &env))
+	assert.Equal(t, "secret", env.Password)
+
+	_, err = newTestMasterKey(other, "a", "b", "c").Key()
+	assert.EqualError(t, err, "wrong master passphrase")
+
+	// the environment can no longer be decrypted with an empty key
+	err = jcrypt.UnmarshalFromFile(r.getEnvFilePath("test"), &env, &jcrypt.Options{GetKeyHandler: jcrypt.StaticKey([]byte{})})
+	assert.True(t, jcrypt.IsWrongPassword(err))
+}
+
+func TestMasterKeyFile(t *testing.T) {
+	r := newTestReader(t)
+	writeTestEnvironment(t, r, "test", Environment{Address: "test:51131", Password: "secret"})
+
+	m := NewMasterKey(r)
+	r.KeySource = GetKeyHandler(m.Key)
+	rekey(t, r, m, ProtectionKeyFile, "")
+
+	other := &Reader{dir: r.dir}
+	other.KeySource = GetKeyHandler(NewMasterKey(other).Key)
+	var env Environment
+	require.NoError(t, other.ReadEnvironment("test", &env))
+	assert.Equal(t, "secret", env.Password)
+
+	require.NoError(t, os.Chmod(filepath.Join(r.dir, keyFileName), 0644))
+	_, err := NewMasterKey(r).Key()
+	assert.ErrorContains(t, err, "must only be accessible by its owner")
+
+	// switching back removes the key file
+	require.NoError(t, os.Chmod(filepath.Join(r.dir, keyFileName), 0600))
+	rekey(t, r, m, ProtectionNone, "")
+	assert.NoFileExists(t, filepath.Join(r.dir, keyFileName))
+	require.NoError(t, (&Reader{dir: r.dir}).ReadEnvironment("test", &env))
+}
+
+func TestMasterKeyMigration(t *testing.T) {
+	r := newTestReader(t)
+	writeTestEnvironment(t, r, "test", Environment{Address: "test:51131", Password: "secret"})
+
+	// configure a key file without rekeying existing environments
+	m := NewMasterKey(r)
+	_, persist, err := m.Change(ProtectionKeyFile, "")
+	require.NoError(t, err)
+	require.NoError(t, persist())
+
+	r.KeySource = GetKeyHandler(NewMasterKey(r).Key)
+	var env Environment
+	require.NoError(t, r.ReadEnvironment("test", &env))
+	assert.Equal(t, "secret", env.Password)
+
+	err = jcrypt.UnmarshalFromFile(r.getEnvFilePath("test"), &env, &jcrypt.Options{GetKeyHandler: jcrypt.StaticKey([]byte{})})
+	assert.True(t, jcrypt.IsWrongPassword(err))
+
+	fi, err := os.Stat(r.getEnvFilePath("test"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+}
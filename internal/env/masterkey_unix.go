@@ -0,0 +1,70 @@
+//go:build unix
+
+package env
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+)
+
+// checkPrivateDir returns an error if dir is not a directory that is owned and only accessible by the current user.
+func checkPrivateDir(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return checkPrivate(dir, fi)
+}
+
+func checkPrivate(name string, fi os.FileInfo) error {
+	if stat, ok := fi.Sys().(*syscall.Stat_t); !ok || int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is not owned by the current user", name)
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s must only be accessible by its owner", name)
+	}
+	return nil
+}
+
+// readPrivateCacheFile reads file without following symbolic links and only if it is private to the current user.
+func readPrivateCacheFile(file string) ([]byte, error) {
+	f, err := os.OpenFile(file, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", file)
+	}
+	if err := checkPrivate(file, fi); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(f)
+}
+
+// writePrivateCacheFile replaces file by a new file that is only accessible by the current user. Existing files and symbolic links are never written to.
+func writePrivateCacheFile(file string, data []byte) error {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL|syscall.O_NOFOLLOW, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,120 @@
+//go:build unix
+
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func privateTempDir(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.Chmod(dir, 0700))
+	return dir
+}
+
+func TestMasterKeyCache(t *testing.T) {
+	runtimeDir := privateTempDir(t)
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	r := newTestReader(t)
+	m := newTestMasterKey(r)
+	rekey(t, r, m, ProtectionPassphrase, "master")
+
+	first := newTestMasterKey(r, "master")
+	first.CacheTTL = time.Minute
+	key, err := first.Key()
+	require.NoError(t, err)
+
+	fi, err := os.Stat(filepath.Join(runtimeDir, keyCacheFileName))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	second := newTestMasterKey(r)
+	second.CacheTTL = time.Minute
+	cachedKey, err := second.Key()
+	require.NoError(t, err)
+	assert.Equal(t, key, cachedKey)
+
+	// the cache is ignored if disabled
+	_, err = newTestMasterKey(r).Key()
+	assert.Error(t, err)
+
+	// changing the protection removes the cache
+	rekey(t, r, m, ProtectionNone, "")
+	assert.NoFileExists(t, filepath.Join(runtimeDir, keyCacheFileName))
+}
+
+func TestMasterKeyCacheRequiresPrivateDir(t *testing.T) {
+	runtimeDir := t.TempDir()
+	require.NoError(t, os.Chmod(runtimeDir, 0755))
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	r := newTestReader(t)
+	rekey(t, r, newTestMasterKey(r), ProtectionPassphrase, "master")
+
+	m := newTestMasterKey(r, "master")
+	m.CacheTTL = time.Minute
+	_, err := m.Key()
+	require.NoError(t, err)
+	assert.Zero(t, m.CacheTTL)
+	assert.NoFileExists(t, filepath.Join(runtimeDir, keyCacheFileName))
+}
+
+func TestMasterKeyCacheFallbackDir(t *testing.T) {
+	tmpDir := privateTempDir(t)
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("TMPDIR", tmpDir)
+
+	r := newTestReader(t)
+	rekey(t, r, newTestMasterKey(r), ProtectionPassphrase, "master")
+
+	m := newTestMasterKey(r, "master")
+	m.CacheTTL = time.Minute
+	_, err := m.Key()
+	require.NoError(t, err)
+
+	dir := filepath.Join(tmpDir, fmt.Sprintf("rri-client-%d", os.Getuid()))
+	fi, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), fi.Mode().Perm())
+	assert.FileExists(t, filepath.Join(dir, keyCacheFileName))
+
+	// a directory prepared by another user or with wrong permissions is not used
+	require.NoError(t, os.Chmod(dir, 0777))
+	other := newTestMasterKey(r)
+	other.CacheTTL = time.Minute
+	_, err = other.Key()
+	assert.Error(t, err)
+}
+
+func TestMasterKeyCacheIgnoresSymlinks(t *testing.T) {
+	runtimeDir := privateTempDir(t)
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	r := newTestReader(t)
+	rekey(t, r, newTestMasterKey(r), ProtectionPassphrase, "master")
+
+	target := filepath.Join(t.TempDir(), "target")
+	require.NoError(t, os.WriteFile(target, []byte("unchanged"), 0600))
+	require.NoError(t, os.Symlink(target, filepath.Join(runtimeDir, keyCacheFileName)))
+
+	m := newTestMasterKey(r, "master")
+	m.CacheTTL = time.Minute
+	_, err := m.Key()
+	require.NoError(t, err)
+
+	// the link is replaced instead of written through
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "unchanged", string(data))
+	fi, err := os.Lstat(filepath.Join(runtimeDir, keyCacheFileName))
+	require.NoError(t, err)
+	assert.True(t, fi.Mode().IsRegular())
+}
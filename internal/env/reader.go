@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+
 	"github.com/DENICeG/go-console/v2"
 	"github.com/manifoldco/promptui"
 	"github.com/sbreitf1/go-jcrypt"
@@ -34,12 +37,52 @@ func NewReader(homeDirName string) (*Reader, error) {
 		return nil, err
 	}
 
-	return &Reader{dir: filepath.Join(dir, homeDirName)}, nil
+	return NewReaderForDir(filepath.Join(dir, homeDirName)), nil
+}
+
+// NewReaderForDir returns a new environment reader for the configuration directory dir.
+func NewReaderForDir(dir string) *Reader {
+	return &Reader{dir: dir}
+}
+
+// EscapeFileName escapes all characters of name that are not safe to use in file names.
+func EscapeFileName(name string) string {
+	var sb strings.Builder
+	for _, b := range []byte(name) {
+		isSafe := (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || b == '-' || b == '_' || (b == '.' && sb.Len() > 0)
+		if isSafe {
+			sb.WriteByte(b)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+// UnescapeFileName reverts EscapeFileName. Names that are not escaped are returned as they are.
+func UnescapeFileName(name string) string {
+	unescaped, err := url.PathUnescape(name)
+	if err != nil {
+		return name
+	}
+	return unescaped
 }
 
 func (e *Reader) getEnvFilePath(envName string) string {
-	// TODO conditional escaping
-	return filepath.Join(e.dir, envName+".json")
+	file := filepath.Join(e.dir, EscapeFileName(envName)+".json")
+	if EscapeFileName(envName) != envName && !strings.ContainsAny(envName, `/\`) {
+		// environments created before escaping was introduced are stored with their plain name
+		legacyFile := filepath.Join(e.dir, envName+".json")
+		if exists, _ := isFile(legacyFile); exists {
+			return legacyFile
+		}
+	}
+	return file
+}
+
+// envNameFromFile returns the environment name for an environment file name.
+func envNameFromFile(fileName string) string {
+	return UnescapeFileName(strings.TrimSuffix(fileName, ".json"))
 }
 
 // ReadEnvironment reads an existing environment.
@@ -59,11 +102,6 @@ func (e *Reader) createOrReadEnvironment(envName string, env any, enterEnvHandle
 		return err
 	}
 
-	keySource := jcrypt.KeySource(e.KeySource)
-	if keySource == nil {
-		keySource = func() ([]byte, error) { return []byte{}, nil }
-	}
-
 	if !exists {
 		if enterEnvHandler != nil {
 			console.Printlnf("Environment %q does not exist yet, pleaser enter below:", envName) //nolint
@@ -72,14 +110,8 @@ func (e *Reader) createOrReadEnvironment(envName string, env any, enterEnvHandle
 				return err
 			}
 
-			if err := os.MkdirAll(e.dir, os.ModePerm); err != nil {
+			if err := e.writeEnvironmentFile(file, env); err != nil {
 				console.Printlnf("WARNING: failed to save environment: %s", err.Error()) //nolint
-			} else {
-				if err := jcrypt.MarshalToFile(file, env, &jcrypt.Options{
-					GetKeyHandler: keySource,
-				}); err != nil {
-					console.Printlnf("WARNING: failed to save environment: %s", err.Error()) //nolint
-				}
 			}
 
 			e.envOrderBringToFront(envName) //nolint
@@ -88,9 +120,7 @@ func (e *Reader) createOrReadEnvironment(envName string, env any, enterEnvHandle
 		return fmt.Errorf("environment %q not found", envName)
 	}
 
-	if err := jcrypt.UnmarshalFromFile(file, env, &jcrypt.Options{
-		GetKeyHandler: keySource,
-	}); err != nil {
+	if err := e.readEnvironmentFile(file, env); err != nil {
 		return err
 	}
 
@@ -98,20 +128,119 @@ func (e *Reader) createOrReadEnvironment(envName string, env any, enterEnvHandle
 	return nil
 }
 
-// SelectEnvironment displays all configured environments in specified order and prompts the user.
-func (e *Reader) SelectEnvironment(env any) error {
+func (e *Reader) keySource() jcrypt.KeySource {
+	if e.KeySource == nil {
+		return func() ([]byte, error) { return []byte{}, nil }
+	}
+	return jcrypt.KeySource(e.KeySource)
+}
+
+func (e *Reader) readEnvironmentFile(file string, env any) error {
+	err := jcrypt.UnmarshalFromFile(file, env, &jcrypt.Options{
+		GetKeyHandler: e.keySource(),
+	})
+	if !jcrypt.IsWrongPassword(err) || e.KeySource == nil {
+		return err
+	}
+
+	// environments saved before a master key has been configured are encrypted with an empty key and migrated transparently
+	if legacyErr := jcrypt.UnmarshalFromFile(file, env, &jcrypt.Options{
+		GetKeyHandler: jcrypt.StaticKey([]byte{}),
+	}); legacyErr != nil {
+		return err
+	}
+
+	return e.writeEnvironmentFile(file, env)
+}
+
+func (e *Reader) writeEnvironmentFile(file string, env any) error {
+	return e.writeEnvironmentFileWithKey(file, env, e.keySource())
+}
+
+func (e *Reader) writeEnvironmentFileWithKey(file string, env any, keySource jcrypt.KeySource) error {
+	data, err := jcrypt.Marshal(env, &jcrypt.Options{
+		GetKeyHandler: keySource,
+	})
+	if err != nil {
+		return err
+	}
+
+	// environment files contain credentials
+	return writePrivateFile(file, data)
+}
+
+// Rekey encrypts all environments with newKey. persist is called before the environment files are replaced to store the new protection.
+func (e *Reader) Rekey(newKey []byte, persist func() error) error {
 	envFiles, err := e.GetEnvironmentFiles()
 	if err != nil {
 		return err
 	}
+
+	// write all environments to temporary files first to not lose any environment on errors
+	tmpFiles := make(map[string]string)
+	defer func() {
+		for _, tmpFile := range tmpFiles {
+			os.Remove(tmpFile)
+		}
+	}()
+
+	for _, fi := range envFiles {
+		file := filepath.Join(e.dir, fi.Name())
+		var env Environment
+		if err := e.readEnvironmentFile(file, &env); err != nil {
+			return fmt.Errorf("failed to read environment %q: %w", envNameFromFile(fi.Name()), err)
+		}
+
+		tmpFile := file + ".rekey"
+		if err := e.writeEnvironmentFileWithKey(tmpFile, &env, jcrypt.StaticKey(newKey)); err != nil {
+			return err
+		}
+		tmpFiles[file] = tmpFile
+	}
+
+	if err := persist(); err != nil {
+		return err
+	}
+
+	for file, tmpFile := range tmpFiles {
+		if err := os.Rename(tmpFile, file); err != nil {
+			return err
+		}
+		delete(tmpFiles, file)
+	}
+
+	return nil
+}
+
+// CredentialProvider returns a credential provider that decrypts user and password from an existing environment on every login.
+func (e *Reader) CredentialProvider(envName string) rri.CredentialProvider {
+	return rri.CredentialProviderFunc(func() (string, string, error) {
+		var env Environment
+		if err := e.readEnvironmentFile(e.getEnvFilePath(envName), &env); err != nil {
+			return "", "", fmt.Errorf("failed to read environment %q: %s", envName, err.Error())
+		}
+
+		if !env.HasCredentials() {
+			return "", "", fmt.Errorf("environment %q does not contain credentials", envName)
+		}
+
+		return env.User, env.Password, nil
+	})
+}
+
+// SelectEnvironment displays all configured environments in specified order, prompts the user and returns the name of the selected environment.
+func (e *Reader) SelectEnvironment(env any) (string, error) {
+	envFiles, err := e.GetEnvironmentFiles()
+	if err != nil {
+		return "", err
+	}
 	if len(envFiles) == 0 {
-		return fmt.Errorf("no environments specified")
+		return "", fmt.Errorf("no environments specified")
 	}
 
 	envTitles := make([]string, len(envFiles))
 	for i, fi := range envFiles {
-		name := fi.Name()
-		name = strings.TrimSuffix(name, ".json")
+		name := envNameFromFile(fi.Name())
 
 		envTitles[i] = name
 		if e.GetEnvFileTitle != nil {
@@ -122,11 +251,11 @@ func (e *Reader) SelectEnvironment(env any) error {
 	ui := promptui.Select{Label: "Select environment", Items: envTitles, HideSelected: true}
 	index, _, err := ui.Run()
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	fileName := envFiles[index].Name()
-	return e.createOrReadEnvironment(fileName[:len(fileName)-5], env, nil)
+	envName := envNameFromFile(envFiles[index].Name())
+	return envName, e.createOrReadEnvironment(envName, env, nil)
 }
 
 // ListEnvironments returns a list of all environment titles.
@@ -142,8 +271,7 @@ func (e *Reader) ListEnvironments() ([]string, error) {
 
 	envTitles := make([]string, len(envFiles))
 	for i, fi := range envFiles {
-		name := fi.Name()
-		name = strings.TrimSuffix(name, ".json")
+		name := envNameFromFile(fi.Name())
 
 		envTitles[i] = name
 		if e.GetEnvFileTitle != nil {
@@ -220,7 +348,7 @@ func (e *Reader) writeEnvOrder(order envOrder) error {
 	return os.WriteFile(filepath.Join(e.dir, envOrderFileName), orderData, os.ModePerm)
 }
 
-func (e *Reader) envOrderBringToFront(name string) error {
+func (e *Reader) envOrderBringToFront(envName string) error {
 	order, err := e.readEnvOrder()
 	if err != nil {
 		return err
@@ -230,9 +358,7 @@ func (e *Reader) envOrderBringToFront(name string) error {
 		return nil
 	}
 
-	if !strings.HasSuffix(name, ".json") {
-		name += ".json"
-	}
+	name := filepath.Base(e.getEnvFilePath(envName))
 	if order.Order == nil {
 		order.Order = []string{name}
 	} else {
@@ -248,6 +374,29 @@ func (e *Reader) envOrderBringToFront(name string) error {
 	return e.writeEnvOrder(order)
 }
 
+// envOrderReplace replaces the file name oldName in the environment order by newName or removes it if newName is empty.
+func (e *Reader) envOrderReplace(oldName, newName string) error {
+	order, err := e.readEnvOrder()
+	if err != nil {
+		return err
+	}
+
+	newOrder := make([]string, 0, len(order.Order))
+	for _, name := range order.Order {
+		if name != oldName {
+			newOrder = append(newOrder, name)
+		} else if len(newName) > 0 {
+			newOrder = append(newOrder, newName)
+		}
+	}
+	if len(newOrder) == len(order.Order) && len(newName) == 0 {
+		return nil
+	}
+	order.Order = newOrder
+
+	return e.writeEnvOrder(order)
+}
+
 // DeleteEnvironment deletes an existing environment.
 func (e *Reader) DeleteEnvironment(envName string) error {
 	file := e.getEnvFilePath(envName)
@@ -260,5 +409,81 @@ func (e *Reader) DeleteEnvironment(envName string) error {
 		return fmt.Errorf("environment %q does not exist", envName)
 	}
 
-	return os.Remove(file)
+	if err := os.Remove(file); err != nil {
+		return err
+	}
+
+	return e.envOrderReplace(filepath.Base(file), "")
+}
+
+// EditEnvironment reads an existing environment, passes it to edit and saves the result.
+func (e *Reader) EditEnvironment(envName string, env any, edit EnterEnvHandler) error {
+	file := e.getEnvFilePath(envName)
+	if err := e.readEnvironmentFile(file, env); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("environment %q does not exist", envName)
+		}
+		return err
+	}
+
+	if err := edit(env); err != nil {
+		return err
+	}
+
+	return e.writeEnvironmentFile(file, env)
+}
+
+// RenameEnvironment renames an existing environment. The new name must not be used by another environment.
+func (e *Reader) RenameEnvironment(oldName, newName string) error {
+	oldFile, newFile, err := e.getSourceAndTargetFile(oldName, newName)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(oldFile, newFile); err != nil {
+		return err
+	}
+
+	return e.envOrderReplace(filepath.Base(oldFile), filepath.Base(newFile))
+}
+
+// CopyEnvironment creates a new environment with the same settings as an existing environment.
+func (e *Reader) CopyEnvironment(srcName, dstName string) error {
+	srcFile, dstFile, err := e.getSourceAndTargetFile(srcName, dstName)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(srcFile)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(dstFile, data, 0600)
+}
+
+func (e *Reader) getSourceAndTargetFile(srcName, dstName string) (string, string, error) {
+	if len(dstName) == 0 {
+		return "", "", fmt.Errorf("missing new environment name")
+	}
+
+	srcFile := e.getEnvFilePath(srcName)
+	exists, err := isFile(srcFile)
+	if err != nil {
+		return "", "", err
+	}
+	if !exists {
+		return "", "", fmt.Errorf("environment %q does not exist", srcName)
+	}
+
+	dstFile := e.getEnvFilePath(dstName)
+	exists, err = isFile(dstFile)
+	if err != nil {
+		return "", "", err
+	}
+	if exists {
+		return "", "", fmt.Errorf("environment %q already exists", dstName)
+	}
+
+	return srcFile, dstFile, nil
 }
@@ -0,0 +1,112 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReader(t *testing.T) *Reader {
+	return &Reader{dir: t.TempDir()}
+}
+
+func writeTestEnvironment(t *testing.T, r *Reader, envName string, env Environment) {
+	require.NoError(t, r.writeEnvironmentFile(r.getEnvFilePath(envName), &env))
+	require.NoError(t, r.envOrderBringToFront(envName))
+}
+
+func TestEscapeFileName(t *testing.T) {
+	assert.Equal(t, "test-env_1.2", EscapeFileName("test-env_1.2"))
+	assert.Equal(t, "%2E.%2Fprod%20env", EscapeFileName("../prod env"))
+	assert.Equal(t, "../prod env", UnescapeFileName(EscapeFileName("../prod env")))
+}
+
+func TestGetEnvFilePath(t *testing.T) {
+	r := newTestReader(t)
+	assert.Equal(t, filepath.Join(r.dir, "%2E.%2Fprod.json"), r.getEnvFilePath("../prod"))
+	assert.Equal(t, filepath.Join(r.dir, "prod%20env.json"), r.getEnvFilePath("prod env"))
+
+	// environments created before escaping are still found
+	require.NoError(t, os.WriteFile(filepath.Join(r.dir, "prod env.json"), []byte(`{"address":"localhost:51131"}`), 0600))
+	assert.Equal(t, filepath.Join(r.dir, "prod env.json"), r.getEnvFilePath("prod env"))
+
+	names, err := r.ListEnvironments()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod env"}, names)
+}
+
+func TestRenameAndCopyEnvironment(t *testing.T) {
+	r := newTestReader(t)
+	writeTestEnvironment(t, r, "test", Environment{Address: "test:51131", User: "DENIC-1000011-TEST", Password: "secret"})
+	writeTestEnvironment(t, r, "prod env", Environment{Address: "prod:51131"})
+
+	require.NoError(t, r.RenameEnvironment("test", "staging"))
+	assert.EqualError(t, r.RenameEnvironment("test", "other"), `environment "test" does not exist`)
+	assert.EqualError(t, r.RenameEnvironment("staging", "prod env"), `environment "prod env" already exists`)
+
+	require.NoError(t, r.CopyEnvironment("staging", "staging/2"))
+
+	var env Environment
+	require.NoError(t, r.ReadEnvironment("staging/2", &env))
+	assert.Equal(t, "secret", env.Password)
+
+	names, err := r.ListEnvironments()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"staging/2", "prod env", "staging"}, names)
+
+	require.NoError(t, r.DeleteEnvironment("prod env"))
+	order, err := r.readEnvOrder()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"staging%2F2.json", "staging.json"}, order.Order)
+}
+
+func TestEditEnvironment(t *testing.T) {
+	r := newTestReader(t)
+	writeTestEnvironment(t, r, "test", Environment{Address: "test:51131", User: "DENIC-1000011-TEST", Password: "secret"})
+
+	var env Environment
+	require.NoError(t, r.EditEnvironment("test", &env, func(envi any) error {
+		envi.(*Environment).Output = "json"
+		return nil
+	}))
+
+	env = Environment{}
+	require.NoError(t, r.ReadEnvironment("test", &env))
+	assert.Equal(t, Environment{Address: "test:51131", User: "DENIC-1000011-TEST", Password: "secret", Output: "json"}, env)
+
+	assert.EqualError(t, r.EditEnvironment("missing", &env, nil), `environment "missing" does not exist`)
+}
+
+func TestExportImportEnvironments(t *testing.T) {
+	r := newTestReader(t)
+	writeTestEnvironment(t, r, "test", Environment{Address: "test:51131", User: "DENIC-1000011-TEST", Password: "secret", ClientCert: "client.pem"})
+	writeTestEnvironment(t, r, "prod", Environment{Address: "prod:51131"})
+
+	file := filepath.Join(t.TempDir(), "envs.json")
+	require.NoError(t, r.ExportEnvironments(file, []byte("passphrase")))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+	assert.NotContains(t, string(data), "test:51131")
+
+	other := newTestReader(t)
+	writeTestEnvironment(t, other, "prod", Environment{Address: "other:51131"})
+
+	_, _, err = other.ImportEnvironments(file, []byte("wrong"))
+	assert.Error(t, err)
+
+	imported, skipped, err := other.ImportEnvironments(file, []byte("passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test"}, imported)
+	assert.Equal(t, []string{"prod"}, skipped)
+
+	var env Environment
+	require.NoError(t, other.ReadEnvironment("test", &env))
+	assert.Equal(t, Environment{Address: "test:51131", User: "DENIC-1000011-TEST", Password: "secret", ClientCert: "client.pem"}, env)
+
+	assert.EqualError(t, r.ExportEnvironments(file, []byte("passphrase"), "missing"), `environment "missing" does not exist`)
+}
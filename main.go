@@ -3,7 +3,9 @@ package main
 import (
 	"embed"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/DENICeG/go-rriclient/internal/env"
@@ -36,36 +38,75 @@ func main() {
 		argCmd           = app.Arg("command", "Command with arguments or RRI host like host:51131").Strings()
 		argHost          = app.Flag("host", "A RRI host like host:51131").Short('h').String()
 		argUser          = app.Flag("user", "RRI user to use for login").Short('u').String()
-		argPassword      = app.Flag("pass", "RRI password to use for login. Will be asked for if only user is set").Short('p').String()
+		argPassword      = app.Flag("pass", "RRI password to use for login. Will be asked for if only user is set. Visible in process listings, prefer --pass-env, --pass-fd or --pass-cmd").Short('p').String()
+		argPassEnv       = app.Flag("pass-env", "Environment variable to read the RRI password from").String()
+		argPassFD        = app.Flag("pass-fd", "File descriptor to read the RRI password from").Default("-1").Int()
+		argPassCmd       = app.Flag("pass-cmd", "Helper command that prints the RRI password to stdout. Executed for every login").String()
 		argFile          = app.Flag("file", "Input file containing RRI requests separated by a '=-=' line").Short('f').String()
 		argEnvironment   = app.Flag("env", "Named environment to use or create").Short('e').String()
 		argDeleteEnv     = app.Flag("delete-env", "Delete an existing environment").String()
 		argListEnv       = app.Flag("list-env", "List all environments").Bool()
+		argEditEnv       = app.Flag("edit-env", "Edit the settings of an existing environment").String()
+		argRenameEnv     = app.Flag("rename-env", "Rename an existing environment like --rename-env old=new").StringMap()
+		argCopyEnv       = app.Flag("copy-env", "Copy an existing environment like --copy-env test=test2").StringMap()
+		argExportEnv     = app.Flag("export-env", "Export all environments or the one selected with --env to a passphrase encrypted bundle file").String()
+		argImportEnv     = app.Flag("import-env", "Import all environments from a bundle file created with --export-env").String()
+		argRekeyEnv      = app.Flag("rekey-env", "Protect environment passwords with a master passphrase or key file and re-encrypt all environments").Bool()
+		argKeyFile       = app.Flag("key-file", "Key file to encrypt environment passwords with instead of ~/.rri-client/env-key").String()
+		argKeyCache      = app.Flag("key-cache", "Cache the master passphrase for the given duration like 15m so other sessions do not prompt again").Duration()
+		argYes           = app.Flag("yes", "Send destructive queries to protected environments without confirmation").Short('y').Bool()
 		argFail          = app.Flag("fail", "Exit with code 1 if RRI returns a failed result").Bool()
+		argContinue      = app.Flag("continue-on-error", "Continue processing the query file after a failed query").Bool()
+		argDryRun        = app.Flag("dry-run", "Only parse and validate the query file without sending any query").Bool()
+		argReport        = app.Flag("report", "Write a report for the processed query file in the given format").Enum("json", "junit", "csv")
+		argReportFile    = app.Flag("report-file", "File to write the report to. Required for --report").String()
+		argFrom          = app.Flag("from", "JSON or YAML file with contact or domain data for create and update commands. Use --from=- for stdin").String()
+		argSet           = app.Flag("set", "Set a variable for ${NAME} placeholders in query files and presets like --set domain=denic.de").StringMap()
+		argOutput        = app.Flag("output", "Print responses as kv, json, yaml or table. Defaults to the output of the environment or kv").Short('o').Enum("kv", "json", "yaml", "table")
 		argVerbose       = app.Flag("verbose", "Print all sent and received requests").Short('v').Bool()
+		argPrecheck      = app.Flag("precheck", "Check the name servers of CREATE, UPDATE and CHPROV queries before they are sent").Bool()
+		argTranscript    = app.Flag("transcript", "Record all commands, queries and responses to a file. The format is derived from the extension (.md, .jsonl) unless --transcript-format is set").String()
+		argTranscriptFmt = app.Flag("transcript-format", "Format of the transcript file").Enum("markdown", "jsonl", "queries")
 		argInsecure      = app.Flag("insecure", "Disable SSL Certificate checks").Bool()
 		argVersion       = app.Flag("version", "Display application version and exit").Bool()
 		argDumpCLIConfig = app.Flag("dump-cli-config", "Print all configured colors and signs for testing").Bool()
 		argPreset        = app.Flag("preset", "Dynamically load, edit and execute a query from a preset").Short('P').Bool()
+		argPresetDir     = app.Flag("preset-dir", "Additional directory to load presets from. Can be repeated").Strings()
+		argEditor        = app.Flag("editor", "Edit presets in $VISUAL or $EDITOR instead of the terminal").Bool()
+		argKeepAlive     = app.Flag("keep-alive", "Send a keep-alive query after the session has been idle for the given duration like 5m").Duration()
+		argIdleTimeout   = app.Flag("idle-timeout", "Close the connection after the session has been idle for the given duration. It is restored with the next query").Duration()
 	)
 
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 
-	dirInfo, err := embedFS.ReadDir("examples")
+	envReader, err := env.NewReader(".rri-client")
 	if err != nil {
 		logAndExit(err)
 	}
 
-	presets, err := preset.Load(embedFS, dirInfo)
+	builtinPresets, err := fs.Sub(embedFS, "examples")
 	if err != nil {
 		logAndExit(err)
 	}
 
-	envReader, err := env.NewReader(".rri-client")
+	// user presets replace built-in presets, project-local presets replace user presets
+	userPresetDir := filepath.Join(envReader.Dir(), "presets")
+	presetSources := append([]preset.Source{{Name: preset.BuiltinSource, FS: builtinPresets}},
+		preset.DirSources(append([]string{userPresetDir, filepath.Join(".rri-client", "presets")}, *argPresetDir...)...)...)
+
+	presets, err := preset.Load(presetSources...)
 	if err != nil {
 		logAndExit(err)
 	}
 
+	masterKey := env.NewMasterKey(envReader)
+	masterKey.ReadPassphrase = cli.ReadMasterPassphrase
+	masterKey.CacheTTL = *argKeyCache
+	if len(*argKeyFile) > 0 {
+		masterKey.KeyFile = *argKeyFile
+	}
+
+	envReader.KeySource = env.GetKeyHandler(masterKey.Key)
 	envReader.EnterEnvHandler = cli.EnterEnvironment
 	envReader.GetEnvFileTitle = env.GetEnvTitle
 
@@ -86,29 +127,127 @@ func main() {
 
 	shutdown(exit)
 
-	env, err := cli.RetrieveEnvironment(envReader, argHost, argEnvironment, argUser, argPassword, argCmd)
+	exit, err = cli.EditEnv(argEditEnv, envReader)
 	if err != nil {
 		logAndExit(err)
 	}
 
-	if len(env.Address) == 0 {
-		logAndExit(fmt.Errorf("missing RRI server address"))
+	shutdown(exit)
+
+	exit, err = cli.RenameEnv(*argRenameEnv, envReader)
+	if err != nil {
+		logAndExit(err)
+	}
+
+	shutdown(exit)
+
+	exit, err = cli.CopyEnv(*argCopyEnv, envReader)
+	if err != nil {
+		logAndExit(err)
+	}
+
+	shutdown(exit)
+
+	var exportEnvNames []string
+	if len(*argEnvironment) > 0 {
+		exportEnvNames = []string{*argEnvironment}
+	}
+	exit, err = cli.ExportEnv(argExportEnv, exportEnvNames, envReader)
+	if err != nil {
+		logAndExit(err)
+	}
+
+	shutdown(exit)
+
+	exit, err = cli.ImportEnv(argImportEnv, envReader)
+	if err != nil {
+		logAndExit(err)
 	}
 
-	client, err := rri.NewClient(env.Address, &rri.ClientConfig{Insecure: env.Insecure || *argInsecure})
+	shutdown(exit)
+
+	exit, err = cli.RekeyEnv(argRekeyEnv, envReader, masterKey)
 	if err != nil {
-		if !*argInsecure && strings.Contains(err.Error(), "x509") {
+		logAndExit(err)
+	}
+
+	shutdown(exit)
+
+	batchOptions := cli.BatchOptions{
+		ContinueOnError: *argContinue,
+		DryRun:          *argDryRun,
+		ReportFormat:    *argReport,
+		ReportFile:      *argReportFile,
+	}
+	if err := batchOptions.Validate(); err != nil {
+		logAndExit(err)
+	}
+
+	if *argDryRun && len(*argFile) > 0 {
+		// validating a query file does not require a connection
+		cliService := cli.New(nil, presets, nil)
+		cliService.Batch = batchOptions
+		cliService.SetVariables(*argSet)
+		err = cliService.HandleFile([]string{*argFile})
+		if err != nil {
+			logAndExit(err)
+		}
+
+		return
+	}
+
+	credentialOptions := cli.CredentialOptions{
+		PasswordEnv: *argPassEnv,
+		PasswordFD:  *argPassFD,
+		PasswordCmd: *argPassCmd,
+	}
+
+	connect := func(envi env.Environment) (*rri.Client, error) {
+		certificates, err := cli.ClientCertificates(envi)
+		if err != nil {
+			return nil, err
+		}
+
+		client, err := rri.NewClient(envi.Address, &rri.ClientConfig{
+			Insecure:          envi.Insecure || *argInsecure,
+			Certificates:      certificates,
+			KeepAliveInterval: *argKeepAlive,
+			IdleTimeout:       *argIdleTimeout,
+		})
+		if err != nil && !*argInsecure && strings.Contains(err.Error(), "x509") {
 			// show help message for x509 related errors
 			console.Println("HINT: try the '--insecure' flag if you have trouble with self signed certificates")
 		}
+		return client, err
+	}
 
+	env, credentials, err := cli.RetrieveEnvironment(envReader, argHost, argEnvironment, argUser, argPassword, argCmd, credentialOptions)
+	if err != nil {
 		logAndExit(err)
 	}
 
-	defer client.Close()
+	if len(env.Address) == 0 {
+		logAndExit(fmt.Errorf("missing RRI server address"))
+	}
 
-	presetCompletion := cli.NewPresetCompletion(*presets)
-	cliService := cli.New(client, *presets, presetCompletion, embedFS)
+	client, err := connect(env)
+	if err != nil {
+		logAndExit(err)
+	}
+
+	presetCompletion := cli.NewPresetCompletion(presets)
+	cliService := cli.New(client, presets, presetCompletion)
+	cliService.PresetDir = userPresetDir
+	cliService.UseEditor = *argEditor
+	cliService.EnvName = env.Name
+	cliService.EnvReader = envReader
+	cliService.Connect = connect
+	cliService.Protected = env.Protected
+	cliService.AssumeYes = *argYes
+	defer cliService.Close()
+	if err := cliService.SetColorTheme(env.ColorTheme); err != nil {
+		logAndExit(err)
+	}
 
 	if argPreset != nil && *argPreset == true {
 		cliService.HandlePreset([]string{})
@@ -120,18 +259,41 @@ func main() {
 		return
 	}
 
-	if *argVerbose {
-		client.RawQueryPrinter = cliService.RawQueryPrinter
-		client.InnerErrorPrinter = cliService.ErrorPrinter
+	cliService.Verbose = *argVerbose
+	cliService.Precheck = *argPrecheck
+	if len(*argTranscript) > 0 {
+		if err := cliService.StartTranscript(*argTranscript, *argTranscriptFmt); err != nil {
+			logAndExit(err)
+		}
 	}
 
-	if env.HasCredentials() {
-		err = client.Login(env.User, env.Password)
+	if credentials != nil {
+		err = client.LoginWith(credentials)
 		if err != nil {
 			logAndExit(err)
 		}
 	}
 
+	cliService.ReturnErrorOnFail = *argFail
+	cliService.DataFile = *argFrom
+	output := *argOutput
+	if len(output) == 0 {
+		output = env.Output
+	}
+	if len(output) == 0 {
+		output = string(cli.OutputKV)
+	}
+	outputMode, err := cli.ParseOutputMode(output)
+	if err != nil {
+		logAndExit(err)
+	}
+	cliService.Output = outputMode
+	if len(*argOutput) > 0 {
+		cliService.ForcedOutput = outputMode
+	}
+	cliService.Batch = batchOptions
+	cliService.SetVariables(*argSet)
+
 	if len(*argFile) > 0 {
 		err = cliService.HandleFile([]string{*argFile})
 		if err != nil {
@@ -141,7 +303,14 @@ func main() {
 		return
 	}
 
-	cliService.ReturnErrorOnFail = *argFail
+	historyName := env.Name
+	if len(historyName) == 0 {
+		historyName = env.Address
+	}
+	if err := cliService.LoadHistory(envReader.Dir(), historyName); err != nil {
+		cliService.ErrorPrinter(fmt.Errorf("failed to load history: %w", err))
+	}
+
 	err = cliService.Run(envReader.Dir(), *argCmd)
 	if err != nil {
 		logAndExit(err)
@@ -0,0 +1,446 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
+
+const (
+	batchResultSuccess = "success"
+	batchResultFailure = "failure"
+	batchResultError   = "error"
+	batchResultInvalid = "invalid"
+	batchResultSkipped = "skipped"
+	batchResultValid   = "valid"
+)
+
+// BatchOptions controls how query files are processed.
+type BatchOptions struct {
+	// ContinueOnError continues with the next query after a failed result or error.
+	ContinueOnError bool
+	// DryRun only parses and validates all queries without sending them.
+	DryRun bool
+	// ReportFormat denotes the report format (json, junit or csv). No report is written if empty.
+	ReportFormat string
+	// ReportFile denotes the file to write the report to. Required if ReportFormat is set, because responses are printed to stdout.
+	ReportFile string
+}
+
+// Validate returns an error if the options cannot be combined.
+func (o BatchOptions) Validate() error {
+	if len(o.ReportFormat) > 0 && len(o.ReportFile) == 0 {
+		return fmt.Errorf("--report requires --report-file, because responses are printed to stdout")
+	}
+	return nil
+}
+
+type batchMessage struct {
+	Level string `json:"level"`
+	ID    int64  `json:"id,omitempty"`
+	Text  string `json:"text"`
+}
+
+func (m batchMessage) String() string {
+	if m.ID == 0 {
+		return fmt.Sprintf("%s: %s", m.Level, m.Text)
+	}
+	return fmt.Sprintf("%s: %d %s", m.Level, m.ID, m.Text)
+}
+
+type batchResult struct {
+	Index      int            `json:"index"`
+	Action     string         `json:"action"`
+	Result     string         `json:"result"`
+	STID       string         `json:"stid,omitempty"`
+	Messages   []batchMessage `json:"messages,omitempty"`
+	DurationMS int64          `json:"durationMs"`
+}
+
+func (r batchResult) failed() bool {
+	return r.Result == batchResultFailure || r.Result == batchResultError || r.Result == batchResultInvalid
+}
+
+func (r *batchResult) addMessage(level string, id int64, text string) {
+	r.Messages = append(r.Messages, batchMessage{Level: level, ID: id, Text: text})
+}
+
+func (r *batchResult) addBusinessMessages(level string, messages []rri.BusinessMessage) {
+	for _, msg := range messages {
+		r.addMessage(level, msg.ID(), msg.Message())
+	}
+}
+
+// readResponse sets result, STID and business messages from response.
+func (r *batchResult) readResponse(response *rri.Response) {
+	r.Result = batchResultFailure
+	if response.IsSuccessful() {
+		r.Result = batchResultSuccess
+	}
+	r.STID = response.STID()
+	r.addBusinessMessages("info", response.InfoMessages())
+	r.addBusinessMessages("warning", response.WarningMessages())
+	r.addBusinessMessages("error", response.ErrorMessages())
+}
+
+// batchQuery is a single entry of a query file.
+type batchQuery struct {
+	raw    string
+	query  *rri.Query
+	action rri.QueryAction
+	domain string
+	err    error
+}
+
+func parseBatchQueries(queryStrings []string, xmlFormat bool) []batchQuery {
+	queries := make([]batchQuery, len(queryStrings))
+	for i, queryString := range queryStrings {
+		queries[i].raw = queryString
+
+		parse := rri.ParseQueryKV
+		if xmlFormat {
+			parse = rri.ParseQueryXML
+		}
+
+		query, err := parse(strings.TrimSpace(queryString))
+		if err != nil {
+			queries[i].err = err
+			continue
+		}
+		// XML queries are sent as written
+		if !xmlFormat {
+			queries[i].query = query
+		}
+		queries[i].action = query.Action().Normalize()
+		queries[i].domain = queryDomain(query)
+		if !queries[i].action.IsKnown() {
+			queries[i].err = fmt.Errorf("unknown action '%s'", query.Action())
+		}
+	}
+	return queries
+}
+
+func (s *Service) executeQueries(queryStrings []string, xmlFormat bool) error {
+	queries := parseBatchQueries(queryStrings, xmlFormat)
+
+	if s.Batch.DryRun {
+		return s.finishBatch(s.validateQueries(queries))
+	}
+
+	results := make([]batchResult, len(queries))
+	for i, query := range queries {
+		results[i] = batchResult{Index: i + 1, Action: string(query.action), Result: batchResultSkipped}
+	}
+
+	// rejected name servers are treated like invalid queries
+	if s.Precheck {
+		for i := range queries {
+			if queries[i].err == nil && queries[i].query != nil {
+				queries[i].err = s.precheckQuery(queries[i].query)
+			}
+		}
+	}
+
+	// do not send anything if any query is invalid and errors are not accepted
+	invalid := false
+	for i, query := range queries {
+		if query.err != nil {
+			results[i].Result = batchResultInvalid
+			results[i].addMessage("error", 0, query.err.Error())
+			console.Printlnf("%sQuery #%d is invalid: %s%s", s.colorErrorResponseMessage, i+1, query.err.Error(), s.colorEnd)
+			invalid = true
+		}
+	}
+	if invalid && !s.Batch.ContinueOnError {
+		return s.finishBatch(results)
+	}
+
+	if err := s.confirmBatch(queries); err != nil {
+		return err
+	}
+
+	skipAuthQueries := s.rriClient.IsLoggedIn()
+	hasAuthQueries := false
+	for _, query := range queries {
+		if isAuthAction(query.action) {
+			hasAuthQueries = true
+		}
+	}
+	if skipAuthQueries && hasAuthQueries {
+		// TODO colored orange
+		s.printNotice("Currently logged in. Auth queries will be skipped")
+	}
+
+	for i, query := range queries {
+		if query.err != nil {
+			continue
+		}
+
+		if skipAuthQueries && isAuthAction(query.action) {
+			results[i].addMessage("info", 0, "already logged in")
+			continue
+		}
+
+		start := time.Now()
+		if xmlFormat {
+			results[i] = s.executeXMLQuery(i, query)
+		} else {
+			results[i] = s.executeKVQuery(i, query)
+		}
+		results[i].DurationMS = time.Since(start).Milliseconds()
+
+		if results[i].failed() && !s.Batch.ContinueOnError {
+			break
+		}
+	}
+
+	return s.finishBatch(results)
+}
+
+func isAuthAction(action rri.QueryAction) bool {
+	return action == rri.ActionLogin || action == rri.ActionLogout
+}
+
+func (s *Service) validateQueries(queries []batchQuery) []batchResult {
+	results := make([]batchResult, len(queries))
+	for i, query := range queries {
+		results[i] = batchResult{Index: i + 1, Action: string(query.action), Result: batchResultValid}
+		if query.err != nil {
+			results[i].Result = batchResultInvalid
+			results[i].addMessage("error", 0, query.err.Error())
+			console.Printlnf("%sQuery #%d is invalid: %s%s", s.colorErrorResponseMessage, i+1, query.err.Error(), s.colorEnd)
+		} else {
+			console.Printlnf("Query #%d (%s) is valid", i+1, query.action)
+		}
+	}
+	return results
+}
+
+func (s *Service) executeKVQuery(i int, query batchQuery) batchResult {
+	result := batchResult{Index: i + 1, Action: string(query.action)}
+
+	response, err := s.rriClient.SendQuery(query.query)
+	if err != nil {
+		result.Result = batchResultError
+		result.addMessage("error", 0, err.Error())
+		s.ErrorPrinter(fmt.Errorf("failed to send query #%d: %w", i+1, err))
+		return result
+	}
+
+	s.completion.harvestResponse(response)
+
+	if err := s.printResponse(response); err != nil {
+		s.ErrorPrinter(err)
+	}
+
+	result.readResponse(response)
+	return result
+}
+
+func (s *Service) executeXMLQuery(i int, query batchQuery) batchResult {
+	result := batchResult{Index: i + 1, Action: string(query.action)}
+
+	response, err := s.rriClient.SendRaw(query.raw)
+	if err != nil {
+		result.Result = batchResultError
+		result.addMessage("error", 0, err.Error())
+		s.ErrorPrinter(fmt.Errorf("failed to send query #%d: %w", i+1, err))
+		return result
+	}
+
+	responseObj, err := rri.ParseResponseXML(response)
+	if err != nil {
+		result.Result = batchResultError
+		result.addMessage("error", 0, fmt.Sprintf("invalid xml response: %s", err.Error()))
+		s.ErrorPrinter(fmt.Errorf("query #%d returned an invalid response: %w", i+1, err))
+		console.Println(response)
+		return result
+	}
+
+	s.completion.harvestResponse(responseObj)
+	result.readResponse(responseObj)
+
+	if s.Output == OutputKV {
+		err = s.printXMLResult(response, i, responseObj.IsSuccessful())
+	} else {
+		err = s.printResponse(responseObj)
+	}
+	if err != nil {
+		result.addMessage("error", 0, err.Error())
+	}
+
+	return result
+}
+
+// finishBatch writes the report and returns an error according to the batch options.
+func (s *Service) finishBatch(results []batchResult) error {
+	if len(s.Batch.ReportFormat) > 0 {
+		if err := s.writeReport(results); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	for _, result := range results {
+		if result.Result == batchResultInvalid {
+			return fmt.Errorf("query #%d is invalid", result.Index)
+		}
+	}
+
+	if s.ReturnErrorOnFail {
+		for _, result := range results {
+			if result.failed() {
+				return fmt.Errorf("query #%d returned result '%s'", result.Index, result.Result)
+			}
+		}
+	} else {
+		for _, result := range results {
+			if result.Result == batchResultError {
+				return fmt.Errorf("query #%d could not be processed", result.Index)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) writeReport(results []batchResult) error {
+	if err := s.Batch.Validate(); err != nil {
+		return err
+	}
+
+	file, err := os.Create(s.Batch.ReportFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return writeReport(file, s.Batch.ReportFormat, results)
+}
+
+func writeReport(w io.Writer, format string, results []batchResult) error {
+	switch strings.ToLower(format) {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	case "junit":
+		return writeJUnitReport(w, results)
+	case "csv":
+		return writeCSVReport(w, results)
+	default:
+		return fmt.Errorf("unknown report format '%s'", format)
+	}
+}
+
+func writeCSVReport(w io.Writer, results []batchResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"index", "action", "result", "stid", "messages", "duration-ms"}); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		messages := make([]string, len(result.Messages))
+		for i, msg := range result.Messages {
+			messages[i] = msg.String()
+		}
+		err := writer.Write([]string{strconv.Itoa(result.Index), result.Action, result.Result, result.STID, strings.Join(messages, "; "), strconv.FormatInt(result.DurationMS, 10)})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func writeJUnitReport(w io.Writer, results []batchResult) error {
+	suite := junitTestSuite{Name: "rri", Tests: len(results)}
+	var totalMS int64
+
+	for _, result := range results {
+		messages := make([]string, len(result.Messages))
+		for i, msg := range result.Messages {
+			messages[i] = msg.String()
+		}
+		text := strings.Join(messages, "\n")
+
+		testCase := junitTestCase{
+			Name:      fmt.Sprintf("#%d %s", result.Index, result.Action),
+			ClassName: "rri." + strings.ToLower(result.Action),
+			Time:      formatSeconds(result.DurationMS),
+		}
+		if len(result.STID) > 0 {
+			testCase.SystemOut = "STID: " + result.STID
+		}
+
+		switch result.Result {
+		case batchResultFailure:
+			suite.Failures++
+			testCase.Failure = &junitMessage{Message: "RRI returned result 'failure'", Text: text}
+		case batchResultError, batchResultInvalid:
+			suite.Errors++
+			testCase.Error = &junitMessage{Message: result.Result, Text: text}
+		case batchResultSkipped:
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Text: text}
+		}
+
+		totalMS += result.DurationMS
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Time = formatSeconds(totalMS)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func formatSeconds(ms int64) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', 3, 64)
+}
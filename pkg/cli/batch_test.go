@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"encoding/xml"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunXMLExamples(t *testing.T) {
+	files := make([]string, 0)
+	err := filepath.WalkDir("../../examples/xml", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			s := &Service{Batch: BatchOptions{DryRun: true}}
+			assert.NoError(t, s.HandleFile([]string{file}))
+		})
+	}
+}
+
+func testBatchResults() []batchResult {
+	return []batchResult{
+		{Index: 1, Action: "INFO", Result: batchResultSuccess, STID: "abc-1", DurationMS: 1500},
+		{Index: 2, Action: "DELETE", Result: batchResultFailure, Messages: []batchMessage{{Level: "error", ID: 53000000001, Text: "domain not found"}}, DurationMS: 20},
+		{Index: 3, Action: "CHECK", Result: batchResultInvalid, Messages: []batchMessage{{Level: "error", Text: "missing domain"}}},
+		{Index: 4, Action: "UPDATE", Result: batchResultSkipped},
+	}
+}
+
+func TestWriteCSVReport(t *testing.T) {
+	var sb strings.Builder
+	require.NoError(t, writeReport(&sb, "CSV", testBatchResults()))
+	assert.Equal(t, `index,action,result,stid,messages,duration-ms
+1,INFO,success,abc-1,,1500
+2,DELETE,failure,,error: 53000000001 domain not found,20
+3,CHECK,invalid,,error: missing domain,0
+4,UPDATE,skipped,,,0
+`, sb.String())
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	var sb strings.Builder
+	require.NoError(t, writeReport(&sb, "junit", testBatchResults()))
+	assert.Equal(t, xml.Header+`<testsuites>
+  <testsuite name="rri" tests="4" failures="1" errors="1" skipped="1" time="1.520">
+    <testcase name="#1 INFO" classname="rri.info" time="1.500">
+      <system-out>STID: abc-1</system-out>
+    </testcase>
+    <testcase name="#2 DELETE" classname="rri.delete" time="0.020">
+      <failure message="RRI returned result &#39;failure&#39;">error: 53000000001 domain not found</failure>
+    </testcase>
+    <testcase name="#3 CHECK" classname="rri.check" time="0.000">
+      <error message="invalid">error: missing domain</error>
+    </testcase>
+    <testcase name="#4 UPDATE" classname="rri.update" time="0.000">
+      <skipped></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, sb.String())
+}
+
+func TestBatchOptionsValidate(t *testing.T) {
+	assert.NoError(t, BatchOptions{}.Validate())
+	assert.NoError(t, BatchOptions{ReportFormat: "json", ReportFile: "report.json"}.Validate())
+	assert.EqualError(t, BatchOptions{ReportFormat: "json"}.Validate(), "--report requires --report-file, because responses are printed to stdout")
+
+	assert.EqualError(t, writeReport(io.Discard, "yaml", nil), "unknown report format 'yaml'")
+}
@@ -0,0 +1,267 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
+
+const (
+	bulkStatusSuccess = "success"
+	bulkStatusFailed  = "failed"
+	// bulkStatusSkipped denotes a domain that succeeded in an earlier run.
+	bulkStatusSkipped = "skipped"
+)
+
+type bulkRecord struct {
+	Domain string   `json:"domain"`
+	Status string   `json:"status"`
+	STID   string   `json:"stid,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+func newBulkRecord(result rri.BulkResult) bulkRecord {
+	record := bulkRecord{
+		Domain: result.Domain,
+		Status: bulkStatusFailed,
+		STID:   result.STID,
+	}
+	if result.IsSuccessful() {
+		record.Status = bulkStatusSuccess
+	}
+	if result.Err != nil {
+		record.Errors = append(record.Errors, result.Err.Error())
+	}
+	for _, msg := range result.Errors {
+		record.Errors = append(record.Errors, msg.String())
+	}
+	return record
+}
+
+// bulkState is stored next to the CSV file after every processed domain, so an interrupted run can be resumed.
+type bulkState struct {
+	Action  rri.BulkAction        `json:"action"`
+	Results map[string]bulkRecord `json:"results"`
+}
+
+func loadBulkState(file string, action rri.BulkAction) (*bulkState, error) {
+	state := &bulkState{Action: action, Results: make(map[string]bulkRecord)}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %s", file, err.Error())
+	}
+	if state.Action != action {
+		return nil, fmt.Errorf("state file %s belongs to bulk %s, remove it to start over", file, state.Action)
+	}
+	if state.Results == nil {
+		state.Results = make(map[string]bulkRecord)
+	}
+	return state, nil
+}
+
+// save replaces the state file atomically to not lose progress if the client is killed while writing.
+func (state *bulkState) save(file string) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpFile := file + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, file)
+}
+
+func (state *bulkState) isDone(domain string) bool {
+	record, ok := state.Results[domain]
+	return ok && record.Status == bulkStatusSuccess
+}
+
+func (s *Service) cmdBulk(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("missing bulk action. expect one of %s", strings.Join(bulkActionNames(), ", "))
+	}
+	action, err := rri.ParseBulkAction(args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 2 {
+		return fmt.Errorf("missing csv file")
+	}
+	csvFile := args[1]
+
+	format := "csv"
+	if len(args) > 2 {
+		format = strings.ToLower(args[2])
+	}
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("unknown output format '%s'. expect csv or json", format)
+	}
+
+	opts := rri.BulkDomainsOptions{Client: s.rriClient, Sessions: 1}
+	if len(args) > 3 {
+		sessions, err := strconv.Atoi(args[3])
+		if err != nil || sessions < 1 {
+			return fmt.Errorf("invalid session count '%s'", args[3])
+		}
+		opts.Sessions = sessions
+	}
+	if len(args) > 4 {
+		rate, err := strconv.ParseFloat(args[4], 64)
+		if err != nil || rate < 0 {
+			return fmt.Errorf("invalid rate limit '%s'", args[4])
+		}
+		opts.RateLimit = rate
+	}
+
+	f, err := os.Open(csvFile)
+	if err != nil {
+		return err
+	}
+	rows, err := rri.ReadBulkCSV(f)
+	f.Close() //nolint
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", csvFile, err)
+	}
+
+	if !s.rriClient.IsLoggedIn() {
+		return fmt.Errorf("you need to log in before processing domains")
+	}
+
+	stateFile := csvFile + ".state"
+	state, err := loadBulkState(stateFile, action)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, row := range rows {
+		if !state.isDone(row.Domain) {
+			pending++
+		}
+	}
+	if pending < len(rows) {
+		fmt.Fprintf(os.Stderr, "resuming from %s, %d of %d domains left\n", stateFile, pending, len(rows))
+	}
+	if err := s.confirmBulk(action, pending); err != nil {
+		return err
+	}
+
+	processed := 0
+	opts.Skip = state.isDone
+	opts.Progress = func(result rri.BulkResult) {
+		processed++
+		record := newBulkRecord(result)
+		state.Results[result.Domain] = record
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s: %s\n", processed, pending, result.Domain, record.Status)
+		if err := state.save(stateFile); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to write state file: %s\n", err.Error())
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	results, bulkErr := rri.BulkDomains(ctx, action, rows, opts)
+
+	records := make([]bulkRecord, len(results))
+	failed := 0
+	for i, result := range results {
+		if result.Skipped {
+			records[i] = state.Results[result.Domain]
+			records[i].Status = bulkStatusSkipped
+			continue
+		}
+		records[i] = newBulkRecord(result)
+		if records[i].Status != bulkStatusSuccess {
+			failed++
+		}
+	}
+
+	if format == "json" {
+		err = writeBulkJSON(os.Stdout, records)
+	} else {
+		err = writeBulkCSV(os.Stdout, records)
+	}
+	if err != nil {
+		return err
+	}
+
+	if failed == 0 && bulkErr == nil {
+		if err := os.Remove(stateFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "%d domains have not been processed successfully, run the same command again to retry them\n", failed)
+	}
+
+	if bulkErr != nil {
+		return bulkErr
+	}
+
+	if s.ReturnErrorOnFail && failed > 0 {
+		return fmt.Errorf("failed to process %d domains", failed)
+	}
+
+	return nil
+}
+
+// confirmBulk requires typing the environment name before destructive bulk actions are sent to protected environments.
+func (s *Service) confirmBulk(action rri.BulkAction, count int) error {
+	if !s.Protected || s.AssumeYes || count == 0 || !isDestructiveAction(action.Query(rri.BulkRow{}).Action()) {
+		return nil
+	}
+
+	console.Printlnf("%sProtected environment %q, bulk %s will send %d destructive queries%s", s.colorProtected, s.protectedTarget(), action, count, s.colorEnd)
+	return s.confirmProtected("environment name", s.protectedTarget())
+}
+
+func bulkActionNames() []string {
+	names := make([]string, 0)
+	for _, action := range rri.BulkActions() {
+		names = append(names, string(action))
+	}
+	return names
+}
+
+func writeBulkJSON(w io.Writer, records []bulkRecord) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+func writeBulkCSV(w io.Writer, records []bulkRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"domain", "status", "stid", "errors"}); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := writer.Write([]string{record.Domain, record.Status, record.STID, strings.Join(record.Errors, "; ")}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
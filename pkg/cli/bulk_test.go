@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBulkState(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "domains.csv.state")
+
+	state, err := loadBulkState(file, rri.BulkActionDelete)
+	require.NoError(t, err)
+	assert.Empty(t, state.Results)
+
+	state.Results["denic.de"] = bulkRecord{Domain: "denic.de", Status: bulkStatusSuccess, STID: "abc-1"}
+	state.Results["failed.de"] = bulkRecord{Domain: "failed.de", Status: bulkStatusFailed}
+	require.NoError(t, state.save(file))
+	assert.NoFileExists(t, file+".tmp")
+
+	state, err = loadBulkState(file, rri.BulkActionDelete)
+	require.NoError(t, err)
+	assert.Equal(t, "abc-1", state.Results["denic.de"].STID)
+	assert.True(t, state.isDone("denic.de"))
+	assert.False(t, state.isDone("failed.de"))
+	assert.False(t, state.isDone("unknown.de"))
+
+	_, err = loadBulkState(file, rri.BulkActionUpdate)
+	assert.EqualError(t, err, "state file "+file+" belongs to bulk delete, remove it to start over")
+
+	require.NoError(t, os.WriteFile(file, []byte("{"), 0600))
+	_, err = loadBulkState(file, rri.BulkActionDelete)
+	assert.EqualError(t, err, "invalid state file "+file+": unexpected end of JSON input")
+}
+
+func TestBulkResume(t *testing.T) {
+	csvFile := filepath.Join(t.TempDir(), "domains.csv")
+	stateFile := csvFile + ".state"
+	require.NoError(t, os.WriteFile(csvFile, []byte("domain\ndenic.de\ndönic.de\nfailed.de\n"), 0600))
+
+	var mutex sync.Mutex
+	sent := make([]string, 0)
+	fail := true
+	handler := func(user string, session *rri.Session, query *rri.Query) (*rri.Response, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		domain := query.FirstField(rri.QueryFieldNameDomainIDN)
+		sent = append(sent, domain)
+		if domain == "failed.de" && fail {
+			return rri.NewResponseWithError(rri.ResultFailure, nil, rri.NewBusinessMessage(53000000001, "failed")), nil
+		}
+		return rri.NewResponse(rri.ResultSuccess, nil), nil
+	}
+
+	withMockService(t, handler, func(s *Service) {
+		require.NoError(t, s.cmdBulk([]string{"delete", csvFile, "json"}))
+		assert.ElementsMatch(t, []string{"denic.de", "dönic.de", "failed.de"}, sent)
+
+		state, err := loadBulkState(stateFile, rri.BulkActionDelete)
+		require.NoError(t, err)
+		assert.Equal(t, bulkStatusSuccess, state.Results["dönic.de"].Status)
+		assert.Equal(t, bulkStatusFailed, state.Results["failed.de"].Status)
+		assert.Equal(t, []string{"53000000001 failed"}, state.Results["failed.de"].Errors)
+
+		// the state file of another action is not reused
+		assert.EqualError(t, s.cmdBulk([]string{"chholder", csvFile}), "state file "+stateFile+" belongs to bulk delete, remove it to start over")
+
+		// only the failed domain is sent again and the state file is removed after all domains succeeded
+		sent = sent[:0]
+		fail = false
+		require.NoError(t, s.cmdBulk([]string{"delete", csvFile, "json"}))
+		assert.Equal(t, []string{"failed.de"}, sent)
+		assert.NoFileExists(t, stateFile)
+	})
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/DENICeG/go-rriclient/pkg/parser"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
+
+type checkBulkRecord struct {
+	Domain    string   `json:"domain"`
+	DomainACE string   `json:"domainAce"`
+	Status    string   `json:"status"`
+	Available bool     `json:"available"`
+	STID      string   `json:"stid,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+func newCheckBulkRecord(result rri.CheckDomainResult) checkBulkRecord {
+	record := checkBulkRecord{
+		Domain:    result.Domain,
+		DomainACE: result.DomainACE,
+		Status:    string(result.Status),
+		Available: result.IsAvailable(),
+		STID:      result.STID,
+	}
+	if result.Err != nil {
+		record.Errors = append(record.Errors, result.Err.Error())
+	}
+	for _, msg := range result.Errors {
+		record.Errors = append(record.Errors, msg.String())
+	}
+	return record
+}
+
+func (s *Service) cmdCheckBulk(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("missing domain list file")
+	}
+
+	format := "csv"
+	if len(args) > 1 {
+		format = strings.ToLower(args[1])
+	}
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("unknown output format '%s'. expect csv or json", format)
+	}
+
+	opts := rri.CheckDomainsOptions{Client: s.rriClient, Sessions: 1}
+	if len(args) > 2 {
+		sessions, err := strconv.Atoi(args[2])
+		if err != nil || sessions < 1 {
+			return fmt.Errorf("invalid session count '%s'", args[2])
+		}
+		opts.Sessions = sessions
+	}
+	if len(args) > 3 {
+		rate, err := strconv.ParseFloat(args[3], 64)
+		if err != nil || rate < 0 {
+			return fmt.Errorf("invalid rate limit '%s'", args[3])
+		}
+		opts.RateLimit = rate
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0)
+	for _, line := range parser.SplitLines(data) {
+		name := strings.TrimSpace(string(line))
+		if len(name) == 0 || strings.HasPrefix(name, "#") {
+			continue
+		}
+		names = append(names, name)
+	}
+
+	if !s.rriClient.IsLoggedIn() {
+		return fmt.Errorf("you need to log in before checking domains")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	results, checkErr := rri.CheckDomains(ctx, names, opts)
+
+	if format == "json" {
+		err = writeCheckBulkJSON(os.Stdout, results)
+	} else {
+		err = writeCheckBulkCSV(os.Stdout, results)
+	}
+	if err != nil {
+		return err
+	}
+
+	if checkErr != nil {
+		return checkErr
+	}
+
+	if s.ReturnErrorOnFail {
+		for _, result := range results {
+			if result.Status == rri.DomainStatusFailed {
+				return fmt.Errorf("failed to check domain '%s'", result.Domain)
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeCheckBulkJSON(w io.Writer, results []rri.CheckDomainResult) error {
+	records := make([]checkBulkRecord, len(results))
+	for i, result := range results {
+		records[i] = newCheckBulkRecord(result)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+func writeCheckBulkCSV(w io.Writer, results []rri.CheckDomainResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"domain", "domain-ace", "status", "available", "stid", "errors"}); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		record := newCheckBulkRecord(result)
+		err := writer.Write([]string{record.Domain, record.DomainACE, record.Status, strconv.FormatBool(record.Available), record.STID, strings.Join(record.Errors, "; ")})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
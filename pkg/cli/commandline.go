@@ -1,8 +1,6 @@
 package cli
 
 import (
-	"embed"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -10,22 +8,37 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/DENICeG/go-rriclient/pkg/highlight"
-
 	"github.com/DENICeG/go-rriclient/internal/env"
+	"github.com/DENICeG/go-rriclient/pkg/highlight"
 	"github.com/DENICeG/go-rriclient/pkg/preset"
 	"github.com/DENICeG/go-rriclient/pkg/rri"
 
 	"github.com/DENICeG/go-console/v2"
 	"github.com/DENICeG/go-console/v2/commandline"
+	"golang.org/x/term"
 )
 
 type Service struct {
-	rriClient                  *rri.Client
-	completion                 *domainOrHandleCompletion
-	ReturnErrorOnFail          bool
+	rriClient         *rri.Client
+	completion        *domainOrHandleCompletion
+	ReturnErrorOnFail bool
+	// DataFile denotes a JSON or YAML document to read contact and domain data from for create and update commands without --from argument. Use "-" for stdin.
+	DataFile string
+	// Output denotes how responses are printed.
+	Output OutputMode
+	// ForcedOutput denotes an output mode passed on the command line that takes precedence over the output mode of environments opened later.
+	ForcedOutput OutputMode
+	// terminalColors denotes whether stdout supports escape sequences, colors are never enabled otherwise.
+	terminalColors             bool
+	colors                     bool
+	colorTheme                 string
+	Batch                      BatchOptions
+	variables                  map[string]string
+	commandHistory             *commandHistory
+	historyFile                string
 	customCommands             []customCommand
 	colorPromptRRI             string
 	colorPromptUser            string
@@ -36,43 +49,90 @@ type Service struct {
 	colorErrorResponseMessage  string
 	colorTechnicalErrorMessage string
 	colorInnerError            string
+	colorProtected             string
 	colorEnd                   string
 	signSend                   string
 	signReceive                string
-	presets                    preset.Data
-	embedFS                    embed.FS
+	presets                    *preset.Data
 	presetCompletion           *PresetCompletion
+	// PresetDir denotes the directory new presets are saved to.
+	PresetDir string
+	// EnvName denotes the name of the current environment. Custom commands disabled for it are not available.
+	EnvName string
+	// EnvReader is used to open other environments with the env and session commands.
+	EnvReader *env.Reader
+	// Connect opens the connections of the env, connect and session commands.
+	Connect ConnectFunc
+	// Protected requires confirmation before destructive queries are sent.
+	Protected bool
+	// AssumeYes sends destructive queries to protected environments without confirmation.
+	AssumeYes bool
+	// Verbose prints all sent and received queries.
+	Verbose bool
+	// Precheck checks the name servers of CREATE, UPDATE and CHPROV queries before they are sent.
+	Precheck bool
+	// UseEditor enables editing presets in $VISUAL or $EDITOR instead of the terminal.
+	UseEditor bool
+	// lastResponse denotes the last received response that is evaluated by scripts.
+	lastResponse *rri.Response
+	// sessions contains all open connections of the interactive command line, session is the active one.
+	sessions []*session
+	session  *session
+	// transcript records all commands and queries if not nil.
+	transcript *transcript
+	// expiredSessions collects errors of the keep-alive routines that are printed before the next command.
+	expiredSessions      []error
+	expiredSessionsMutex sync.Mutex
 }
 
 // New returns a new Service instance.
-func New(client *rri.Client, presets preset.Data, presetCompletion *PresetCompletion, embedFS embed.FS) *Service {
+func New(client *rri.Client, presets *preset.Data, presetCompletion *PresetCompletion) *Service {
 	result := &Service{
-		rriClient:                  client,
-		completion:                 NewCompletion(),
-		colorPromptRRI:             "\033[1;34m",
-		colorPromptUser:            "\033[1;32m",
-		colorPromptHost:            "\033[1;32m",
-		colorSendRaw:               "\033[0;94m",
-		colorReceiveRaw:            "\033[0;96m",
-		colorSuccessResponse:       "\033[0;29m",
-		colorErrorResponseMessage:  "\033[0;91m",
-		colorTechnicalErrorMessage: "\033[1;91m",
-		colorInnerError:            "\033[2;91m",
-		colorEnd:                   "\033[0m",
-		signSend:                   "-->",
-		signReceive:                "<--",
-		presets:                    presets,
-		embedFS:                    embedFS,
-		presetCompletion:           presetCompletion,
-	}
-
-	if !console.SupportsColors() {
-		result.disableColors()
+		rriClient:        client,
+		completion:       NewCompletion(),
+		commandHistory:   &commandHistory{},
+		signSend:         "-->",
+		signReceive:      "<--",
+		presets:          presets,
+		presetCompletion: presetCompletion,
+		Output:           OutputKV,
+		colorTheme:       highlight.DefaultStyle,
+		// do not write escape sequences when output is piped to other programs
+		terminalColors: console.SupportsColors() && term.IsTerminal(int(os.Stdout.Fd())),
+	}
+	if result.terminalColors {
+		result.enableColors()
+	}
+
+	if client != nil {
+		result.attachClient(client)
+	}
+
+	result.completion.currentRegAccID = func() (int, error) {
+		if result.rriClient == nil || !result.rriClient.IsLoggedIn() {
+			return 0, fmt.Errorf("not logged in")
+		}
+		return result.rriClient.CurrentRegAccID()
 	}
 
 	return result
 }
 
+func (s *Service) enableColors() {
+	s.colorPromptRRI = "\033[1;34m"
+	s.colorPromptUser = "\033[1;32m"
+	s.colorPromptHost = "\033[1;32m"
+	s.colorSendRaw = "\033[0;94m"
+	s.colorReceiveRaw = "\033[0;96m"
+	s.colorSuccessResponse = "\033[0;29m"
+	s.colorErrorResponseMessage = "\033[0;91m"
+	s.colorTechnicalErrorMessage = "\033[1;91m"
+	s.colorInnerError = "\033[2;91m"
+	s.colorProtected = "\033[1;93m"
+	s.colorEnd = "\033[0m"
+	s.colors = true
+}
+
 func (s *Service) disableColors() {
 	s.colorPromptRRI = ""
 	s.colorPromptUser = ""
@@ -83,7 +143,9 @@ func (s *Service) disableColors() {
 	s.colorErrorResponseMessage = ""
 	s.colorTechnicalErrorMessage = ""
 	s.colorInnerError = ""
+	s.colorProtected = ""
 	s.colorEnd = ""
+	s.colors = false
 }
 
 func (s *Service) PrintColorsAndSigns() {
@@ -104,24 +166,28 @@ func (s *Service) PrintColorsAndSigns() {
 	printColor("ColorErrorResponseMessage", s.colorErrorResponseMessage)
 	printColor("ColorTechnicalErrorMessage", s.colorTechnicalErrorMessage)
 	printColor("ColorInnerError", s.colorInnerError)
+	printColor("ColorProtected", s.colorProtected)
 	printSign("SignSend", s.signSend)
 	printSign("SignReceive", s.signReceive)
 }
 
 func (s *Service) Run(confDir string, cmd []string) error {
-	var err error
-	s.customCommands, err = readCustomCommands(filepath.Join(confDir, "custom-commands"))
+	customCommands, err := readCustomCommands(filepath.Join(confDir, "custom-commands"), func(err error) {
+		console.Println("Skipped custom command:", err.Error())
+	})
 	if err != nil {
 		if !os.IsNotExist(err) {
 			console.Println("Failed to import custom commands:", err.Error())
 		}
 	}
+	// commands disabled for the current environment are rejected on execution, because the environment can be switched
+	s.customCommands = append(s.customCommands, customCommands...)
 
 	cli := s.prepareCLI(s.presetCompletion)
 
 	if len(cmd) > 0 {
 		// exec command that has been passed via command line and return result
-		return cli.ExecCommand(cmd[0], cmd[1:])
+		return s.execCommand(cli, cmd)
 	}
 
 	console.Println("Interactive RRI Command Line")
@@ -129,7 +195,7 @@ func (s *Service) Run(confDir string, cmd []string) error {
 	console.Println("  use tab for auto-completion and arrow keys for history")
 
 	// start interactive command line loop
-	if err := cli.Run(); err != nil {
+	if err := s.runInteractive(cli); err != nil {
 		if errors.Is(err, commandline.ErrCtrlC) {
 			console.Println()
 			return nil
@@ -139,63 +205,46 @@ func (s *Service) Run(confDir string, cmd []string) error {
 	return nil
 }
 
-type customCommand struct {
-	DisabledFor []string           `json:"disable-for-env"`
-	Name        string             `json:"name"`
-	Cmd         string             `json:"cmd"`
-	Description string             `json:"description"`
-	Action      string             `json:"action"`
-	Args        []customCommandArg `json:"args"`
-}
-
-type customCommandArg struct {
-	Name  string `json:"name"`
-	Type  string `json:"type"`
-	Field string `json:"field"`
-	Value string `json:"value"`
-}
-
-func (arg customCommandArg) IsInputParameter() bool {
-	return strings.ToLower(arg.Type) == "domain"
-}
+// runInteractive reads and executes commands like commandline.Environment.Run, but uses the persistent command history.
+func (s *Service) runInteractive(cli *commandline.Environment) error {
+	for {
+		cmd, err := commandline.ReadCommand(cli.Prompt(), &commandline.ReadCommandOptions{
+			GetHistoryEntry:      s.commandHistory.GetHistoryEntry,
+			GetCompletionOptions: cli.GetCompletionOptions,
+			PrintOptionsHandler:  cli.PrintOptions,
+		})
+		if err != nil {
+			return err
+		}
 
-func readCustomCommands(dir string) ([]customCommand, error) {
-	files, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, err
-	}
+		cmd, resolved, err := s.resolveHistoryReference(cmd)
+		if err != nil {
+			cli.ErrorHandler("", nil, err) //nolint
+			continue
+		}
+		if resolved {
+			console.Println(commandline.GetCommandString(cmd))
+		}
 
-	result := make([]customCommand, 0)
-	for _, f := range files {
-		if !f.IsDir() {
-			data, err := os.ReadFile(filepath.Join(dir, f.Name()))
-			if err != nil {
-				return nil, err
-			}
-			var cmd customCommand
-			if err := json.Unmarshal(data, &cmd); err != nil {
-				return nil, fmt.Errorf("could not import %q: %s", f.Name(), err.Error())
-			}
+		s.printExpiredSessions()
+		if len(cmd) == 0 || len(cmd[0]) == 0 {
+			continue
+		}
 
-			if len(cmd.Name) == 0 {
-				cmd.Name = f.Name()
-			}
+		s.putCommandHistory(cmd)
+		err = s.execCommand(cli, cmd)
 
-			if len(cmd.Cmd) == 0 {
-				cmd.Cmd = f.Name()
-			}
+		if saveErr := s.saveHistory(); saveErr != nil {
+			s.ErrorPrinter(fmt.Errorf("failed to save history: %w", saveErr))
+		}
 
-			for i := range cmd.Args {
-				if strings.ToLower(cmd.Args[i].Type) == "domain" && len(cmd.Args[i].Field) == 0 {
-					cmd.Args[i].Field = "domain"
-					cmd.Args[i].Name = "domain"
-				}
+		if err != nil {
+			if errors.Is(err, commandline.ErrExit) {
+				return nil
 			}
-			result = append(result, cmd)
+			cli.ErrorHandler(cmd[0], cmd[1:], err) //nolint
 		}
 	}
-
-	return result, nil
 }
 
 func (s *Service) prepareCLI(presetCompletion *PresetCompletion) *commandline.Environment {
@@ -206,7 +255,12 @@ func (s *Service) prepareCLI(presetCompletion *PresetCompletion) *commandline.En
 		if s.rriClient.IsLoggedIn() {
 			user = fmt.Sprintf("%s%s%s@", s.colorPromptUser, s.rriClient.CurrentUser(), s.colorEnd)
 		}
-		host = fmt.Sprintf("%s%s%s", s.colorPromptHost, s.rriClient.RemoteAddress(), s.colorEnd)
+		hostColor := s.colorPromptHost
+		if s.Protected {
+			// make protected environments like production easy to recognize
+			hostColor = s.colorProtected
+		}
+		host = fmt.Sprintf("%s%s%s", hostColor, s.rriClient.RemoteAddress(), s.colorEnd)
 		return fmt.Sprintf("%s{%s%s}%s", prefix, user, host, suffix)
 	}
 
@@ -220,42 +274,60 @@ func (s *Service) prepareCLI(presetCompletion *PresetCompletion) *commandline.En
 
 	cli.RegisterCommand(commandline.NewCustomCommand("login", nil, s.cmdLogin))
 	cli.RegisterCommand(commandline.NewCustomCommand("logout", nil, s.cmdLogout))
+	cli.RegisterCommand(commandline.NewCustomCommand("env", commandline.NewFixedArgCompletion(s.envNameCompletion()), s.cmdEnv))
+	cli.RegisterCommand(commandline.NewCustomCommand("connect", nil, s.cmdConnect))
+	cli.RegisterCommand(commandline.NewCustomCommand("session", s.sessionCompletion, s.cmdSession))
 
+	authInfo1Grammar := newArgGrammar(s.completion.histDomains, noArgCompletion, noArgCompletion)
 	s.registerSwitchCommand(cli, "create", cmdSwitches{
-		Domain:    s.cmdCreateDomain,
-		Handle:    s.cmdCreateHandle,
-		AuthInfo1: s.cmdCreateAuthInfo1,
+		Domain:        s.cmdCreateDomain,
+		Handle:        s.cmdCreateHandle,
+		AuthInfo1:     s.cmdCreateAuthInfo1,
+		DomainArgs:    s.domainDataGrammar(),
+		AuthInfo1Args: &authInfo1Grammar,
 	})
 	s.registerSwitchCommand(cli, "check", cmdSwitches{
 		Domain: s.newDomainQueryCommand(rri.NewCheckDomainQuery),
 		Handle: s.newHandleQueryCommand(rri.NewCheckHandleQuery),
 	})
+	cli.RegisterCommand(commandline.NewCustomCommand("check-bulk", commandline.NewFixedArgCompletion(commandline.NewLocalFileSystemArgCompletion(true), commandline.NewOneOfArgCompletion("csv", "json")), s.cmdCheckBulk))
 	s.registerSwitchCommand(cli, "info", cmdSwitches{
 		Domain: s.newDomainQueryCommand(rri.NewInfoDomainQuery),
 		Handle: s.newHandleQueryCommand(rri.NewInfoHandleQuery),
 	})
 	s.registerSwitchCommand(cli, "update", cmdSwitches{
-		Domain: s.cmdUpdateDomain,
+		Domain:     s.cmdUpdateDomain,
+		DomainArgs: s.domainDataGrammar(),
 	})
 
 	s.registerDomainCommand(cli, "delete", s.newDomainQueryCommand(rri.NewDeleteDomainQuery))
 	s.registerDomainCommand(cli, "restore", s.newDomainQueryCommand(rri.NewRestoreDomainQuery))
 	s.registerDomainCommand(cli, "transit", s.cmdTransit, commandline.NewOneOfArgCompletion("disconnect", "connect"))
-	s.registerDomainCommand(cli, "chholder", s.cmdChangeHolder)
-	s.registerDomainCommand(cli, "chprov", s.cmdChangeProvider)
+	cli.RegisterCommand(commandline.NewCustomCommand("chholder", s.domainDataGrammar().completionHandler(), s.cmdChangeHolder))
+	cli.RegisterCommand(commandline.NewCustomCommand("chprov", s.domainDataGrammar(noArgCompletion).completionHandler(), s.cmdChangeProvider))
+	cli.RegisterCommand(commandline.NewCustomCommand("transfer", s.domainDataGrammar(noArgCompletion).completionHandler(), s.cmdTransfer))
+	cli.RegisterCommand(commandline.NewCustomCommand("bulk", commandline.NewFixedArgCompletion(newEnumArgCompletion(rri.BulkActions()), commandline.NewLocalFileSystemArgCompletion(true), commandline.NewOneOfArgCompletion("csv", "json")), s.cmdBulk))
 
-	s.registerDomainCommand(cli, "queue-read", s.cmdQueueRead)
-	s.registerDomainCommand(cli, "queue-delete", s.cmdQueueDelete)
+	cli.RegisterCommand(commandline.NewCustomCommand("queue-read", nil, s.cmdQueueRead))
+	cli.RegisterCommand(commandline.NewCustomCommand("queue-delete", nil, s.cmdQueueDelete))
 
 	// register custom commands
 	for _, cmd := range s.customCommands {
 		s.registerCustomCommand(cli, cmd)
 	}
 
-	cli.RegisterCommand(commandline.NewCustomCommand("raw", nil, s.cmdRaw))
+	cli.RegisterCommand(commandline.NewCustomCommand("raw", commandline.NewFixedArgCompletion(rawQueryCompletion), s.cmdRaw))
 	cli.RegisterCommand(commandline.NewCustomCommand("file", commandline.NewFixedArgCompletion(commandline.NewLocalFileSystemArgCompletion(true)), s.HandleFile))
+	cli.RegisterCommand(commandline.NewCustomCommand("run", commandline.NewFixedArgCompletion(commandline.NewLocalFileSystemArgCompletion(true)), func(args []string) error {
+		return s.cmdRun(cli, args)
+	}))
+	cli.RegisterCommand(commandline.NewCustomCommand("convert", commandline.NewFixedArgCompletion(commandline.NewLocalFileSystemArgCompletion(true), newEnumArgCompletion([]rri.Format{rri.FormatKV, rri.FormatXML}), commandline.NewLocalFileSystemArgCompletion(true)), s.cmdConvert))
 
+	cli.RegisterCommand(commandline.NewCustomCommand("history", nil, s.cmdHistory))
 	cli.RegisterCommand(commandline.NewCustomCommand("verbose", nil, s.cmdVerbose))
+	cli.RegisterCommand(commandline.NewCustomCommand("precheck", commandline.NewFixedArgCompletion(commandline.NewOneOfArgCompletion("on", "off")), s.cmdPrecheck))
+	cli.RegisterCommand(commandline.NewCustomCommand("record", s.recordCompletion, s.cmdRecord))
+	cli.RegisterCommand(commandline.NewCustomCommand("output", commandline.NewFixedArgCompletion(newEnumArgCompletion(OutputModes())), s.cmdOutput))
 	cli.RegisterCommand(commandline.NewCustomCommand("preset", s.presetCompletion.GetCompletionOptions, s.HandlePreset))
 
 	return cli
@@ -273,40 +345,69 @@ func (s *Service) cmdHelp(args []string) error {
 		{},
 		{Cmd: []string{"login"}, Args: []string{"user", "password"}, Desc: "log in to a RRI account"},
 		{Cmd: []string{"logout"}, Args: nil, Desc: "log out from the current RRI account"},
+		{Cmd: []string{"env"}, Args: []string{"name"}, Desc: "close the current connection and connect to another environment"},
+		{Cmd: []string{"connect"}, Args: []string{"host", "user"}, Desc: "close the current connection and connect to another RRI host"},
+		{Cmd: []string{"session", "list"}, Args: nil, Desc: "list all open sessions"},
+		{Cmd: []string{"session", "open"}, Args: []string{"env"}, Desc: "connect to an environment and keep the current session open"},
+		{Cmd: []string{"session", "use"}, Args: []string{"name"}, Desc: "switch to another open session"},
+		{Cmd: []string{"session", "close"}, Args: []string{"name"}, Desc: "close a session"},
 		{},
-		{Cmd: []string{"create", "handle"}, Args: []string{"domain"}, Desc: "send a CREATE command for a specific handle"},
+		{Cmd: []string{"create", "handle"}, Args: []string{"domain"}, Desc: "send a CREATE command for a specific handle. read data with --from file|-"},
 		{Cmd: []string{"check", "handle"}, Args: []string{"domain"}, Desc: "send a CHECK command for a specific handle"},
 		{Cmd: []string{"info", "handle"}, Args: []string{"domain"}, Desc: "send an INFO command for a specific handle"},
 		{},
-		{Cmd: []string{"create", "domain"}, Args: []string{"domain"}, Desc: "send a CREATE command for a new domain"},
+		{Cmd: []string{"create", "domain"}, Args: []string{"domain"}, Desc: "send a CREATE command for a new domain. read data with --from file|-"},
 		{Cmd: []string{"check", "domain"}, Args: []string{"domain"}, Desc: "send a CHECK command for a specific domain"},
+		{Cmd: []string{"check-bulk"}, Args: []string{"file", "csv|json", "sessions", "rate"}, Desc: "send CHECK commands for all domains listed in a file and print the results"},
 		{Cmd: []string{"info", "domain"}, Args: []string{"domain"}, Desc: "send an INFO command for a specific domain"},
-		{Cmd: []string{"update", "domain"}, Args: []string{"domain"}, Desc: "send an UPDATE command for a specific domain"},
-		{Cmd: []string{"chholder"}, Args: []string{"domain"}, Desc: "send an CHHOLDER command for a specific domain"},
+		{Cmd: []string{"update", "domain"}, Args: []string{"domain"}, Desc: "send an UPDATE command for a specific domain. read data with --from file|-"},
+		{Cmd: []string{"chholder"}, Args: []string{"domain"}, Desc: "send an CHHOLDER command for a specific domain. read data with --from file|-"},
 		{},
 		{Cmd: []string{"delete"}, Args: []string{"domain"}, Desc: "send a DELETE command for a specific domain"},
 		{Cmd: []string{"restore"}, Args: []string{"domain"}, Desc: "send a RESTORE command for a specific domain"},
 		{Cmd: []string{"transit"}, Args: []string{"domain"}, Desc: "send a TRANSIT command for a specific domain"},
 		{Cmd: []string{"create", "authinfo1"}, Args: []string{"domain", "secret", "expire"}, Desc: "send a CREATE-AUTHINFO1 command for a specific domain"},
-		{Cmd: []string{"chprov"}, Args: []string{"domain", "secret"}, Desc: "send a CHPROV command for a specific domain"},
+		{Cmd: []string{"chprov"}, Args: []string{"domain", "secret"}, Desc: "send a CHPROV command for a specific domain. read data with --from file|-"},
+		{Cmd: []string{"transfer"}, Args: []string{"domain", "secret"}, Desc: "check a domain, send CHPROV and wait for the queue message. read data with --from file|-"},
+		{Cmd: []string{"bulk"}, Args: []string{"update|chholder|delete", "csv", "csv|json", "sessions", "rate"}, Desc: "send a command for every domain of a csv file and print the results. resumes interrupted runs"},
 		{},
 		{Cmd: []string{"queue-read"}, Args: nil, Desc: "send a QUEUE-READ command"},
 		{Cmd: []string{"queue-delete"}, Args: []string{"msgid"}, Desc: "sends a QUEUE-DELETE command for a specific message id."},
 		{},
 		{Cmd: []string{"raw"}, Args: nil, Desc: "enter a raw query and send it"},
 		{Cmd: []string{"file"}, Args: []string{"path"}, Desc: "process a query file as accepted by flag --file"},
+		{Cmd: []string{"run"}, Args: []string{"path"}, Desc: "run a script with variables, conditions, loops and assertions"},
+		{Cmd: []string{"convert"}, Args: []string{"path", "kv|xml", "output-path"}, Desc: "convert the queries of a file between KV and XML syntax"},
 		{},
+		{Cmd: []string{"history"}, Args: []string{"search"}, Desc: "list or search previous commands. use !n to re-run command n and !! for the last one"},
 		{Cmd: []string{"verbose"}, Args: nil, Desc: "toggle verbose mode"},
+		{Cmd: []string{"precheck"}, Args: []string{"on|off"}, Desc: "check name servers before CREATE, UPDATE and CHPROV queries are sent"},
+		{Cmd: []string{"precheck"}, Args: []string{"domain", "nserver-1", "nserver-2"}, Desc: "check whether name servers are ready for the delegation of a domain"},
+		{Cmd: []string{"record", "start"}, Args: []string{"file", "markdown|jsonl|queries"}, Desc: "record all commands, queries and responses to a transcript file"},
+		{Cmd: []string{"record", "stop"}, Args: nil, Desc: "stop recording and close the transcript file"},
+		{Cmd: []string{"output"}, Args: []string{"kv|json|yaml|table"}, Desc: "show or set how responses are printed"},
 		{},
 		{Cmd: []string{"preset"}, Args: []string{"preset-name"}, Desc: "Execute a preset, that can be edited by the user"},
+		{Cmd: []string{"preset"}, Args: []string{"preset-name", "--save", "name"}, Desc: "Edit a preset and save it as new preset instead of sending it. The query is not validated"},
 	}
 
 	if len(s.customCommands) > 0 {
-		head := commands[:25]
-		tail := make([]customCmd, 6)
-		copy(tail, commands[25:])
+		// custom commands are listed right before the raw command
+		customIndex := len(commands)
+		for i, c := range commands {
+			if len(c.Cmd) > 0 && c.Cmd[0] == "raw" {
+				customIndex = i
+				break
+			}
+		}
+		head := commands[:customIndex]
+		tail := make([]customCmd, len(commands)-customIndex)
+		copy(tail, commands[customIndex:])
 		commands = head
 		for _, cmd := range s.customCommands {
+			if cmd.IsDisabledFor(s.EnvName) {
+				continue
+			}
 			args := make([]string, 0)
 			for _, arg := range cmd.Args {
 				if arg.IsInputParameter() {
@@ -437,26 +538,36 @@ type cmdSwitches struct {
 	Domain    commandline.ExecCommandHandler
 	Handle    commandline.ExecCommandHandler
 	AuthInfo1 commandline.ExecCommandHandler
+	// DomainArgs, HandleArgs and AuthInfo1Args describe the arguments following the type for completion. Defaults to a single domain or handle.
+	DomainArgs    *argGrammar
+	HandleArgs    *argGrammar
+	AuthInfo1Args *argGrammar
 }
 
 func (s *Service) registerSwitchCommand(cle *commandline.Environment, name string, switches cmdSwitches) {
 	// assemble arg completion
 	types := make([]string, 0)
+	grammars := make(map[string]argGrammar)
+	addType := func(t string, grammar *argGrammar, defaultArg commandline.ArgCompletion) {
+		types = append(types, t)
+		if grammar != nil {
+			grammars[t] = *grammar
+		} else {
+			grammars[t] = newArgGrammar(defaultArg)
+		}
+	}
 	if switches.Domain != nil {
-		types = append(types, "domain")
+		addType("domain", switches.DomainArgs, s.completion.histDomains)
 	}
 	if switches.Handle != nil {
-		types = append(types, "handle")
+		addType("handle", switches.HandleArgs, s.completion.handles())
 	}
 	if switches.AuthInfo1 != nil {
-		types = append(types, "authinfo1")
+		addType("authinfo1", switches.AuthInfo1Args, s.completion.histDomains)
 	}
 
 	cle.RegisterCommand(commandline.NewCustomCommand(name,
-		commandline.NewFixedArgCompletion(
-			commandline.NewOneOfArgCompletion(types...),
-			s.completion,
-		),
+		newSwitchCompletion(grammars),
 		func(args []string) error {
 			if len(args) == 0 {
 				return fmt.Errorf("missing command type")
@@ -476,8 +587,8 @@ func (s *Service) registerSwitchCommand(cle *commandline.Environment, name strin
 				return switches.AuthInfo1(args[1:])
 			}
 
-			// try to guess type from first parameter
-			if rri.IsDomainName(args[0]) {
+			// try to guess type from first parameter, invalid domain names are rejected with a precise error by the domain command
+			if strings.HasSuffix(strings.ToLower(strings.TrimSuffix(args[0], ".")), ".de") {
 				if switches.Domain != nil {
 					return switches.Domain(args)
 				}
@@ -513,18 +624,41 @@ func (s *Service) registerDomainCommand(cle *commandline.Environment, name strin
 	cle.RegisterCommand(commandline.NewCustomCommand(name, commandline.NewFixedArgCompletion(argCompletionHandlers...), commandHandler))
 }
 
+// domainDataGrammar returns the argument grammar of commands reading domain data, e.g. {domain} {holder} {general-request} {abuse-contact} {nserver-1} ...
+func (s *Service) domainDataGrammar(additionalArgs ...commandline.ArgCompletion) *argGrammar {
+	args := []commandline.ArgCompletion{s.completion.histDomains}
+	args = append(args, additionalArgs...)
+	args = append(args, s.completion.handles(), s.completion.handles(), s.completion.handles(), noArgCompletion)
+	grammar := newVariadicArgGrammar(args...)
+	return &grammar
+}
+
 func (s *Service) newDomainQueryCommand(f func(domain string) *rri.Query) commandline.ExecCommandHandler {
 	return func(args []string) error {
 		if len(args) < 1 {
 			return fmt.Errorf("missing domain name")
 		}
 
-		_, err := s.processQuery(f(args[0]))
-		s.completion.PutDomain(args[0])
+		domain, err := parseDomainArg(args[0])
+		if err != nil {
+			return err
+		}
+
+		_, err = s.processQuery(f(domain))
+		s.completion.PutDomain(domain)
 		return err
 	}
 }
 
+// parseDomainArg returns the normalized IDN form of a domain name argument or the reason why DENIC does not permit it.
+func parseDomainArg(str string) (string, error) {
+	domain, err := rri.ParseDomain(str)
+	if err != nil {
+		return "", err
+	}
+	return domain.IDN(), nil
+}
+
 func (s *Service) newHandleQueryCommand(f func(handle rri.DenicHandle) *rri.Query) commandline.ExecCommandHandler {
 	return func(args []string) error {
 		if len(args) < 1 {
@@ -542,41 +676,6 @@ func (s *Service) newHandleQueryCommand(f func(handle rri.DenicHandle) *rri.Quer
 	}
 }
 
-func (s *Service) registerCustomCommand(cli *commandline.Environment, cmd customCommand) {
-	clArgs := make([]commandline.ArgCompletion, 0)
-	for _, arg := range cmd.Args {
-		if strings.EqualFold(arg.Type, "domain") {
-			clArgs = append(clArgs, s.completion.histDomains)
-		}
-	}
-
-	cli.RegisterCommand(commandline.NewCustomCommand(cmd.Cmd, commandline.NewFixedArgCompletion(clArgs...), func(args []string) error {
-		fields := rri.NewQueryFieldList()
-		argIndex := 0
-		for _, arg := range cmd.Args {
-			var inValue string
-			if arg.IsInputParameter() {
-				if len(args) <= argIndex {
-					return fmt.Errorf("missing argument '%s'", arg.Name)
-				}
-				inValue = args[argIndex]
-				argIndex++
-			}
-
-			switch strings.ToLower(arg.Type) {
-			case "domain":
-				fields.Add(rri.QueryFieldName(arg.Field), inValue)
-			case "const":
-				fields.Add(rri.QueryFieldName(arg.Field), arg.Value)
-			}
-		}
-		// TODO fill history
-		query := rri.NewQuery(rri.LatestVersion, rri.QueryAction(cmd.Action), fields, nil)
-		_, err := s.processQuery(query)
-		return err
-	}))
-}
-
 func (s *Service) RawQueryPrinter(msg string, isOutgoing bool) {
 	if isOutgoing {
 		console.Printlnf("%s %s%q%s", s.signSend, s.colorSendRaw, rri.CensorRawMessage(msg), s.colorEnd)
@@ -589,18 +688,58 @@ func (s *Service) ErrorPrinter(err error) {
 	console.Printlnf("%sERR: %s%s", s.colorInnerError, err.Error(), s.colorEnd)
 }
 
-func (s *Service) processQuery(query *rri.Query) (bool, error) {
-	res, err := s.rriClient.SendQuery(query)
-	if err != nil {
-		return false, fmt.Errorf("failed to send query: %w", err)
+// attachClient installs the printers of the service in client.
+func (s *Service) attachClient(client *rri.Client) {
+	client.RawQueryPrinter = s.rawQueryHandler
+	client.RawExchangeHandler = s.rawExchangeHandler
+	client.InnerErrorPrinter = s.innerErrorHandler
+	client.SessionExpiredHandler = s.sessionExpiredHandler
+}
+
+// sessionExpiredHandler is called from keep-alive routines and defers the message to not disturb the prompt.
+func (s *Service) sessionExpiredHandler(err error) {
+	s.expiredSessionsMutex.Lock()
+	defer s.expiredSessionsMutex.Unlock()
+	s.expiredSessions = append(s.expiredSessions, err)
+}
+
+// printExpiredSessions prints the errors collected by sessionExpiredHandler.
+func (s *Service) printExpiredSessions() {
+	s.expiredSessionsMutex.Lock()
+	errs := s.expiredSessions
+	s.expiredSessions = nil
+	s.expiredSessionsMutex.Unlock()
+
+	for _, err := range errs {
+		s.ErrorPrinter(err)
 	}
+}
 
-	resString, err := highlight.Transform(res.String(), highlight.YAML)
-	if err != nil {
-		return false, fmt.Errorf("failed to transform query: %w", err)
+// rawQueryHandler prints raw queries in verbose mode.
+func (s *Service) rawQueryHandler(msg string, isOutgoing bool) {
+	if s.Verbose {
+		s.RawQueryPrinter(msg, isOutgoing)
 	}
+}
 
-	console.Println(resString)
+// rawExchangeHandler records queries and their responses to the transcript. It is called concurrently by the parallel sessions of bulk commands.
+func (s *Service) rawExchangeHandler(query, response string) {
+	if t := s.transcript; t != nil {
+		t.record(query, response)
+	}
+}
+
+func (s *Service) innerErrorHandler(err error) {
+	if s.Verbose {
+		s.ErrorPrinter(err)
+	}
+}
+
+func (s *Service) processQuery(query *rri.Query) (bool, error) {
+	res, err := s.sendQuery(query)
+	if err != nil {
+		return false, err
+	}
 
 	if s.ReturnErrorOnFail && !res.IsSuccessful() {
 		return false, fmt.Errorf("RRI returned result 'failed'")
@@ -609,14 +748,52 @@ func (s *Service) processQuery(query *rri.Query) (bool, error) {
 	return res.IsSuccessful(), nil
 }
 
-func (s *Service) readDomainData(args []string, dataOffset int) (string, rri.DomainData, error) {
+// sendQuery sends query, harvests the response for completion and prints it.
+func (s *Service) sendQuery(query *rri.Query) (*rri.Response, error) {
+	// check the name servers first to not ask for confirmation of queries that would be rejected anyway
+	if err := s.precheckQuery(query); err != nil {
+		return nil, err
+	}
+	if err := s.confirmQuery(query); err != nil {
+		return nil, err
+	}
+
+	res, err := s.rriClient.SendQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send query: %w", err)
+	}
+
+	s.completion.harvestResponse(res)
+	s.lastResponse = res
+
+	if err := s.printResponse(res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// readDomainData reads domain data from args starting at dataOffset, from dataFile if not empty, or prompts for missing values.
+func (s *Service) readDomainData(args []string, dataOffset int, dataFile string) (string, rri.DomainData, error) {
 	if len(args) < 1 {
 		return "", rri.DomainData{}, fmt.Errorf("missing domain name")
 	}
 
-	domainName := args[0]
-	if !strings.HasSuffix(strings.ToLower(domainName), ".de") {
-		return "", rri.DomainData{}, fmt.Errorf("domain name must end with .de")
+	domainName, err := parseDomainArg(args[0])
+	if err != nil {
+		return "", rri.DomainData{}, err
+	}
+
+	if len(dataFile) > 0 {
+		if len(args) > dataOffset {
+			return "", rri.DomainData{}, fmt.Errorf("unexpected arguments with %s", dataFileArg)
+		}
+
+		domainData, err := s.readDomainDataFile(dataFile)
+		if err != nil {
+			return "", rri.DomainData{}, err
+		}
+		return domainName, domainData, nil
 	}
 
 	handleNames := []string{"Holder", "GeneralRequest", "AbuseContact"}
@@ -670,14 +847,31 @@ func (s *Service) readDomainData(args []string, dataOffset int) (string, rri.Dom
 	}, nil
 }
 
-func readContactData(args []string) (rri.DenicHandle, rri.ContactData, error) {
+// readContactData reads contact data from dataFile if not empty or prompts for all values.
+func (s *Service) readContactData(args []string, dataFile string) (rri.DenicHandle, rri.ContactData, error) {
+	if len(args) < 1 {
+		return rri.EmptyDenicHandle(), rri.ContactData{}, fmt.Errorf("missing handle")
+	}
+
 	handle, err := rri.ParseDenicHandle(args[0])
 	if err != nil {
 		return rri.EmptyDenicHandle(), rri.ContactData{}, fmt.Errorf("%q: %s", args[0], err.Error())
 	}
 
-	console.Print("Type [PERSON ; ORG]> ")
-	strContactType, err := commandline.ReadLineWithHistory(contactTypeHist())
+	if len(dataFile) > 0 {
+		if len(args) > 1 {
+			return rri.EmptyDenicHandle(), rri.ContactData{}, fmt.Errorf("unexpected arguments with %s", dataFileArg)
+		}
+
+		contactData, err := s.readContactDataFile(dataFile)
+		if err != nil {
+			return rri.EmptyDenicHandle(), rri.ContactData{}, err
+		}
+		return handle, contactData, nil
+	}
+
+	console.Print("Type [PERSON ; ORG ; REQUEST]> ")
+	strContactType, err := commandline.ReadLineWithHistory(newEnumHistory(rri.ContactTypes()))
 	if err != nil {
 		return rri.EmptyDenicHandle(), rri.ContactData{}, err
 	}
@@ -724,7 +918,7 @@ func readContactData(args []string) (rri.DenicHandle, rri.ContactData, error) {
 
 		for {
 			console.Print("VerifiedClaim> ")
-			str, err = console.ReadLine()
+			str, err = commandline.ReadLineWithHistory(newEnumHistory(rri.VerificationClaims()))
 			if err != nil {
 				return rri.EmptyDenicHandle(), rri.ContactData{}, err
 			}
@@ -737,7 +931,7 @@ func readContactData(args []string) (rri.DenicHandle, rri.ContactData, error) {
 		}
 
 		console.Print("VerificationResult [success, failed]> ")
-		str, err = console.ReadLine()
+		str, err = commandline.ReadLineWithHistory(newEnumHistory(rri.VerificationResults()))
 		if err != nil {
 			return rri.EmptyDenicHandle(), rri.ContactData{}, err
 		}
@@ -768,21 +962,21 @@ func readContactData(args []string) (rri.DenicHandle, rri.ContactData, error) {
 		info.VerificationTimestamp = time
 
 		console.Print("VerificationEvidence> ")
-		str, err = console.ReadLine()
+		str, err = commandline.ReadLineWithHistory(newEnumHistory(rri.VerificationEvidences()))
 		if err != nil {
 			return rri.EmptyDenicHandle(), rri.ContactData{}, err
 		}
 		info.VerificationEvidence = rri.VerificationEvidence(str)
 
 		console.Print("VerificationMethod> ")
-		str, err = console.ReadLine()
+		str, err = commandline.ReadLineWithHistory(newEnumHistory(rri.VerificationMethods()))
 		if err != nil {
 			return rri.EmptyDenicHandle(), rri.ContactData{}, err
 		}
 		info.VerificationMethod = rri.VerificationMethod(str)
 
 		console.Print("TrustFramework> ")
-		str, err = console.ReadLine()
+		str, err = commandline.ReadLineWithHistory(newEnumHistory(rri.TrustFrameworks()))
 		if err != nil {
 			return rri.EmptyDenicHandle(), rri.ContactData{}, err
 		}
@@ -804,14 +998,46 @@ func readContactData(args []string) (rri.DenicHandle, rri.ContactData, error) {
 	}, nil
 }
 
-func contactTypeHist() commandline.LineHistory {
-	hist := commandline.NewLineHistory(2)
-	hist.Put("ORG")
-	hist.Put("PERSON")
-	return hist
+// CredentialOptions denotes alternative password sources that keep the password out of the process arguments.
+type CredentialOptions struct {
+	// PasswordEnv denotes an environment variable to read the password from.
+	PasswordEnv string
+	// PasswordFD denotes a file descriptor to read the password from. Ignored if negative.
+	PasswordFD int
+	// PasswordCmd denotes a helper command that writes the password to stdout.
+	PasswordCmd string
 }
 
-func RetrieveEnvironment(envReader *env.Reader, host, environment, user, password *string, cmd *[]string) (env.Environment, error) {
+// IsSet returns true if any password source is configured.
+func (o CredentialOptions) IsSet() bool {
+	return len(o.PasswordEnv) > 0 || o.PasswordFD >= 0 || len(o.PasswordCmd) > 0
+}
+
+func (o CredentialOptions) provider(user string) (rri.CredentialProvider, error) {
+	if len(user) == 0 {
+		return nil, fmt.Errorf("missing RRI user for password source")
+	}
+
+	switch {
+	case len(o.PasswordEnv) > 0:
+		return rri.EnvCredentials(user, o.PasswordEnv), nil
+
+	case o.PasswordFD >= 0:
+		return rri.FileDescriptorCredentials(user, uintptr(o.PasswordFD)), nil
+
+	default:
+		cmd, _ := commandline.ParseCommand(o.PasswordCmd)
+		if len(cmd) == 0 {
+			return nil, fmt.Errorf("empty password command")
+		}
+		return rri.CommandCredentials(user, cmd[0], cmd[1:]...), nil
+	}
+}
+
+// RetrieveEnvironment assembles the environment from command line and environment files. The returned credential provider is nil if no user is configured.
+//
+// The password is not part of the returned environment, it is only accessible through the credential provider.
+func RetrieveEnvironment(envReader *env.Reader, host, environment, user, password *string, cmd *[]string, credentialOptions CredentialOptions) (env.Environment, rri.CredentialProvider, error) {
 	var addressFromCommandLine string
 	if len(*host) > 0 {
 		addressFromCommandLine = *host
@@ -827,40 +1053,63 @@ func RetrieveEnvironment(envReader *env.Reader, host, environment, user, passwor
 	}
 
 	var envi env.Environment
+	var envName string
 	if len(*environment) > 0 {
-		err := envReader.CreateOrReadEnvironment(*environment, &envi)
+		envName = *environment
+		err := envReader.CreateOrReadEnvironment(envName, &envi)
 		if err != nil {
-			return env.Environment{}, err
+			return env.Environment{}, nil, err
 		}
 	} else if len(addressFromCommandLine) == 0 {
-		err := envReader.SelectEnvironment(&envi)
+		var err error
+		envName, err = envReader.SelectEnvironment(&envi)
 		if err != nil {
-			return env.Environment{}, err
+			return env.Environment{}, nil, err
 		}
 	}
 
 	if len(addressFromCommandLine) > 0 {
 		envi.Address = addressFromCommandLine
 	}
+
+	userOverridden := len(*user) > 0 && *user != envi.User
 	if len(*user) > 0 {
 		envi.User = *user
 	}
-	if len(*password) > 0 {
-		envi.Password = *password
-	}
 
-	if len(envi.User) > 0 && len(envi.Password) == 0 {
-		// ask for missing user credentials
+	var provider rri.CredentialProvider
+	switch {
+	case credentialOptions.IsSet():
 		var err error
+		provider, err = credentialOptions.provider(envi.User)
+		if err != nil {
+			return env.Environment{}, nil, err
+		}
+
+	case len(*password) > 0:
+		provider = rri.StaticCredentials(envi.User, *password)
+
+	case len(envName) > 0 && !userOverridden && envi.HasCredentials():
+		// decrypt password from environment file on demand
+		provider = envReader.CredentialProvider(envName)
+
+	case len(envi.User) > 0 && len(envi.Password) == 0:
+		// ask for missing user credentials
 		console.Printlnf("Please enter RRI password for user %q", envi.User)
 		console.Print("> ")
-		envi.Password, err = console.ReadPassword()
+		pass, err := console.ReadPassword()
 		if err != nil {
-			return env.Environment{}, err
+			return env.Environment{}, nil, err
 		}
+		provider = rri.StaticCredentials(envi.User, pass)
+
+	case envi.HasCredentials():
+		provider = rri.StaticCredentials(envi.User, envi.Password)
 	}
 
-	return envi, nil
+	envi.Name = envName
+	envi.Password = ""
+	return envi, provider, nil
 }
 
 func EnterEnvironment(envi any) error {
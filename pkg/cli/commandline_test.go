@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withMockService runs f with a service that is logged in to a mock server answering all queries with handler.
+func withMockService(t *testing.T, handler rri.MockQueryHandler, f func(s *Service)) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+		server.Handler = handler
+
+		client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true})
+		require.NoError(t, err)
+		defer client.Close()
+		s := New(client, nil, nil)
+		require.NoError(t, client.Login("DENIC-1000011-TEST", "secret"))
+
+		f(s)
+	})
+}
+
+func TestSessionExpiredHandler(t *testing.T) {
+	s := New(nil, nil, nil)
+	withConsoleMock(t, nil, func(m *consoleMock) {
+		// keep-alive routines must not print into the prompt
+		s.sessionExpiredHandler(errors.New("session expired"))
+		assert.Empty(t, m.output.String())
+
+		s.printExpiredSessions()
+		assert.Contains(t, m.output.String(), "ERR: session expired")
+
+		m.output.Reset()
+		s.printExpiredSessions()
+		assert.Empty(t, m.output.String())
+	})
+}
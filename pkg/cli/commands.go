@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -47,7 +47,12 @@ func (s *Service) cmdLogout(args []string) error {
 }
 
 func (s *Service) cmdCreateHandle(args []string) error {
-	handle, contactData, err := readContactData(args)
+	args, dataFile, err := s.splitDataFileArg(args)
+	if err != nil {
+		return err
+	}
+
+	handle, contactData, err := s.readContactData(args, dataFile)
 	if err != nil {
 		return err
 	}
@@ -59,7 +64,12 @@ func (s *Service) cmdCreateHandle(args []string) error {
 }
 
 func (s *Service) cmdCreateDomain(args []string) error {
-	domainName, domainData, err := s.readDomainData(args, 1)
+	args, dataFile, err := s.splitDataFileArg(args)
+	if err != nil {
+		return err
+	}
+
+	domainName, domainData, err := s.readDomainData(args, 1, dataFile)
 	if err != nil {
 		return err
 	}
@@ -71,7 +81,12 @@ func (s *Service) cmdCreateDomain(args []string) error {
 }
 
 func (s *Service) cmdUpdateDomain(args []string) error {
-	domainName, domainData, err := s.readDomainData(args, 1)
+	args, dataFile, err := s.splitDataFileArg(args)
+	if err != nil {
+		return err
+	}
+
+	domainName, domainData, err := s.readDomainData(args, 1, dataFile)
 	if err != nil {
 		return err
 	}
@@ -85,7 +100,12 @@ func (s *Service) cmdUpdateDomain(args []string) error {
 }
 
 func (s *Service) cmdChangeHolder(args []string) error {
-	domainName, domainData, err := s.readDomainData(args, 1)
+	args, dataFile, err := s.splitDataFileArg(args)
+	if err != nil {
+		return err
+	}
+
+	domainName, domainData, err := s.readDomainData(args, 1, dataFile)
 	if err != nil {
 		return err
 	}
@@ -102,7 +122,10 @@ func (s *Service) cmdTransit(args []string) error {
 	if len(args) < 1 {
 		return fmt.Errorf("missing domain name")
 	}
-	domainName := args[0]
+	domainName, err := parseDomainArg(args[0])
+	if err != nil {
+		return err
+	}
 	var disconnect bool
 	if len(args) > 1 {
 		switch strings.ToLower(args[1]) {
@@ -115,7 +138,7 @@ func (s *Service) cmdTransit(args []string) error {
 		}
 	}
 
-	_, err := s.processQuery(rri.NewTransitDomainQuery(domainName, disconnect))
+	_, err = s.processQuery(rri.NewTransitDomainQuery(domainName, disconnect))
 	s.completion.PutDomain(domainName)
 
 	return err
@@ -126,13 +149,17 @@ func (s *Service) cmdCreateAuthInfo1(args []string) error {
 		return fmt.Errorf("missing domain name")
 	}
 
+	domainName, err := parseDomainArg(args[0])
+	if err != nil {
+		return err
+	}
+
 	if len(args) < 2 {
 		return fmt.Errorf("missing auth info secret")
 	}
 
 	var expire time.Time
 	if len(args) >= 3 {
-		var err error
 		expire, err = time.ParseInLocation("2006-01-02", args[2], time.Local)
 		if err != nil {
 			expire, err = time.ParseInLocation("20060102", args[2], time.Local)
@@ -145,11 +172,17 @@ func (s *Service) cmdCreateAuthInfo1(args []string) error {
 		console.Println("using default expiration of 1 week")
 	}
 
-	_, err := s.processQuery(rri.NewCreateAuthInfo1Query(args[0], args[1], expire))
+	_, err = s.processQuery(rri.NewCreateAuthInfo1Query(domainName, args[1], expire))
+	s.completion.PutDomain(domainName)
 	return err
 }
 
 func (s *Service) cmdChangeProvider(args []string) error {
+	args, dataFile, err := s.splitDataFileArg(args)
+	if err != nil {
+		return err
+	}
+
 	if len(args) < 1 {
 		return fmt.Errorf("missing domain name")
 	}
@@ -158,7 +191,7 @@ func (s *Service) cmdChangeProvider(args []string) error {
 		return fmt.Errorf("missing auth info secret")
 	}
 
-	domainName, domainData, err := s.readDomainData(args, 2)
+	domainName, domainData, err := s.readDomainData(args, 2, dataFile)
 	if err != nil {
 		return err
 	}
@@ -183,10 +216,20 @@ func (s *Service) cmdQueueDelete(args []string) error {
 	return err
 }
 
+// unescapeRawArg replaces escaped line breaks in a raw query passed as argument.
+func unescapeRawArg(arg string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(arg, "\\n", "\n"), "\\r", "\r"), "\\\\", "\\")
+}
+
+// escapeRawArg escapes backslashes and line breaks in msg to pass it as argument of the raw command.
+func escapeRawArg(msg string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(msg, "\\", "\\\\"), "\n", "\\n"), "\r", "\\r")
+}
+
 func (s *Service) cmdRaw(args []string) error {
 	var rawCommand string
 	if len(args) > 0 {
-		rawCommand = strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(args[0], "\\n", "\n"), "\\r", "\r"), "\\\\", "\\")
+		rawCommand = unescapeRawArg(args[0])
 	} else {
 		raw, ok, err := input.Text("")
 		if err != nil {
@@ -199,33 +242,25 @@ func (s *Service) cmdRaw(args []string) error {
 	}
 
 	if len(rawCommand) > 0 {
+		if err := s.precheckRawQuery(rawCommand); err != nil {
+			return err
+		}
+		if err := s.confirmRawQuery(rawCommand); err != nil {
+			return err
+		}
+
 		response, err := s.rriClient.SendRaw(rawCommand)
 		if err != nil {
 			return err
 		}
 
-		if strings.Contains(response, "<") {
-			response, err = highlight.Transform(response, highlight.XML)
-			if err != nil {
-				return err
-			}
-		} else {
-			response, err = highlight.Transform(response, highlight.YAML)
-			if err != nil {
-				return err
-			}
+		responseObj, err := s.printRawResponse(response)
+		if err != nil {
+			return err
 		}
 
-		console.Println(response)
-
-		if s.ReturnErrorOnFail {
-			responseObj, err := rri.ParseResponse(response)
-			if err != nil {
-				return fmt.Errorf("RRI server returned an invalid response")
-			}
-			if !responseObj.IsSuccessful() {
-				return fmt.Errorf("RRI returned result 'failed'")
-			}
+		if s.ReturnErrorOnFail && !responseObj.IsSuccessful() {
+			return fmt.Errorf("RRI returned result 'failed'")
 		}
 	}
 
@@ -242,51 +277,83 @@ func (s *Service) HandleFile(args []string) error {
 		return err
 	}
 
+	expanded, err := s.expandVariables(string(data))
+	if err != nil {
+		return err
+	}
+	data = []byte(expanded)
+
 	lines := parser.SplitLines(data)
 	queries := parser.SplitQueries(lines)
 
-	if isXML(data) {
-		return s.executeXMLQueries(queries)
+	return s.executeQueries(queries, isXML(data))
+}
+
+// cmdConvert translates all queries of a file between KV and XML syntax. Queries are converted to the opposite format of the first query unless a target format is given.
+func (s *Service) cmdConvert(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("missing query file")
 	}
 
-	err = s.executeKVQueries(queries)
+	data, err := os.ReadFile(args[0])
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	queries := parser.SplitQueries(parser.SplitLines(data))
+	if len(queries) == 0 {
+		return fmt.Errorf("no queries found in %q", args[0])
+	}
 
-func (s *Service) executeXMLQueries(queries []string) error {
-	for i, query := range queries {
-		resp, err := s.rriClient.SendRaw(query)
-		if err != nil {
+	to := rri.FormatXML
+	if rri.DetectFormat(queries[0]) == rri.FormatXML {
+		to = rri.FormatKV
+	}
+	if len(args) > 1 {
+		if to, err = rri.ParseFormat(args[1]); err != nil {
 			return err
 		}
+	}
 
-		err = s.printXMLResult(resp, i, highlight.XML)
-		if err != nil {
+	converted := make([]string, len(queries))
+	for i, query := range queries {
+		if converted[i], err = rri.ConvertQuery(strings.TrimSpace(query), to); err != nil {
+			return fmt.Errorf("failed to convert query #%d: %w", i+1, err)
+		}
+	}
+	output := strings.Join(converted, "\n=-=\n") + "\n"
+
+	if len(args) > 2 {
+		if err := os.WriteFile(args[2], []byte(output), 0o644); err != nil {
 			return err
 		}
+		console.Printlnf("converted %d queries to %s", len(converted), to)
+		return nil
 	}
 
+	format := highlight.YAML
+	if to == rri.FormatXML {
+		format = highlight.XML
+	}
+	highlighted, err := s.highlightOutput(output, format)
+	if err != nil {
+		return err
+	}
+	console.Print(highlighted)
 	return nil
 }
 
-func (s *Service) printXMLResult(resp string, i int, format highlight.Format) error {
-	isSuccess := strings.Contains(resp, "<tr:result>success</tr:result>")
-
+func (s *Service) printXMLResult(resp string, i int, isSuccess bool) error {
 	console.Println("----------------------------------------")
 	console.Println(fmt.Sprintf("Query #%v has success result: %v", i+1, isSuccess))
 	console.Println("----------------------------------------")
 
-	resp, err := highlight.Transform(resp, format)
+	resp, err := s.highlightOutput(resp, highlight.XML)
 	if err != nil {
 		return err
 	}
 
 	console.Println(resp)
-	// println(resp)
 
 	return nil
 }
@@ -295,71 +362,38 @@ func isXML(data []byte) bool {
 	return bytes.Contains(data, []byte("<"))
 }
 
-func (s *Service) executeKVQueries(queryStrings []string) error {
-	skipAuthQueries := s.rriClient.IsLoggedIn()
-
-	queries, err := parser.ParseQueriesKV(queryStrings)
-	if err != nil {
-		return err
-	}
-
-	// find queries to execute first
-	executedQueryCount := 0
-	hasLoginLogoutQueries := false
-	for _, query := range queries {
-		if query.Action() == rri.ActionLogin || query.Action() == rri.ActionLogout {
-			hasLoginLogoutQueries = true
-			if !skipAuthQueries {
-				executedQueryCount++
-			}
-		} else {
-			executedQueryCount++
-		}
-	}
-
-	if skipAuthQueries && hasLoginLogoutQueries {
-		// TODO colored orange
-		console.Println("Currently logged in. Auth queries will be skipped")
-	}
-
-	for _, query := range queries {
-		if skipAuthQueries {
-			if query.Action() == rri.ActionLogin || query.Action() == rri.ActionLogout {
-				continue
-			}
-		}
-
-		success, err := s.processQuery(query)
-		if err != nil {
-			return err
-		}
-
-		if !success {
-			break
-		}
-	}
-
-	return nil
-}
-
 func (s *Service) cmdVerbose(args []string) error {
-	if s.rriClient.RawQueryPrinter == nil {
-		s.rriClient.RawQueryPrinter = s.RawQueryPrinter
-		s.rriClient.InnerErrorPrinter = s.ErrorPrinter
+	s.Verbose = !s.Verbose
+	if s.Verbose {
 		console.Println("Verbose mode on")
 	} else {
-		s.rriClient.RawQueryPrinter = nil
-		s.rriClient.InnerErrorPrinter = nil
 		console.Println("Verbose mode off")
 	}
 
 	return nil
 }
 
+const presetSaveArg = "--save"
+
 func (s *Service) HandlePreset(args []string) error {
 	var chosenPreset *preset.Entry
+	var saveName string
 	var err error
 
+	remaining := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] == presetSaveArg {
+			if i+1 >= len(args) {
+				return fmt.Errorf("missing preset name for %s", presetSaveArg)
+			}
+			saveName = args[i+1]
+			i++
+			continue
+		}
+		remaining = append(remaining, args[i])
+	}
+	args = remaining
+
 	if len(args) > 0 {
 		chosenPreset = s.presets.Get(args[0])
 		if chosenPreset == nil {
@@ -369,123 +403,76 @@ func (s *Service) HandlePreset(args []string) error {
 	}
 
 	if chosenPreset == nil {
-		chosenPreset, err = s.manualPresetFlow()
+		chosenPreset, err = s.pickPreset()
 		if err != nil {
 			return err
 		}
 	}
 
-	presetContent, err := s.embedFS.ReadFile("examples/" + chosenPreset.Type + "/" + chosenPreset.DirName + "/" + chosenPreset.FileName)
+	presetContent, err := chosenPreset.Read()
 	if err != nil {
 		return err
 	}
 
-	format := highlight.YAML
-	if strings.EqualFold(chosenPreset.Type, "xml") {
-		format = highlight.XML
+	if len(saveName) > 0 {
+		// placeholders are kept, so the new preset does not contain values like passwords
+		result, err := s.editQuery(string(presetContent))
+		if err != nil {
+			return err
+		}
+		return s.savePreset(saveName, result)
 	}
 
-	// Highlight in edit mode doesn't work yet
-	// stringContent, err := highlight.Transform(string(presetContent), format)
-	// if err != nil {
-	// 	return err
-	// }
-
-	result, _, err := input.Text(string(presetContent))
+	expanded, err := s.expandVariables(string(presetContent))
 	if err != nil {
 		return err
 	}
 
-	res, err := s.rriClient.SendRaw(result)
+	result, send, err := s.reviewQuery(expanded)
 	if err != nil {
 		return err
 	}
+	if !send {
+		console.Println("query not sent")
+		return nil
+	}
 
-	res, err = highlight.Transform(res, format)
-	if err != nil {
+	if err := s.precheckRawQuery(result); err != nil {
+		return err
+	}
+	if err := s.confirmRawQuery(result); err != nil {
 		return err
 	}
 
-	console.Println(res) //nolint
-
-	return err
-}
-
-func (s *Service) manualPresetFlow() (*preset.Entry, error) {
-	format, err := s.chosePresetType()
+	res, err := s.rriClient.SendRaw(result)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	ClearTerminal()
-
-	s.listPresets(format)
-	chosenIndex, err := s.chosePreset()
+	responseObj, err := s.printRawResponse(res)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	ClearTerminal()
-
-	result := s.presets.Preset[chosenIndex]
-
-	return &result, nil
-}
-
-func (s *Service) chosePreset() (int, error) {
-	console.Println()
-	console.Print("Preset number: ")
-	numberString, err := console.ReadLine()
-	if err != nil {
-		return -1, err
+	if s.ReturnErrorOnFail && !responseObj.IsSuccessful() {
+		return fmt.Errorf("RRI returned result 'failed'")
 	}
 
-	return strconv.Atoi(numberString)
+	return nil
 }
 
-func (s *Service) chosePresetType() (string, error) {
-	format := ""
-	var err error
-
-	for {
-		console.Println("Choose preset: \n1: kv  \n2: xml")
-		format, err = console.ReadLine()
-		if err != nil {
-			return format, err
-		}
-
-		if format == "1" {
-			return "kv", nil
-
-		}
-
-		if format == "2" {
-			return "xml", nil
-		}
+// savePreset saves content as new preset to the preset directory and makes it available for completion.
+func (s *Service) savePreset(name, content string) error {
+	if len(s.PresetDir) == 0 {
+		return fmt.Errorf("no preset directory configured")
 	}
-}
 
-func (s *Service) listPresets(format string) {
-	if format == "kv" {
-		console.Print("kv")
+	entry, err := preset.Save(s.PresetDir, name, rri.DetectFormat(content), []byte(content))
+	if err != nil {
+		return err
 	}
 
-	var currentDirName string
-
-	for i := 0; i < len(s.presets.Preset); i++ {
-		if format != s.presets.Preset[i].Type {
-			continue
-		}
-
-		if i == s.presets.XMLStartIndex {
-			console.Print("xml")
-		}
-
-		if currentDirName != s.presets.Preset[i].DirName {
-			currentDirName = s.presets.Preset[i].DirName
-			console.Printf("\n\t%v: ", currentDirName)
-		}
-
-		console.Printf("\n\t\t%v %v", i, s.presets.Preset[i].FileName)
-	}
+	s.presets.Add(entry)
+	console.Printlnf("saved preset %s to %s", entry.FileName, filepath.Join(s.PresetDir, filepath.FromSlash(entry.Path)))
+	return nil
 }
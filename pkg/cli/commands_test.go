@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/preset"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlePresetSaveKeepsPlaceholders(t *testing.T) {
+	// the editor leaves the preset unchanged
+	t.Setenv("VISUAL", "true")
+
+	sourceDir := t.TempDir()
+	content := "version: 5.0\naction: CHPROV\ndomain: ${domain}\nauthinfo: ${authinfo}\n"
+	_, err := preset.Save(sourceDir, "chprov", rri.FormatKV, []byte(content))
+	require.NoError(t, err)
+	presets, err := preset.Load(preset.DirSources(sourceDir)...)
+	require.NoError(t, err)
+
+	s := New(nil, presets, nil)
+	s.UseEditor = true
+	s.PresetDir = t.TempDir()
+	s.SetVariables(map[string]string{"domain": "denic.de", "authinfo": "top-secret"})
+
+	withConsoleMock(t, nil, func(m *consoleMock) {
+		require.NoError(t, s.HandlePreset([]string{"chprov", "--save", "chprov-copy"}))
+	})
+
+	saved, err := os.ReadFile(filepath.Join(s.PresetDir, string(rri.FormatKV), "chprov-copy"))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(saved))
+	assert.NotNil(t, presets.Get("chprov-copy"))
+}
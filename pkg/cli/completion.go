@@ -1,15 +1,20 @@
 package cli
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/DENICeG/go-console/v2/commandline"
 	"github.com/DENICeG/go-rriclient/pkg/preset"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
 )
 
 type domainOrHandleCompletion struct {
 	histDomains *domainHistory
 	histHandles *handleHistory
+	// currentRegAccID returns the RegAcc ID of the logged in user to complete handle prefixes.
+	currentRegAccID func() (int, error)
 }
 
 // NewCompletion returns a new domainOrHandleCompletion instance.
@@ -28,29 +33,169 @@ func (c *domainOrHandleCompletion) PutHandle(handle string) {
 	c.histHandles.Put(handle)
 }
 
-func (c *domainOrHandleCompletion) GetCompletionOptions(currentCommand []string, entryIndex int) []commandline.CompletionOption {
-	if len(currentCommand) < 2 && entryIndex != 2 {
+// handles returns an argument completion for handles from history, prefixed with the handle prefix of the logged in RegAcc.
+func (c *domainOrHandleCompletion) handles() commandline.ArgCompletion {
+	return argCompletionFunc(func(currentCommand []string, entryIndex int) []commandline.CompletionOption {
+		options := c.histHandles.GetCompletionOptions(currentCommand, entryIndex)
+		if c.currentRegAccID != nil {
+			if regAccID, err := c.currentRegAccID(); err == nil {
+				options = append(options, commandline.NewCompletionOption(fmt.Sprintf("DENIC-%d-", regAccID), true))
+			}
+		}
+		return options
+	})
+}
+
+// harvestResponse puts all domains and handles contained in response to history.
+func (c *domainOrHandleCompletion) harvestResponse(response *rri.Response) {
+	harvest := func(fields rri.ResponseFieldList) {
+		for _, field := range fields {
+			switch {
+			// XML responses denote the domain name as handle
+			case (field.Name == rri.ResponseFieldNameDomain.Normalize() || field.Name == "HANDLE") && rri.IsDomainName(field.Value):
+				c.PutDomain(field.Value)
+			case rri.IsHandle(field.Value):
+				if _, err := rri.ParseDenicHandle(field.Value); err == nil {
+					c.PutHandle(field.Value)
+				}
+			}
+		}
+	}
+
+	harvest(response.Fields())
+	for _, entity := range response.Entities() {
+		harvest(entity.Fields())
+	}
+}
+
+// argCompletionFunc implements commandline.ArgCompletion for a plain function.
+type argCompletionFunc func(currentCommand []string, entryIndex int) []commandline.CompletionOption
+
+func (f argCompletionFunc) GetCompletionOptions(currentCommand []string, entryIndex int) []commandline.CompletionOption {
+	return f(currentCommand, entryIndex)
+}
+
+// noArgCompletion is used for arguments that cannot be completed like secrets.
+var noArgCompletion = argCompletionFunc(func([]string, int) []commandline.CompletionOption { return nil })
+
+// newEnumArgCompletion returns an argument completion for a list of string-like values.
+func newEnumArgCompletion[T ~string](values []T) commandline.ArgCompletion {
+	options := make([]string, len(values))
+	for i, v := range values {
+		options[i] = string(v)
+	}
+	return commandline.NewOneOfArgCompletion(options...)
+}
+
+// newEnumHistory returns a line history to cycle through a list of string-like values with arrow keys.
+func newEnumHistory[T ~string](values []T) commandline.LineHistory {
+	hist := commandline.NewLineHistory(len(values))
+	for i := len(values) - 1; i >= 0; i-- {
+		hist.Put(string(values[i]))
+	}
+	return hist
+}
+
+// argGrammar describes the arguments of a command. The last argument is repeated if variadic is set.
+type argGrammar struct {
+	args     []commandline.ArgCompletion
+	variadic bool
+}
+
+func newArgGrammar(args ...commandline.ArgCompletion) argGrammar {
+	return argGrammar{args: args}
+}
+
+func newVariadicArgGrammar(args ...commandline.ArgCompletion) argGrammar {
+	return argGrammar{args: args, variadic: true}
+}
+
+// complete returns the completion options for the argument at argIndex, where 0 denotes the first argument.
+func (g argGrammar) complete(currentCommand []string, entryIndex, argIndex int) []commandline.CompletionOption {
+	if argIndex < 0 || len(g.args) == 0 {
 		return nil
 	}
+	if argIndex >= len(g.args) {
+		if !g.variadic {
+			return nil
+		}
+		argIndex = len(g.args) - 1
+	}
+	return g.args[argIndex].GetCompletionOptions(currentCommand, entryIndex)
+}
 
-	if currentCommand[1] == "domain" || currentCommand[1] == "authinfo1" {
-		return c.GetCompletionOptions(currentCommand, entryIndex)
+// completionHandler returns a completion handler for commands without type switch.
+func (g argGrammar) completionHandler() commandline.CommandCompletionHandler {
+	return func(currentCommand []string, entryIndex int) []commandline.CompletionOption {
+		return g.complete(currentCommand, entryIndex, entryIndex-1)
 	}
+}
 
-	if currentCommand[1] == "handle" {
-		return c.histHandles.GetCompletionOptions(currentCommand, entryIndex)
+// newSwitchCompletion returns a completion handler for commands like "create domain ..." with a type switch as first argument.
+func newSwitchCompletion(grammars map[string]argGrammar) commandline.CommandCompletionHandler {
+	types := make([]string, 0, len(grammars))
+	for t := range grammars {
+		types = append(types, t)
 	}
+	sort.Strings(types)
+	typeCompletion := commandline.NewOneOfArgCompletion(types...)
 
-	return nil
+	return func(currentCommand []string, entryIndex int) []commandline.CompletionOption {
+		if entryIndex == 1 {
+			return typeCompletion.GetCompletionOptions(currentCommand, entryIndex)
+		}
+		if entryIndex < 2 || len(currentCommand) < 2 {
+			return nil
+		}
+
+		grammar, ok := grammars[currentCommand[1]]
+		if !ok {
+			return nil
+		}
+		return grammar.complete(currentCommand, entryIndex, entryIndex-2)
+	}
 }
 
+// rawQueryCompletion completes field names and actions of raw queries that are entered as single argument with \n line separators.
+var rawQueryCompletion = argCompletionFunc(func(currentCommand []string, entryIndex int) []commandline.CompletionOption {
+	current := currentCommand[entryIndex]
+	line := current
+	if index := strings.LastIndex(current, `\n`); index >= 0 {
+		line = current[index+2:]
+	}
+
+	options := make([]commandline.CompletionOption, 0)
+
+	// replacements must not contain spaces as they would be escaped in quoted arguments
+	if key, value, ok := strings.Cut(line, ":"); ok {
+		if !strings.EqualFold(strings.TrimSpace(key), string(rri.QueryFieldNameAction)) {
+			return nil
+		}
+
+		value = strings.TrimLeft(value, " ")
+		for _, action := range rri.KnownActions() {
+			if strings.HasPrefix(string(action), strings.ToUpper(value)) {
+				options = append(options, commandline.NewLabelledCompletionOption(string(action), current+string(action)[len(value):], true))
+			}
+		}
+		return options
+	}
+
+	for _, fieldName := range rri.KnownQueryFieldNames() {
+		if strings.HasPrefix(string(fieldName), strings.ToLower(line)) {
+			options = append(options, commandline.NewLabelledCompletionOption(string(fieldName), current+string(fieldName)[len(line):]+":", true))
+		}
+	}
+	return options
+})
+
 // PresetCompletion implements the Completion interface for preset names.
 type PresetCompletion struct {
-	presets preset.Data
+	presets *preset.Data
 }
 
 // NewPresetCompletion returns a new PresetCompletion instance.
-func NewPresetCompletion(presets preset.Data) *PresetCompletion {
+func NewPresetCompletion(presets *preset.Data) *PresetCompletion {
 	return &PresetCompletion{presets: presets}
 }
 
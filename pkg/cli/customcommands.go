@@ -0,0 +1,411 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/commandline"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
+
+const (
+	customArgTypeDomain = "domain"
+	customArgTypeHandle = "handle"
+	customArgTypeString = "string"
+	customArgTypeEnum   = "enum"
+	customArgTypeDate   = "date"
+	customArgTypeInt    = "int"
+	customArgTypeList   = "list"
+	customArgTypeConst  = "const"
+)
+
+// customArgDateLayout is the date format used by RRI fields like authinfoexpire.
+const customArgDateLayout = "20060102"
+
+// customReferencePattern matches ${arg} references to command arguments and ${n.field} references to fields of the response to step n.
+var customReferencePattern = regexp.MustCompile(`\$\{([A-Za-z0-9_-]+)(?:\.([A-Za-z0-9_-]+))?\}`)
+
+type customCommand struct {
+	DisabledFor []string           `json:"disable-for-env"`
+	Name        string             `json:"name"`
+	Cmd         string             `json:"cmd"`
+	Description string             `json:"description"`
+	Action      string             `json:"action"`
+	Args        []customCommandArg `json:"args"`
+	// Steps define a workflow of multiple queries. Commands with a single action are converted to a single step.
+	Steps []customCommandStep `json:"steps"`
+}
+
+type customCommandArg struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Field string `json:"field"`
+	Value string `json:"value"`
+	// Default is used if the argument is omitted.
+	Default string `json:"default"`
+	// Optional arguments without default value are skipped if omitted.
+	Optional bool `json:"optional"`
+	// Values lists the allowed values of enum arguments.
+	Values []string `json:"values"`
+	// Separator splits list arguments, defaults to comma.
+	Separator string `json:"separator"`
+}
+
+type customCommandStep struct {
+	Action string            `json:"action"`
+	Fields []customStepField `json:"fields"`
+	// ContinueOnFailure executes the next step even if RRI returns a failed result.
+	ContinueOnFailure bool `json:"continue-on-failure"`
+}
+
+// customStepField adds a query field. Value may contain ${arg} and ${n.field} references. A value consisting of a single reference adds one field per referenced value.
+type customStepField struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+func (arg customCommandArg) IsInputParameter() bool {
+	return !strings.EqualFold(arg.Type, customArgTypeConst)
+}
+
+// IsDisabledFor returns whether the command is disabled for the environment envName.
+func (cmd customCommand) IsDisabledFor(envName string) bool {
+	for _, disabled := range cmd.DisabledFor {
+		if strings.EqualFold(disabled, envName) {
+			return true
+		}
+	}
+	return false
+}
+
+// readCustomCommands reads all command definitions in dir. Invalid definitions are passed to skipped and do not prevent loading the others.
+func readCustomCommands(dir string, skipped func(err error)) ([]customCommand, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]customCommand, 0)
+	for _, f := range files {
+		if !f.IsDir() {
+			cmd, err := readCustomCommand(filepath.Join(dir, f.Name()))
+			if err != nil {
+				skipped(fmt.Errorf("could not import %q: %s", f.Name(), err.Error()))
+				continue
+			}
+			result = append(result, cmd)
+		}
+	}
+
+	return result, nil
+}
+
+func readCustomCommand(file string) (customCommand, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return customCommand{}, err
+	}
+	var cmd customCommand
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		return customCommand{}, err
+	}
+
+	if len(cmd.Name) == 0 {
+		cmd.Name = filepath.Base(file)
+	}
+
+	if len(cmd.Cmd) == 0 {
+		cmd.Cmd = filepath.Base(file)
+	}
+
+	if err := cmd.prepare(); err != nil {
+		return customCommand{}, err
+	}
+	return cmd, nil
+}
+
+// prepare validates the command definition, sets defaults and converts single action commands to a single step.
+func (cmd *customCommand) prepare() error {
+	for i := range cmd.Args {
+		arg := &cmd.Args[i]
+		arg.Type = strings.ToLower(arg.Type)
+
+		switch arg.Type {
+		case customArgTypeDomain:
+			if len(arg.Field) == 0 {
+				arg.Field = string(rri.QueryFieldNameDomainIDN)
+			}
+		case customArgTypeEnum:
+			if len(arg.Values) == 0 {
+				return fmt.Errorf("enum argument %q without values", arg.Name)
+			}
+		case customArgTypeList:
+			if len(arg.Separator) == 0 {
+				arg.Separator = ","
+			}
+		case customArgTypeHandle, customArgTypeString, customArgTypeDate, customArgTypeInt, customArgTypeConst:
+		default:
+			return fmt.Errorf("unknown argument type %q", arg.Type)
+		}
+
+		if len(arg.Name) == 0 {
+			arg.Name = arg.Field
+		}
+		if len(arg.Name) == 0 {
+			return fmt.Errorf("argument %d without name or field", i+1)
+		}
+
+		if len(arg.Default) > 0 && arg.IsInputParameter() {
+			if _, err := arg.parse(arg.Default); err != nil {
+				return fmt.Errorf("invalid default value of argument %q: %s", arg.Name, err.Error())
+			}
+		}
+	}
+
+	switch {
+	case len(cmd.Action) > 0 && len(cmd.Steps) > 0:
+		return fmt.Errorf("action and steps must not be combined")
+
+	case len(cmd.Action) > 0:
+		step := customCommandStep{Action: cmd.Action}
+		for _, arg := range cmd.Args {
+			if len(arg.Field) > 0 {
+				step.Fields = append(step.Fields, customStepField{Field: arg.Field, Value: "${" + arg.Name + "}"})
+			}
+		}
+		cmd.Steps = []customCommandStep{step}
+
+	case len(cmd.Steps) == 0:
+		return fmt.Errorf("missing action or steps")
+	}
+
+	for i, step := range cmd.Steps {
+		if !rri.QueryAction(step.Action).Normalize().IsKnown() {
+			return fmt.Errorf("unknown action %q in step %d", step.Action, i+1)
+		}
+	}
+
+	return nil
+}
+
+// parse validates value according to the argument type and returns the field values.
+func (arg customCommandArg) parse(value string) ([]string, error) {
+	switch arg.Type {
+	case customArgTypeDomain:
+		domain, err := rri.ParseDomain(value)
+		if err != nil {
+			return nil, err
+		}
+		value = domain.IDN()
+
+	case customArgTypeHandle:
+		if _, err := rri.ParseDenicHandle(value); err != nil {
+			return nil, fmt.Errorf("%q: %s", value, err.Error())
+		}
+
+	case customArgTypeEnum:
+		index := slices.IndexFunc(arg.Values, func(v string) bool { return strings.EqualFold(v, value) })
+		if index < 0 {
+			return nil, fmt.Errorf("%q must be one of %s", value, strings.Join(arg.Values, ", "))
+		}
+		value = arg.Values[index]
+
+	case customArgTypeDate:
+		var date time.Time
+		var err error
+		for _, layout := range []string{time.DateOnly, customArgDateLayout} {
+			if date, err = time.Parse(layout, value); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a date like YYYY-MM-DD", value)
+		}
+		value = date.Format(customArgDateLayout)
+
+	case customArgTypeInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+
+	case customArgTypeList:
+		var values []string
+		for _, v := range strings.Split(value, arg.Separator) {
+			if v = strings.TrimSpace(v); len(v) > 0 {
+				values = append(values, v)
+			}
+		}
+		return values, nil
+
+	case customArgTypeConst:
+		return []string{arg.Value}, nil
+	}
+
+	return []string{value}, nil
+}
+
+// customArgCompletion returns the argument completion according to the argument type.
+func (s *Service) customArgCompletion(arg customCommandArg) commandline.ArgCompletion {
+	switch arg.Type {
+	case customArgTypeDomain:
+		return s.completion.histDomains
+	case customArgTypeHandle:
+		return s.completion.handles()
+	case customArgTypeEnum:
+		return commandline.NewOneOfArgCompletion(arg.Values...)
+	default:
+		return noArgCompletion
+	}
+}
+
+func (s *Service) registerCustomCommand(cli *commandline.Environment, cmd customCommand) {
+	clArgs := make([]commandline.ArgCompletion, 0)
+	for _, arg := range cmd.Args {
+		if arg.IsInputParameter() {
+			clArgs = append(clArgs, s.customArgCompletion(arg))
+		}
+	}
+
+	cli.RegisterCommand(commandline.NewCustomCommand(cmd.Cmd, commandline.NewFixedArgCompletion(clArgs...), func(args []string) error {
+		if cmd.IsDisabledFor(s.EnvName) {
+			return fmt.Errorf("command %q is disabled for environment %q", cmd.Cmd, s.EnvName)
+		}
+		values, err := s.readCustomArgs(cmd, args)
+		if err != nil {
+			return err
+		}
+		return s.runCustomSteps(cmd, values)
+	}))
+}
+
+// readCustomArgs validates the passed arguments and returns the values of all arguments by name.
+func (s *Service) readCustomArgs(cmd customCommand, args []string) (map[string][]string, error) {
+	values := make(map[string][]string)
+	argIndex := 0
+	for _, arg := range cmd.Args {
+		if !arg.IsInputParameter() {
+			values[arg.Name], _ = arg.parse(arg.Value)
+			continue
+		}
+
+		var inValue string
+		switch {
+		case len(args) > argIndex:
+			inValue = args[argIndex]
+		case len(arg.Default) > 0:
+			inValue = arg.Default
+		case arg.Optional:
+			values[arg.Name] = nil
+			argIndex++
+			continue
+		default:
+			return nil, fmt.Errorf("missing argument '%s'", arg.Name)
+		}
+		argIndex++
+
+		parsed, err := arg.parse(inValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument '%s': %s", arg.Name, err.Error())
+		}
+		values[arg.Name] = parsed
+
+		switch arg.Type {
+		case customArgTypeDomain:
+			s.completion.PutDomain(inValue)
+		case customArgTypeHandle:
+			s.completion.PutHandle(inValue)
+		}
+	}
+
+	if len(args) > argIndex {
+		return nil, fmt.Errorf("too many arguments")
+	}
+
+	return values, nil
+}
+
+// runCustomSteps sends the queries of all steps. Stops at the first failed step unless the step continues on failure.
+func (s *Service) runCustomSteps(cmd customCommand, values map[string][]string) error {
+	responses := make([]*rri.Response, 0, len(cmd.Steps))
+	for i, step := range cmd.Steps {
+		fields := rri.NewQueryFieldList()
+		for _, f := range step.Fields {
+			fieldValues, err := resolveCustomReferences(f.Value, values, responses)
+			if err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+			for _, v := range fieldValues {
+				fields.Add(rri.QueryFieldName(f.Field), v)
+			}
+		}
+
+		if len(cmd.Steps) > 1 {
+			console.Printlnf("step %d/%d: %s", i+1, len(cmd.Steps), rri.QueryAction(step.Action).Normalize())
+		}
+
+		res, err := s.sendQuery(rri.NewQuery(rri.LatestVersion, rri.QueryAction(step.Action).Normalize(), fields, nil))
+		if err != nil {
+			return err
+		}
+		responses = append(responses, res)
+
+		if !res.IsSuccessful() && !step.ContinueOnFailure {
+			if i+1 < len(cmd.Steps) {
+				return fmt.Errorf("step %d failed, remaining steps are skipped", i+1)
+			}
+			if s.ReturnErrorOnFail {
+				return fmt.Errorf("RRI returned result 'failed'")
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveCustomReferences replaces ${arg} and ${n.field} references in value. A value consisting of a single reference returns all referenced values, otherwise the first value of every reference is inserted. Fields without values are omitted.
+func resolveCustomReferences(value string, args map[string][]string, responses []*rri.Response) ([]string, error) {
+	lookup := func(match []string) ([]string, error) {
+		if len(match[2]) == 0 {
+			values, ok := args[match[1]]
+			if !ok {
+				return nil, fmt.Errorf("unknown argument reference %q", match[0])
+			}
+			return values, nil
+		}
+
+		step, err := strconv.Atoi(match[1])
+		if err != nil || step < 1 || step > len(responses) {
+			return nil, fmt.Errorf("reference %q must refer to a previous step", match[0])
+		}
+		return responses[step-1].LookupField(rri.ResponseFieldName(match[2])), nil
+	}
+
+	if match := customReferencePattern.FindStringSubmatch(value); match != nil && match[0] == value {
+		return lookup(match)
+	}
+
+	var lookupErr error
+	result := customReferencePattern.ReplaceAllStringFunc(value, func(ref string) string {
+		values, err := lookup(customReferencePattern.FindStringSubmatch(ref))
+		if err != nil {
+			lookupErr = err
+			return ""
+		}
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	})
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	return []string{result}, nil
+}
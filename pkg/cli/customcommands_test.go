@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomCommandPrepare(t *testing.T) {
+	cmd := customCommand{
+		Action: "create-authinfo1",
+		Args: []customCommandArg{
+			{Type: "Domain"},
+			{Name: "secret", Type: "string", Field: "authinfohash"},
+			{Name: "expire", Type: "date", Field: "authinfoexpire", Default: "2030-01-31"},
+			{Name: "nserver", Type: "list", Field: "nserver"},
+			{Name: "version", Type: "const", Value: "5.0"},
+		},
+	}
+	require.NoError(t, cmd.prepare())
+	assert.Equal(t, customArgTypeDomain, cmd.Args[0].Type)
+	assert.Equal(t, string(rri.QueryFieldNameDomainIDN), cmd.Args[0].Name)
+	assert.Equal(t, ",", cmd.Args[3].Separator)
+	assert.Equal(t, []customCommandStep{{Action: "create-authinfo1", Fields: []customStepField{
+		{Field: "domain", Value: "${domain}"},
+		{Field: "authinfohash", Value: "${secret}"},
+		{Field: "authinfoexpire", Value: "${expire}"},
+		{Field: "nserver", Value: "${nserver}"},
+	}}}, cmd.Steps)
+}
+
+func TestCustomCommandPrepareErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  customCommand
+		err  string
+	}{
+		{"unknown type", customCommand{Action: "info", Args: []customCommandArg{{Name: "x", Type: "float"}}}, `unknown argument type "float"`},
+		{"enum without values", customCommand{Action: "info", Args: []customCommandArg{{Name: "x", Type: "enum"}}}, `enum argument "x" without values`},
+		{"missing name", customCommand{Action: "info", Args: []customCommandArg{{Type: "string"}}}, "argument 1 without name or field"},
+		{"invalid default", customCommand{Action: "info", Args: []customCommandArg{{Name: "x", Type: "int", Default: "ten"}}}, `invalid default value of argument "x": "ten" is not a number`},
+		{"action and steps", customCommand{Action: "info", Steps: []customCommandStep{{Action: "info"}}}, "action and steps must not be combined"},
+		{"missing action", customCommand{}, "missing action or steps"},
+		{"unknown action", customCommand{Steps: []customCommandStep{{Action: "info"}, {Action: "foo"}}}, `unknown action "foo" in step 2`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.EqualError(t, test.cmd.prepare(), test.err)
+		})
+	}
+}
+
+func TestCustomCommandArgParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		arg    customCommandArg
+		value  string
+		result []string
+		err    string
+	}{
+		{"domain", customCommandArg{Type: customArgTypeDomain}, "DÖNIC.de", []string{"dönic.de"}, ""},
+		{"invalid domain", customCommandArg{Type: customArgTypeDomain}, "denic.com", nil, `"denic.com" is not a .de domain name`},
+		{"handle", customCommandArg{Type: customArgTypeHandle}, "DENIC-1000006-DENIC", []string{"DENIC-1000006-DENIC"}, ""},
+		{"enum", customCommandArg{Type: customArgTypeEnum, Values: []string{"connect", "failed"}}, "CONNECT", []string{"connect"}, ""},
+		{"invalid enum", customCommandArg{Type: customArgTypeEnum, Values: []string{"connect", "failed"}}, "free", nil, `"free" must be one of connect, failed`},
+		{"date", customCommandArg{Type: customArgTypeDate}, "2030-01-31", []string{"20300131"}, ""},
+		{"rri date", customCommandArg{Type: customArgTypeDate}, "20300131", []string{"20300131"}, ""},
+		{"invalid date", customCommandArg{Type: customArgTypeDate}, "31.01.2030", nil, `"31.01.2030" is not a date like YYYY-MM-DD`},
+		{"int", customCommandArg{Type: customArgTypeInt}, "42", []string{"42"}, ""},
+		{"invalid int", customCommandArg{Type: customArgTypeInt}, "4.2", nil, `"4.2" is not a number`},
+		{"list", customCommandArg{Type: customArgTypeList, Separator: ","}, "ns1.denic.de, ns2.denic.de,,", []string{"ns1.denic.de", "ns2.denic.de"}, ""},
+		{"empty list", customCommandArg{Type: customArgTypeList, Separator: ","}, " , ", nil, ""},
+		{"const", customCommandArg{Type: customArgTypeConst, Value: "5.0"}, "ignored", []string{"5.0"}, ""},
+		{"string", customCommandArg{Type: customArgTypeString}, "any value", []string{"any value"}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.arg.parse(test.value)
+			if len(test.err) > 0 {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestReadCustomArgs(t *testing.T) {
+	cmd := customCommand{
+		Action: "info",
+		Args: []customCommandArg{
+			{Name: "domain", Type: "domain"},
+			{Name: "version", Type: "const", Value: "5.0"},
+			{Name: "status", Type: "enum", Values: []string{"connect", "failed"}, Default: "connect"},
+			{Name: "nserver", Type: "list", Optional: true},
+		},
+	}
+	require.NoError(t, cmd.prepare())
+	s := New(nil, nil, nil)
+
+	tests := []struct {
+		name   string
+		args   []string
+		values map[string][]string
+		err    string
+	}{
+		{"defaults", []string{"denic.de"}, map[string][]string{"domain": {"denic.de"}, "version": {"5.0"}, "status": {"connect"}, "nserver": nil}, ""},
+		{"all", []string{"denic.de", "failed", "ns1.denic.de,ns2.denic.de"}, map[string][]string{"domain": {"denic.de"}, "version": {"5.0"}, "status": {"failed"}, "nserver": {"ns1.denic.de", "ns2.denic.de"}}, ""},
+		{"missing", nil, nil, "missing argument 'domain'"},
+		{"invalid", []string{"denic.de", "free"}, nil, `invalid argument 'status': "free" must be one of connect, failed`},
+		{"too many", []string{"denic.de", "failed", "ns1.denic.de", "foo"}, nil, "too many arguments"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := s.readCustomArgs(cmd, test.args)
+			if len(test.err) > 0 {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.values, values)
+		})
+	}
+}
+
+func TestResolveCustomReferences(t *testing.T) {
+	response, err := rri.ParseResponse("RESULT: success\nDomain: denic.de\nNserver: ns1.denic.de\nNserver: ns2.denic.de\n\n[Holder]\nHandle: DENIC-1000006-DENIC\n")
+	require.NoError(t, err)
+	args := map[string][]string{"domain": {"denic.de"}, "nserver": {"ns1.denic.de", "ns2.denic.de"}, "empty": nil}
+	responses := []*rri.Response{response}
+
+	tests := []struct {
+		value  string
+		result []string
+		err    string
+	}{
+		{"plain", []string{"plain"}, ""},
+		{"${domain}", []string{"denic.de"}, ""},
+		{"${nserver}", []string{"ns1.denic.de", "ns2.denic.de"}, ""},
+		{"${empty}", nil, ""},
+		{"www.${domain} via ${nserver}${empty}", []string{"www.denic.de via ns1.denic.de"}, ""},
+		{"${1.Nserver}", []string{"ns1.denic.de", "ns2.denic.de"}, ""},
+		{"${1.Handle}", []string{"DENIC-1000006-DENIC"}, ""},
+		{"holder ${1.Handle}", []string{"holder DENIC-1000006-DENIC"}, ""},
+		{"${unknown}", nil, `unknown argument reference "${unknown}"`},
+		{"${2.Handle}", nil, `reference "${2.Handle}" must refer to a previous step`},
+		{"x ${0.Handle}", nil, `reference "${0.Handle}" must refer to a previous step`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			result, err := resolveCustomReferences(test.value, args, responses)
+			if len(test.err) > 0 {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestRunCustomSteps(t *testing.T) {
+	cmd := customCommand{
+		Args: []customCommandArg{{Name: "domain", Type: "domain"}},
+		Steps: []customCommandStep{
+			{Action: "info", Fields: []customStepField{{Field: "domain", Value: "${domain}"}}},
+			{Action: "update", Fields: []customStepField{{Field: "domain", Value: "${domain}"}, {Field: "nserver", Value: "${1.Nserver}"}, {Field: "holder", Value: "${1.Handle}"}}},
+			{Action: "check", Fields: []customStepField{{Field: "domain", Value: "${domain}"}}},
+		},
+	}
+	require.NoError(t, cmd.prepare())
+
+	var mutex sync.Mutex
+	queries := make([]*rri.Query, 0)
+	failUpdate := false
+	handler := func(user string, session *rri.Session, query *rri.Query) (*rri.Response, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		queries = append(queries, query)
+
+		switch query.Action() {
+		case rri.ActionInfo:
+			return rri.ParseResponse("RESULT: success\nDomain: denic.de\nNserver: ns1.denic.de\nNserver: ns2.denic.de\n\n[Holder]\nHandle: DENIC-1000006-DENIC\n")
+		case rri.ActionUpdate:
+			if failUpdate {
+				return rri.NewResponseWithError(rri.ResultFailure, nil, rri.NewBusinessMessage(53000000001, "failed")), nil
+			}
+		}
+		return rri.NewResponse(rri.ResultSuccess, nil), nil
+	}
+
+	withMockService(t, handler, func(s *Service) {
+		require.NoError(t, s.runCustomSteps(cmd, map[string][]string{"domain": {"denic.de"}}))
+		require.Len(t, queries, 3)
+		assert.Equal(t, []string{"ns1.denic.de", "ns2.denic.de"}, queries[1].Field(rri.QueryFieldNameNameServer))
+		assert.Equal(t, []string{"DENIC-1000006-DENIC"}, queries[1].Field(rri.QueryFieldNameHolder))
+		assert.Equal(t, rri.ActionCheck, queries[2].Action())
+
+		// a failed step skips the remaining steps
+		queries = queries[:0]
+		failUpdate = true
+		assert.EqualError(t, s.runCustomSteps(cmd, map[string][]string{"domain": {"denic.de"}}), "step 2 failed, remaining steps are skipped")
+		assert.Len(t, queries, 2)
+
+		queries = queries[:0]
+		cmd.Steps[1].ContinueOnFailure = true
+		require.NoError(t, s.runCustomSteps(cmd, map[string][]string{"domain": {"denic.de"}}))
+		assert.Len(t, queries, 3)
+	})
+}
+
+func TestReadCustomCommandsSkipsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "info"), []byte(`{"action": "info", "args": [{"type": "domain"}]}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken"), []byte(`{"action": "info", "args": [{"name": "x", "type": "float"}]}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid-json"), []byte(`{`), 0600))
+
+	skipped := make([]string, 0)
+	commands, err := readCustomCommands(dir, func(err error) {
+		skipped = append(skipped, err.Error())
+	})
+	require.NoError(t, err)
+	require.Len(t, commands, 1)
+	assert.Equal(t, "info", commands[0].Cmd)
+	assert.Equal(t, []string{
+		`could not import "broken": unknown argument type "float"`,
+		`could not import "invalid-json": unexpected end of JSON input`,
+	}, skipped)
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
+
+const dataFileArg = "--from"
+
+// splitDataFileArg removes a "--from file" or "--from=file" argument from args. The DataFile of the service is returned if args do not contain such an argument.
+func (s *Service) splitDataFileArg(args []string) ([]string, string, error) {
+	remaining := make([]string, 0, len(args))
+	dataFile := s.DataFile
+
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == dataFileArg:
+			if i+1 >= len(args) {
+				return nil, "", fmt.Errorf("missing file name for %s", dataFileArg)
+			}
+			dataFile = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], dataFileArg+"="):
+			dataFile = strings.TrimPrefix(args[i], dataFileArg+"=")
+		default:
+			remaining = append(remaining, args[i])
+		}
+	}
+
+	return remaining, dataFile, nil
+}
+
+// readDataFile reads a JSON or YAML document from fileName or stdin for "-". Placeholders are replaced like in query files.
+func (s *Service) readDataFile(fileName string) ([]byte, rri.DataFormat, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if fileName == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fileName)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	expanded, err := s.expandVariables(string(data))
+	if err != nil {
+		return nil, "", err
+	}
+	data = []byte(expanded)
+
+	format, ok := rri.DataFormatFromFileName(fileName)
+	if !ok {
+		format = rri.DetectDataFormat(data)
+	}
+
+	return data, format, nil
+}
+
+func (s *Service) readContactDataFile(fileName string) (rri.ContactData, error) {
+	data, format, err := s.readDataFile(fileName)
+	if err != nil {
+		return rri.ContactData{}, err
+	}
+
+	contactData, err := rri.ParseContactData(data, format)
+	if err != nil {
+		return rri.ContactData{}, fmt.Errorf("failed to parse contact data from %q: %w", fileName, err)
+	}
+
+	return contactData, nil
+}
+
+func (s *Service) readDomainDataFile(fileName string) (rri.DomainData, error) {
+	data, format, err := s.readDataFile(fileName)
+	if err != nil {
+		return rri.DomainData{}, err
+	}
+
+	domainData, err := rri.ParseDomainData(data, format)
+	if err != nil {
+		return rri.DomainData{}, fmt.Errorf("failed to parse domain data from %q: %w", fileName, err)
+	}
+
+	return domainData, nil
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/input"
+	"github.com/DENICeG/go-rriclient/pkg/highlight"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
+
+// editorCommand returns the external editor from $VISUAL or $EDITOR.
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.Fields(os.Getenv(name)); len(editor) > 0 {
+			return editor
+		}
+	}
+	return nil
+}
+
+// editQuery lets the user edit content in the external editor if enabled or in the terminal otherwise.
+func (s *Service) editQuery(content string) (string, error) {
+	editor := editorCommand()
+	if !s.UseEditor || len(editor) == 0 {
+		// input.Text cannot render colors, so the query is only highlighted and validated by reviewQuery after editing
+		result, _, err := input.Text(content)
+		return result, err
+	}
+
+	// the extension enables syntax highlighting in most editors
+	pattern := "rri-query-*.txt"
+	if rri.DetectFormat(content) == rri.FormatXML {
+		pattern = "rri-query-*.xml"
+	}
+
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command(editor[0], append(editor[1:], file.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", editor[0], err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// reviewQuery edits content until it is a valid query and the user confirms to send it. Returns false if the user cancels.
+func (s *Service) reviewQuery(content string) (string, bool, error) {
+	for {
+		edited, err := s.editQuery(content)
+		if err != nil {
+			return "", false, err
+		}
+		content = edited
+
+		format := highlight.YAML
+		if rri.DetectFormat(content) == rri.FormatXML {
+			format = highlight.XML
+		}
+		highlighted, err := s.highlightOutput(strings.TrimSpace(content), format)
+		if err != nil {
+			return "", false, err
+		}
+		console.Println(highlighted)
+		console.Println()
+
+		_, errs := rri.ValidateQueryString(content)
+		for _, err := range errs {
+			console.Printlnf("%s%s%s", s.colorErrorResponseMessage, err.Error(), s.colorEnd)
+		}
+
+		question := "Send query? [y]es, [e]dit, [n]o: "
+		if len(errs) > 0 {
+			question = "Query is invalid. [e]dit, [n]o: "
+		}
+
+		answer, err := s.askChoice(question, len(errs) == 0)
+		if err != nil {
+			return "", false, err
+		}
+		switch answer {
+		case "y":
+			return content, true, nil
+		case "n":
+			return "", false, nil
+		}
+	}
+}
+
+// askChoice reads one of y, e or n from the user. y is only accepted if allowSend is true.
+func (s *Service) askChoice(question string, allowSend bool) (string, error) {
+	for {
+		console.Print(question)
+		answer, err := console.ReadLine()
+		if err != nil {
+			return "", err
+		}
+
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		switch {
+		case answer == "y" && allowSend, answer == "e", answer == "n":
+			return answer, nil
+		}
+	}
+}
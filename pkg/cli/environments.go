@@ -0,0 +1,333 @@
+package cli
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-rriclient/internal/env"
+	"github.com/DENICeG/go-rriclient/pkg/highlight"
+)
+
+// EditEnvironment prompts for all settings of an environment showing the current values. Empty input keeps the current value, - clears optional values.
+func EditEnvironment(envi any) error {
+	e, ok := envi.(*env.Environment)
+	if !ok {
+		panic(fmt.Sprintf("environment has unexpected type %T", envi))
+	}
+
+	var err error
+
+	e.Address, err = readSetting("Address (Host:Port)", e.Address, false, nil)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(e.Address, ":") {
+		// did the user forget to specify a port? use default port
+		e.Address += ":51131"
+	}
+
+	e.User, err = readSetting("User", e.User, true, nil)
+	if err != nil {
+		return err
+	}
+
+	console.Print("Password [empty to keep]> ")
+	password, err := console.ReadPassword()
+	if err != nil {
+		return err
+	}
+	if len(password) > 0 {
+		e.Password = password
+	}
+
+	insecure, err := readSetting("Insecure (true|false)", fmt.Sprint(e.Insecure), false, func(value string) error {
+		if value != "true" && value != "false" {
+			return fmt.Errorf("expected true or false")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	e.Insecure = insecure == "true"
+
+	isFile := func(value string) error {
+		_, err := os.Stat(value)
+		return err
+	}
+	e.ClientCert, err = readSetting("Client certificate file", e.ClientCert, true, isFile)
+	if err != nil {
+		return err
+	}
+	if len(e.ClientCert) > 0 {
+		e.ClientKey, err = readSetting("Client key file [empty to read from certificate file]", e.ClientKey, true, isFile)
+		if err != nil {
+			return err
+		}
+	} else {
+		e.ClientKey = ""
+	}
+
+	e.Output, err = readSetting("Output (kv|json|yaml|table)", e.Output, true, func(value string) error {
+		_, err := ParseOutputMode(value)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	e.ColorTheme, err = readSetting("Color theme (like monokai, github or none)", e.ColorTheme, true, func(value string) error {
+		if value != ColorThemeNone && !highlight.IsStyle(value) {
+			return fmt.Errorf("unknown color theme %q", value)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	protected, err := readSetting("Protected, confirm destructive queries (true|false)", fmt.Sprint(e.Protected), false, func(value string) error {
+		if value != "true" && value != "false" {
+			return fmt.Errorf("expected true or false")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	e.Protected = protected == "true"
+	return nil
+}
+
+// readSetting prompts for a value until validate accepts it. Empty input returns current, - returns an empty value if optional is set.
+func readSetting(label, current string, optional bool, validate func(string) error) (string, error) {
+	for {
+		if len(current) > 0 {
+			console.Printf("%s [%s]> ", label, current)
+		} else {
+			console.Printf("%s> ", label)
+		}
+
+		value, err := console.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case len(value) == 0:
+			return current, nil
+		case value == "-" && optional:
+			return "", nil
+		}
+
+		if validate != nil {
+			if err := validate(value); err != nil {
+				console.Printlnf("invalid value: %s", err.Error())
+				continue
+			}
+		}
+		return value, nil
+	}
+}
+
+// ClientCertificates loads the client certificate configured for the environment.
+func ClientCertificates(envi env.Environment) ([]tls.Certificate, error) {
+	if len(envi.ClientCert) == 0 {
+		return nil, nil
+	}
+
+	keyFile := envi.ClientKey
+	if len(keyFile) == 0 {
+		keyFile = envi.ClientCert
+	}
+
+	cert, err := tls.LoadX509KeyPair(envi.ClientCert, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	return []tls.Certificate{cert}, nil
+}
+
+func EditEnv(argEditEnv *string, envReader *env.Reader) (bool, error) {
+	if len(*argEditEnv) == 0 {
+		return false, nil
+	}
+
+	var envi env.Environment
+	if err := envReader.EditEnvironment(*argEditEnv, &envi, EditEnvironment); err != nil {
+		return false, err
+	}
+
+	console.Printlnf("environment %q has been saved", *argEditEnv)
+	return true, nil // should exit after editing env
+}
+
+func RenameEnv(argRenameEnv map[string]string, envReader *env.Reader) (bool, error) {
+	if len(argRenameEnv) == 0 {
+		return false, nil
+	}
+
+	for oldName, newName := range argRenameEnv {
+		if err := envReader.RenameEnvironment(oldName, newName); err != nil {
+			return false, err
+		}
+
+		// keep the command history of the environment
+		historyDir := filepath.Join(envReader.Dir(), "history")
+		err := os.Rename(filepath.Join(historyDir, env.EscapeFileName(oldName)+".json"), filepath.Join(historyDir, env.EscapeFileName(newName)+".json"))
+		if err != nil && !os.IsNotExist(err) {
+			console.Printlnf("WARNING: failed to rename history: %s", err.Error())
+		}
+
+		console.Printlnf("environment %q has been renamed to %q", oldName, newName)
+	}
+
+	return true, nil // should exit after renaming env
+}
+
+func CopyEnv(argCopyEnv map[string]string, envReader *env.Reader) (bool, error) {
+	if len(argCopyEnv) == 0 {
+		return false, nil
+	}
+
+	for srcName, dstName := range argCopyEnv {
+		if err := envReader.CopyEnvironment(srcName, dstName); err != nil {
+			return false, err
+		}
+		console.Printlnf("environment %q has been copied to %q", srcName, dstName)
+	}
+
+	return true, nil // should exit after copying env
+}
+
+func ExportEnv(argExportEnv *string, envNames []string, envReader *env.Reader) (bool, error) {
+	if len(*argExportEnv) == 0 {
+		return false, nil
+	}
+
+	console.Print("Bundle passphrase> ")
+	passphrase, err := console.ReadPassword()
+	if err != nil {
+		return false, err
+	}
+	if len(passphrase) == 0 {
+		return false, fmt.Errorf("passphrase must not be empty")
+	}
+
+	console.Print("Repeat passphrase> ")
+	repeated, err := console.ReadPassword()
+	if err != nil {
+		return false, err
+	}
+	if repeated != passphrase {
+		return false, fmt.Errorf("passphrases do not match")
+	}
+
+	if err := envReader.ExportEnvironments(*argExportEnv, []byte(passphrase), envNames...); err != nil {
+		return false, err
+	}
+
+	console.Printlnf("environments have been exported to %s", *argExportEnv)
+	return true, nil // should exit after exporting env
+}
+
+func ImportEnv(argImportEnv *string, envReader *env.Reader) (bool, error) {
+	if len(*argImportEnv) == 0 {
+		return false, nil
+	}
+
+	console.Print("Bundle passphrase> ")
+	passphrase, err := console.ReadPassword()
+	if err != nil {
+		return false, err
+	}
+
+	imported, skipped, err := envReader.ImportEnvironments(*argImportEnv, []byte(passphrase))
+	for _, name := range imported {
+		console.Printlnf("- %s imported", name)
+	}
+	for _, name := range skipped {
+		console.Printlnf("- %s skipped, environment already exists", name)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil // should exit after importing env
+}
+
+// ReadMasterPassphrase prompts for the master passphrase of environment passwords.
+func ReadMasterPassphrase(prompt string) (string, error) {
+	console.Print(prompt)
+	return console.ReadPassword()
+}
+
+func RekeyEnv(argRekeyEnv *bool, envReader *env.Reader, masterKey *env.MasterKey) (bool, error) {
+	if !*argRekeyEnv {
+		return false, nil
+	}
+
+	current, err := masterKey.Protection()
+	if err != nil {
+		return false, err
+	}
+	console.Printlnf("environment passwords are currently protected by: %s", current)
+
+	// unlock the current key before asking for the new protection
+	if _, err := masterKey.Key(); err != nil {
+		return false, err
+	}
+
+	var protection env.Protection
+	for len(protection) == 0 {
+		console.Print("Protect environment passwords with [p]assphrase, [k]ey file or [n]othing: ")
+		answer, err := console.ReadLine()
+		if err != nil {
+			return false, err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "p":
+			protection = env.ProtectionPassphrase
+		case "k":
+			protection = env.ProtectionKeyFile
+		case "n":
+			protection = env.ProtectionNone
+		}
+	}
+
+	var passphrase string
+	if protection == env.ProtectionPassphrase {
+		passphrase, err = ReadMasterPassphrase("New master passphrase> ")
+		if err != nil {
+			return false, err
+		}
+		repeated, err := ReadMasterPassphrase("Repeat master passphrase> ")
+		if err != nil {
+			return false, err
+		}
+		if repeated != passphrase {
+			return false, fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	newKey, persist, err := masterKey.Change(protection, passphrase)
+	if err != nil {
+		return false, err
+	}
+	if err := envReader.Rekey(newKey, persist); err != nil {
+		return false, err
+	}
+
+	if protection == env.ProtectionKeyFile {
+		console.Printlnf("environment passwords are now protected by key file %s", masterKey.KeyFile)
+	} else {
+		console.Printlnf("environment passwords are now protected by: %s", protection)
+	}
+	return true, nil // should exit after rekeying env
+}
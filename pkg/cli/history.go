@@ -1,21 +1,45 @@
 package cli
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/DENICeG/go-console/v2"
 	"github.com/DENICeG/go-console/v2/commandline"
+	"github.com/DENICeG/go-rriclient/internal/env"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
 )
 
+const (
+	maxCommandHistory = 500
+	maxDomainHistory  = 200
+	maxHandleHistory  = 200
+)
+
+// putUnique puts value to the front of list and removes older duplicates and entries exceeding maxCount.
+func putUnique(list []string, value string, maxCount int) []string {
+	if index := slices.Index(list, value); index >= 0 {
+		list = slices.Delete(list, index, index+1)
+	}
+
+	list = append([]string{value}, list...)
+	if len(list) > maxCount {
+		list = list[:maxCount]
+	}
+	return list
+}
+
 type domainHistory struct {
 	list []string
 }
 
 func (h *domainHistory) Put(domain string) {
-	if slices.Contains(h.list, domain) {
-		return
-	}
-
-	h.list = append([]string{domain}, h.list...)
+	h.list = putUnique(h.list, domain, maxDomainHistory)
 }
 
 func (h *domainHistory) GetCompletionOptions(currentCommand []string, entryIndex int) []commandline.CompletionOption {
@@ -27,11 +51,7 @@ type handleHistory struct {
 }
 
 func (h *handleHistory) Put(handle string) {
-	if slices.Contains(h.list, handle) {
-		return
-	}
-
-	h.list = append([]string{handle}, h.list...)
+	h.list = putUnique(h.list, handle, maxHandleHistory)
 }
 
 func (h *handleHistory) GetCompletionOptions(currentCommand []string, entryIndex int) []commandline.CompletionOption {
@@ -45,3 +65,193 @@ func (h *handleHistory) GetHistoryEntry(index int) (string, bool) {
 
 	return h.list[index], true
 }
+
+type commandHistory struct {
+	list [][]string
+}
+
+func (h *commandHistory) Put(cmd []string) {
+	if index := slices.IndexFunc(h.list, func(entry []string) bool { return slices.Equal(entry, cmd) }); index >= 0 {
+		h.list = slices.Delete(h.list, index, index+1)
+	}
+
+	h.list = append([][]string{cmd}, h.list...)
+	if len(h.list) > maxCommandHistory {
+		h.list = h.list[:maxCommandHistory]
+	}
+}
+
+// GetHistoryEntry returns the command at the given index, where 0 denotes the latest command.
+func (h *commandHistory) GetHistoryEntry(index int) ([]string, bool) {
+	if index >= len(h.list) {
+		return nil, false
+	}
+
+	return h.list[index], true
+}
+
+// historyFile is the persisted form of all histories of an environment.
+type historyFile struct {
+	Commands [][]string `json:"commands"`
+	Domains  []string   `json:"domains"`
+	Handles  []string   `json:"handles"`
+}
+
+// LoadHistory restores the command, domain and handle history for the given environment from the history directory in confDir. All histories are saved there after every command.
+func (s *Service) LoadHistory(confDir, envName string) error {
+	s.historyFile = filepath.Join(confDir, "history", env.EscapeFileName(envName)+".json")
+
+	data, err := os.ReadFile(s.historyFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var hist historyFile
+	if err := json.Unmarshal(data, &hist); err != nil {
+		return err
+	}
+
+	// put in reverse order to keep the latest entries in front
+	for i := len(hist.Commands) - 1; i >= 0; i-- {
+		if len(hist.Commands[i]) > 0 {
+			s.commandHistory.Put(hist.Commands[i])
+		}
+	}
+	for i := len(hist.Domains) - 1; i >= 0; i-- {
+		s.completion.PutDomain(hist.Domains[i])
+	}
+	for i := len(hist.Handles) - 1; i >= 0; i-- {
+		s.completion.PutHandle(hist.Handles[i])
+	}
+
+	return nil
+}
+
+func (s *Service) saveHistory() error {
+	if len(s.historyFile) == 0 {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(historyFile{
+		Commands: s.commandHistory.list,
+		Domains:  s.completion.histDomains.list,
+		Handles:  s.completion.histHandles.list,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.historyFile), 0700); err != nil {
+		return err
+	}
+
+	// write to a temporary file first to not lose the history on failure
+	tmpFile := s.historyFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, s.historyFile)
+}
+
+// putCommandHistory adds cmd to the command history. Passwords and secrets are censored before.
+func (s *Service) putCommandHistory(cmd []string) {
+	if len(cmd) == 0 || len(cmd[0]) == 0 {
+		return
+	}
+
+	s.commandHistory.Put(censorCommand(cmd))
+}
+
+// censoredArg replaces secrets in commands that are stored to the history or transcripts.
+const censoredArg = "******"
+
+// censorCommand removes the password from login commands and replaces auth info secrets and secrets of raw queries with censoredArg.
+func censorCommand(cmd []string) []string {
+	if len(cmd) < 2 {
+		return cmd
+	}
+
+	switch cmd[0] {
+	case "login":
+		if len(cmd) > 2 {
+			return cmd[:2]
+		}
+	case "chprov", "transfer":
+		return censorArg(cmd, 2)
+	case "create":
+		if cmd[1] == "authinfo1" {
+			return censorArg(cmd, 3)
+		}
+	case "raw":
+		msg := unescapeRawArg(cmd[1])
+		if censored := rri.CensorRawMessage(msg); censored != msg {
+			result := slices.Clone(cmd)
+			result[1] = escapeRawArg(censored)
+			return result
+		}
+	}
+	return cmd
+}
+
+// censorArg replaces the positional argument at index with censoredArg. The --from argument is skipped like in splitDataFileArg.
+func censorArg(cmd []string, index int) []string {
+	pos := 0
+	for i := 0; i < len(cmd); i++ {
+		switch {
+		case cmd[i] == dataFileArg:
+			i++
+		case strings.HasPrefix(cmd[i], dataFileArg+"="):
+		default:
+			if pos == index {
+				result := slices.Clone(cmd)
+				result[i] = censoredArg
+				return result
+			}
+			pos++
+		}
+	}
+	return cmd
+}
+
+// resolveHistoryReference replaces commands like !! or !3 with the referenced entry from command history. Additional arguments are appended.
+func (s *Service) resolveHistoryReference(cmd []string) ([]string, bool, error) {
+	if len(cmd) == 0 || !strings.HasPrefix(cmd[0], "!") || len(cmd[0]) < 2 {
+		return cmd, false, nil
+	}
+
+	index := 1
+	if cmd[0] != "!!" {
+		var err error
+		index, err = strconv.Atoi(cmd[0][1:])
+		if err != nil || index < 1 {
+			return nil, false, fmt.Errorf("invalid history reference %q", cmd[0])
+		}
+	}
+
+	entry, ok := s.commandHistory.GetHistoryEntry(index - 1)
+	if !ok {
+		return nil, false, fmt.Errorf("history entry %q not found", cmd[0])
+	}
+	if slices.ContainsFunc(entry, func(arg string) bool { return strings.Contains(arg, censoredArg) }) {
+		return nil, false, fmt.Errorf("history entry %q contains a censored secret, enter the command again", cmd[0])
+	}
+
+	return append(slices.Clone(entry), cmd[1:]...), true, nil
+}
+
+func (s *Service) cmdHistory(args []string) error {
+	search := strings.ToLower(strings.Join(args, " "))
+
+	for i := len(s.commandHistory.list) - 1; i >= 0; i-- {
+		cmdString := commandline.GetCommandString(s.commandHistory.list[i])
+		if len(search) > 0 && !strings.Contains(strings.ToLower(cmdString), search) {
+			continue
+		}
+		console.Printlnf("%5d  %s", i+1, cmdString)
+	}
+
+	return nil
+}
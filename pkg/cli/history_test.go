@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCensorCommand(t *testing.T) {
+	tests := []struct {
+		cmd      []string
+		expected []string
+	}{
+		{[]string{"login", "DENIC-1000011-TEST", "secret"}, []string{"login", "DENIC-1000011-TEST"}},
+		{[]string{"login", "DENIC-1000011-TEST"}, []string{"login", "DENIC-1000011-TEST"}},
+		{[]string{"chprov", "denic.de", "secret", "holder", "DENIC-1000011-HOLDER"}, []string{"chprov", "denic.de", "******", "holder", "DENIC-1000011-HOLDER"}},
+		{[]string{"chprov", "--from", "data.yaml", "denic.de", "secret"}, []string{"chprov", "--from", "data.yaml", "denic.de", "******"}},
+		{[]string{"transfer", "--from=data.yaml", "denic.de", "secret"}, []string{"transfer", "--from=data.yaml", "denic.de", "******"}},
+		{[]string{"transfer", "denic.de"}, []string{"transfer", "denic.de"}},
+		{[]string{"create", "authinfo1", "denic.de", "secret", "2030-01-01"}, []string{"create", "authinfo1", "denic.de", "******", "2030-01-01"}},
+		{[]string{"create", "domain", "denic.de", "holder", "DENIC-1000011-HOLDER"}, []string{"create", "domain", "denic.de", "holder", "DENIC-1000011-HOLDER"}},
+		{[]string{"raw", `version: 5.0\naction: LOGIN\nuser: DENIC-1000011-TEST\npassword: secret`}, []string{"raw", `version: 5.0\naction: LOGIN\nuser: DENIC-1000011-TEST\npassword: ******`}},
+		{[]string{"raw", `version: 5.0\naction: CHPROV\ndomain: denic.de\nauthinfo: secret`}, []string{"raw", `version: 5.0\naction: CHPROV\ndomain: denic.de\nauthinfo: ******`}},
+		{[]string{"raw", `version: 5.0\naction: INFO\ndomain: denic.de`}, []string{"raw", `version: 5.0\naction: INFO\ndomain: denic.de`}},
+		{[]string{"info", "domain", "denic.de"}, []string{"info", "domain", "denic.de"}},
+	}
+
+	for _, test := range tests {
+		cmd := append([]string{}, test.cmd...)
+		assert.Equal(t, test.expected, censorCommand(cmd), test.cmd)
+		// the executed command is not modified
+		assert.Equal(t, test.cmd, cmd)
+	}
+}
+
+func TestCommandHistory(t *testing.T) {
+	s := New(nil, nil, nil)
+	s.putCommandHistory([]string{"info", "denic.de"})
+	s.putCommandHistory([]string{"check", "denic.de"})
+	s.putCommandHistory([]string{"info", "denic.de"})
+	s.putCommandHistory([]string{""})
+	assert.Equal(t, [][]string{{"info", "denic.de"}, {"check", "denic.de"}}, s.commandHistory.list)
+
+	for i := 0; i < maxCommandHistory+10; i++ {
+		s.putCommandHistory([]string{"info", fmt.Sprintf("denic-%d.de", i)})
+	}
+	require.Len(t, s.commandHistory.list, maxCommandHistory)
+	assert.Equal(t, []string{"info", fmt.Sprintf("denic-%d.de", maxCommandHistory+9)}, s.commandHistory.list[0])
+
+	for i := 0; i < maxDomainHistory+10; i++ {
+		s.completion.PutDomain(fmt.Sprintf("denic-%d.de", i))
+		s.completion.PutHandle(fmt.Sprintf("DENIC-1000011-%d", i))
+	}
+	s.completion.PutDomain("denic-0.de")
+	assert.Len(t, s.completion.histDomains.list, maxDomainHistory)
+	assert.Len(t, s.completion.histHandles.list, maxHandleHistory)
+	assert.Equal(t, "denic-0.de", s.completion.histDomains.list[0])
+	assert.Equal(t, "denic-209.de", s.completion.histDomains.list[1])
+}
+
+func TestResolveHistoryReference(t *testing.T) {
+	s := New(nil, nil, nil)
+	s.putCommandHistory([]string{"info", "domain", "denic.de"})
+	s.putCommandHistory([]string{"chprov", "denic.de", "secret"})
+	s.putCommandHistory([]string{"check", "domain"})
+
+	tests := []struct {
+		cmd      []string
+		expected []string
+		resolved bool
+		err      string
+	}{
+		{[]string{"info", "domain", "denic.de"}, []string{"info", "domain", "denic.de"}, false, ""},
+		{[]string{"!"}, []string{"!"}, false, ""},
+		{[]string{"!!", "denic.de"}, []string{"check", "domain", "denic.de"}, true, ""},
+		{[]string{"!3"}, []string{"info", "domain", "denic.de"}, true, ""},
+		{[]string{"!2"}, nil, false, `history entry "!2" contains a censored secret, enter the command again`},
+		{[]string{"!4"}, nil, false, `history entry "!4" not found`},
+		{[]string{"!0"}, nil, false, `invalid history reference "!0"`},
+		{[]string{"!x"}, nil, false, `invalid history reference "!x"`},
+	}
+
+	for _, test := range tests {
+		cmd, resolved, err := s.resolveHistoryReference(test.cmd)
+		if len(test.err) > 0 {
+			assert.EqualError(t, err, test.err, test.cmd)
+			continue
+		}
+		require.NoError(t, err, test.cmd)
+		assert.Equal(t, test.expected, cmd, test.cmd)
+		assert.Equal(t, test.resolved, resolved, test.cmd)
+	}
+}
+
+func TestSaveAndLoadHistory(t *testing.T) {
+	dir := t.TempDir()
+	s := New(nil, nil, nil)
+	require.NoError(t, s.LoadHistory(dir, "test/env"))
+	s.putCommandHistory([]string{"info", "domain", "denic.de"})
+	s.putCommandHistory([]string{"login", "DENIC-1000011-TEST", "secret"})
+	s.completion.PutDomain("denic.de")
+	require.NoError(t, s.saveHistory())
+	assert.FileExists(t, filepath.Join(dir, "history", "test%2Fenv.json"))
+
+	loaded := New(nil, nil, nil)
+	require.NoError(t, loaded.LoadHistory(dir, "test/env"))
+	assert.Equal(t, [][]string{{"login", "DENIC-1000011-TEST"}, {"info", "domain", "denic.de"}}, loaded.commandHistory.list)
+	assert.Equal(t, []string{"denic.de"}, loaded.completion.histDomains.list)
+}
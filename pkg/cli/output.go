@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-rriclient/pkg/highlight"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"gopkg.in/yaml.v3"
+)
+
+// OutputMode denotes how RRI responses are printed.
+type OutputMode string
+
+const (
+	// OutputKV prints responses in the key-value representation as returned by RRI.
+	OutputKV OutputMode = "kv"
+	// OutputJSON prints responses as structured JSON document.
+	OutputJSON OutputMode = "json"
+	// OutputYAML prints responses as structured YAML document.
+	OutputYAML OutputMode = "yaml"
+	// OutputTable prints responses as aligned table.
+	OutputTable OutputMode = "table"
+)
+
+// ColorThemeNone disables all colors when used as color theme.
+const ColorThemeNone = "none"
+
+// OutputModes returns all supported output modes.
+func OutputModes() []OutputMode {
+	return []OutputMode{OutputKV, OutputJSON, OutputYAML, OutputTable}
+}
+
+// ParseOutputMode parses an output mode from string.
+func ParseOutputMode(str string) (OutputMode, error) {
+	for _, mode := range OutputModes() {
+		if strings.EqualFold(str, string(mode)) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("invalid output mode %q", str)
+}
+
+type businessMessageDocument struct {
+	ID      int64  `json:"id" yaml:"id"`
+	Message string `json:"message" yaml:"message"`
+}
+
+type entityDocument struct {
+	Name   string              `json:"name" yaml:"name"`
+	Fields map[string][]string `json:"fields" yaml:"fields"`
+}
+
+// responseDocument is the structured representation of a response for JSON and YAML output.
+type responseDocument struct {
+	Result   rri.Result                `json:"result" yaml:"result"`
+	STID     string                    `json:"stid,omitempty" yaml:"stid,omitempty"`
+	Info     []businessMessageDocument `json:"info,omitempty" yaml:"info,omitempty"`
+	Warnings []businessMessageDocument `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+	Errors   []businessMessageDocument `json:"errors,omitempty" yaml:"errors,omitempty"`
+	Fields   map[string][]string       `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Entities []entityDocument          `json:"entities,omitempty" yaml:"entities,omitempty"`
+}
+
+func newBusinessMessageDocuments(messages []rri.BusinessMessage) []businessMessageDocument {
+	documents := make([]businessMessageDocument, len(messages))
+	for i, msg := range messages {
+		documents[i] = businessMessageDocument{ID: msg.ID(), Message: msg.Message()}
+	}
+	return documents
+}
+
+// isMetaResponseField returns true for fields that are represented explicitly in responseDocument.
+func isMetaResponseField(name rri.ResponseFieldName) bool {
+	switch name.Normalize() {
+	case rri.ResponseFieldNameResult.Normalize(), rri.ResponseFieldNameSTID.Normalize(), rri.ResponseFieldNameInfo.Normalize(), rri.ResponseFieldNameWarning.Normalize(), rri.ResponseFieldNameError.Normalize():
+		return true
+	default:
+		return false
+	}
+}
+
+func fieldsToMap(fields rri.ResponseFieldList, skipMeta bool) map[string][]string {
+	result := make(map[string][]string)
+	for _, f := range fields {
+		if skipMeta && isMetaResponseField(f.Name) {
+			continue
+		}
+		name := strings.ToLower(string(f.Name))
+		result[name] = append(result[name], f.Value)
+	}
+	return result
+}
+
+func newResponseDocument(res *rri.Response) responseDocument {
+	doc := responseDocument{
+		Result:   res.Result(),
+		STID:     res.STID(),
+		Info:     newBusinessMessageDocuments(res.InfoMessages()),
+		Warnings: newBusinessMessageDocuments(res.WarningMessages()),
+		Errors:   newBusinessMessageDocuments(res.ErrorMessages()),
+		Fields:   fieldsToMap(res.Fields(), true),
+	}
+
+	for _, e := range res.Entities() {
+		doc.Entities = append(doc.Entities, entityDocument{Name: string(e.Name().Normalize()), Fields: fieldsToMap(e.Fields(), false)})
+	}
+
+	return doc
+}
+
+// printNotice prints an informational message. It is written to stderr for JSON and YAML output to keep stdout parseable.
+func (s *Service) printNotice(msg string) {
+	if s.Output == OutputJSON || s.Output == OutputYAML {
+		fmt.Fprintln(os.Stderr, msg)
+		return
+	}
+	console.Println(msg)
+}
+
+// highlightOutput applies syntax highlighting to input unless colors are disabled.
+func (s *Service) highlightOutput(input string, format highlight.Format) (string, error) {
+	if !s.colors {
+		return input, nil
+	}
+	return highlight.TransformWithStyle(input, format, s.colorTheme)
+}
+
+// SetColorTheme sets the syntax highlighting style for responses and queries. The theme none disables all colors.
+func (s *Service) SetColorTheme(theme string) error {
+	switch {
+	case len(theme) == 0:
+		theme = highlight.DefaultStyle
+	case theme == ColorThemeNone:
+		s.disableColors()
+		s.colorTheme = theme
+		return nil
+	case !highlight.IsStyle(theme):
+		return fmt.Errorf("unknown color theme %q", theme)
+	}
+
+	// colors of a previous theme none are restored
+	if s.terminalColors {
+		s.enableColors()
+	}
+	s.colorTheme = theme
+	return nil
+}
+
+// printResponse prints res according to the configured output mode.
+func (s *Service) printResponse(res *rri.Response) error {
+	switch s.Output {
+	case OutputJSON:
+		data, err := json.MarshalIndent(newResponseDocument(res), "", "  ")
+		if err != nil {
+			return err
+		}
+		console.Println(string(data))
+
+	case OutputYAML:
+		var sb strings.Builder
+		encoder := yaml.NewEncoder(&sb)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(newResponseDocument(res)); err != nil {
+			return err
+		}
+		console.Print(sb.String())
+
+	case OutputTable:
+		console.Print(s.formatResponseTable(res))
+
+	default:
+		resString, err := s.highlightOutput(res.String(), highlight.YAML)
+		if err != nil {
+			return fmt.Errorf("failed to transform response: %w", err)
+		}
+		console.Println(resString)
+	}
+
+	return nil
+}
+
+// printRawResponse parses a KV or XML response as returned by SendRaw and prints it according to the output mode. XML responses are printed as returned for kv output.
+func (s *Service) printRawResponse(raw string) (*rri.Response, error) {
+	format := rri.DetectFormat(raw)
+
+	response, err := rri.ParseResponse(raw)
+	if err != nil {
+		console.Println(raw)
+		return nil, fmt.Errorf("RRI server returned an invalid response: %w", err)
+	}
+
+	s.completion.harvestResponse(response)
+	s.lastResponse = response
+
+	if s.Output == OutputKV && format == rri.FormatXML {
+		highlighted, err := s.highlightOutput(raw, highlight.XML)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transform response: %w", err)
+		}
+		console.Println(highlighted)
+		return response, nil
+	}
+
+	return response, s.printResponse(response)
+}
+
+// formatResponseTable returns an aligned table of all response fields followed by one section per entity.
+func (s *Service) formatResponseTable(res *rri.Response) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+
+	resultColor := s.colorErrorResponseMessage
+	if res.IsSuccessful() {
+		resultColor = s.colorSuccessResponse
+	}
+	fmt.Fprintf(w, "%s\t%s%s%s\n", rri.ResponseFieldNameResult, resultColor, res.Result(), s.colorEnd)
+
+	for _, f := range res.Fields() {
+		if f.Name.Normalize() == rri.ResponseFieldNameResult.Normalize() {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\n", f.Name, f.Value)
+	}
+
+	for _, e := range res.Entities() {
+		fmt.Fprintf(w, "\n[%s]\n", strings.ToUpper(string(e.Name())))
+		for _, f := range e.Fields() {
+			fmt.Fprintf(w, "%s\t%s\n", f.Name, f.Value)
+		}
+	}
+
+	w.Flush()
+	return sb.String()
+}
+
+func (s *Service) cmdOutput(args []string) error {
+	if len(args) == 0 {
+		console.Printlnf("output mode is %s", s.Output)
+		return nil
+	}
+
+	mode, err := ParseOutputMode(args[0])
+	if err != nil {
+		return err
+	}
+
+	s.Output = mode
+	console.Printlnf("output mode is now %s", s.Output)
+	return nil
+}
@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/highlight"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOutputResponse = "RESULT: failed\nSTID: abc-1\nERROR: 53000000001 domain not found\nINFO: 13000000011 test environment\n\nDomain: denic.de\nNserver: ns1.denic.de.\nNserver: ns2.denic.de.\n\n[Holder]\nHandle: DENIC-1000006-DENIC\nName: DENIC eG\n"
+
+func parseTestOutputResponse(t *testing.T) *rri.Response {
+	response, err := rri.ParseResponse(testOutputResponse)
+	require.NoError(t, err)
+	return response
+}
+
+func TestParseOutputMode(t *testing.T) {
+	tests := map[string]OutputMode{
+		"kv":    OutputKV,
+		"JSON":  OutputJSON,
+		"yaml":  OutputYAML,
+		"Table": OutputTable,
+	}
+	for str, expected := range tests {
+		mode, err := ParseOutputMode(str)
+		require.NoError(t, err, str)
+		assert.Equal(t, expected, mode, str)
+	}
+
+	for _, str := range []string{"", "xml", "yml"} {
+		_, err := ParseOutputMode(str)
+		assert.EqualError(t, err, "invalid output mode \""+str+"\"", str)
+	}
+}
+
+func TestNewResponseDocument(t *testing.T) {
+	doc := newResponseDocument(parseTestOutputResponse(t))
+	assert.Equal(t, responseDocument{
+		Result:   rri.Result("failed"),
+		STID:     "abc-1",
+		Info:     []businessMessageDocument{{ID: 13000000011, Message: "test environment"}},
+		Warnings: []businessMessageDocument{},
+		Errors:   []businessMessageDocument{{ID: 53000000001, Message: "domain not found"}},
+		Fields:   map[string][]string{"domain": {"denic.de"}, "nserver": {"ns1.denic.de.", "ns2.denic.de."}},
+		Entities: []entityDocument{{Name: "holder", Fields: map[string][]string{"handle": {"DENIC-1000006-DENIC"}, "name": {"DENIC eG"}}}},
+	}, doc)
+}
+
+func TestPrintResponse(t *testing.T) {
+	tests := []struct {
+		mode     OutputMode
+		expected string
+	}{
+		{OutputJSON, `{
+  "result": "failed",
+  "stid": "abc-1",
+  "info": [
+    {
+      "id": 13000000011,
+      "message": "test environment"
+    }
+  ],
+  "errors": [
+    {
+      "id": 53000000001,
+      "message": "domain not found"
+    }
+  ],
+  "fields": {
+    "domain": [
+      "denic.de"
+    ],
+    "nserver": [
+      "ns1.denic.de.",
+      "ns2.denic.de."
+    ]
+  },
+  "entities": [
+    {
+      "name": "holder",
+      "fields": {
+        "handle": [
+          "DENIC-1000006-DENIC"
+        ],
+        "name": [
+          "DENIC eG"
+        ]
+      }
+    }
+  ]
+}
+`},
+		{OutputYAML, `result: failed
+stid: abc-1
+info:
+  - id: 13000000011
+    message: test environment
+errors:
+  - id: 53000000001
+    message: domain not found
+fields:
+  domain:
+    - denic.de
+  nserver:
+    - ns1.denic.de.
+    - ns2.denic.de.
+entities:
+  - name: holder
+    fields:
+      handle:
+        - DENIC-1000006-DENIC
+      name:
+        - DENIC eG
+`},
+		{OutputTable, `RESULT   failed
+STID     abc-1
+ERROR    53000000001 domain not found
+INFO     13000000011 test environment
+DOMAIN   denic.de
+NSERVER  ns1.denic.de.
+NSERVER  ns2.denic.de.
+
+[HOLDER]
+HANDLE  DENIC-1000006-DENIC
+NAME    DENIC eG
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.mode), func(t *testing.T) {
+			s := New(nil, nil, nil)
+			s.disableColors()
+			s.Output = test.mode
+			withConsoleMock(t, nil, func(m *consoleMock) {
+				require.NoError(t, s.printResponse(parseTestOutputResponse(t)))
+				assert.Equal(t, test.expected, m.output.String())
+			})
+		})
+	}
+
+	// kv output is printed as received without colors
+	s := New(nil, nil, nil)
+	s.disableColors()
+	withConsoleMock(t, nil, func(m *consoleMock) {
+		response := parseTestOutputResponse(t)
+		require.NoError(t, s.printResponse(response))
+		assert.Equal(t, response.EncodeKV()+"\n", m.output.String())
+	})
+}
+
+func TestSetColorTheme(t *testing.T) {
+	tests := []struct {
+		theme    string
+		expected string
+		colors   bool
+		err      string
+	}{
+		{"", highlight.DefaultStyle, true, ""},
+		{"github", "github", true, ""},
+		{ColorThemeNone, ColorThemeNone, false, ""},
+		{"unknown", "", false, `unknown color theme "unknown"`},
+	}
+
+	for _, test := range tests {
+		s := New(nil, nil, nil)
+		// colors are only enabled for terminals
+		s.terminalColors = true
+		s.enableColors()
+
+		err := s.SetColorTheme(test.theme)
+		if len(test.err) > 0 {
+			assert.EqualError(t, err, test.err, test.theme)
+			continue
+		}
+		require.NoError(t, err, test.theme)
+		assert.Equal(t, test.expected, s.colorTheme, test.theme)
+		assert.Equal(t, test.colors, s.colors, test.theme)
+		assert.Equal(t, test.colors, len(s.colorEnd) > 0, test.theme)
+
+		highlighted, err := s.highlightOutput("domain: denic.de", highlight.YAML)
+		require.NoError(t, err)
+		assert.Equal(t, !test.colors, highlighted == "domain: denic.de", test.theme)
+	}
+
+	// colors are restored when switching back from none
+	s := New(nil, nil, nil)
+	s.terminalColors = true
+	require.NoError(t, s.SetColorTheme(ColorThemeNone))
+	require.NoError(t, s.SetColorTheme("monokai"))
+	assert.True(t, s.colors)
+
+	// but never enabled without terminal
+	s.terminalColors = false
+	s.disableColors()
+	require.NoError(t, s.SetColorTheme("monokai"))
+	assert.False(t, s.colors)
+}
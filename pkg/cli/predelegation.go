@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
+
+// precheckActions denotes the actions that delegate a domain to name servers.
+var precheckActions = []rri.QueryAction{rri.ActionCreate, rri.ActionUpdate, rri.ActionChangeProvider}
+
+// precheckQuery checks the name servers of queries that delegate a domain if enabled and returns an error if the registry would reject them.
+func (s *Service) precheckQuery(query *rri.Query) error {
+	if !s.Precheck || !slices.Contains(precheckActions, query.Action().Normalize()) {
+		return nil
+	}
+
+	domain := queryDomain(query)
+	nameServers := query.Field(rri.QueryFieldNameNameServer)
+	if len(domain) == 0 || len(nameServers) == 0 {
+		return nil
+	}
+
+	result, err := s.predelegationCheck(domain, nameServers, query.Field(rri.QueryFieldNameDNSKey))
+	if err != nil {
+		return err
+	}
+	if !result.IsSuccessful() {
+		return fmt.Errorf("pre-delegation check for %s failed, disable the check to send the query anyway", domain)
+	}
+	return nil
+}
+
+// precheckRawQuery is like precheckQuery for raw KV or XML queries. Queries that cannot be parsed are not checked and rejected by the server.
+func (s *Service) precheckRawQuery(raw string) error {
+	if !s.Precheck {
+		return nil
+	}
+
+	query, err := rri.ParseQuery(strings.TrimSpace(raw))
+	if err != nil {
+		return nil
+	}
+	return s.precheckQuery(query)
+}
+
+func (s *Service) predelegationCheck(domain string, nameServers, dnsKeys []string) (*rri.PredelegationResult, error) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	s.printNotice(fmt.Sprintf("checking name servers of %s ...", domain))
+	result, err := rri.PredelegationCheck(ctx, domain, rri.DomainData{NameServers: nameServers}, rri.PredelegationOptions{DNSKeys: dnsKeys})
+	if err != nil {
+		return nil, err
+	}
+
+	s.printPredelegationResult(result)
+	return result, nil
+}
+
+func (s *Service) printPredelegationResult(result *rri.PredelegationResult) {
+	for _, check := range result.NameServers {
+		if check.Err == nil {
+			s.printNotice(fmt.Sprintf("  %s (%s): serial %d, %d NS, %d DNSKEY", check.Name, check.Address, check.Serial, len(check.NameServers), len(check.DNSKeys)))
+		}
+	}
+	for _, ds := range result.DS {
+		s.printNotice(fmt.Sprintf("  DS %s", ds))
+	}
+	for _, warning := range result.Warnings {
+		s.printNotice(fmt.Sprintf("%sWARNING: %s%s", s.colorProtected, warning, s.colorEnd))
+	}
+	for _, msg := range result.Errors {
+		s.printNotice(fmt.Sprintf("%sERROR: %s%s", s.colorErrorResponseMessage, msg, s.colorEnd))
+	}
+	if result.IsSuccessful() {
+		s.printNotice(fmt.Sprintf("%sname servers of %s are ready for delegation%s", s.colorSuccessResponse, result.Domain, s.colorEnd))
+	}
+}
+
+func (s *Service) cmdPrecheck(args []string) error {
+	if len(args) == 0 {
+		if s.Precheck {
+			s.printNotice("Pre-delegation check on")
+		} else {
+			s.printNotice("Pre-delegation check off")
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "on", "off":
+		s.Precheck = args[0] == "on"
+		return s.cmdPrecheck(nil)
+	}
+
+	domain, err := parseDomainArg(args[0])
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("missing name servers")
+	}
+	s.completion.PutDomain(domain)
+
+	// addresses following a name server are its glue records
+	nameServers := make([]string, 0)
+	for _, arg := range args[1:] {
+		if net.ParseIP(arg) != nil && len(nameServers) > 0 {
+			nameServers[len(nameServers)-1] += " " + arg
+			continue
+		}
+		nameServers = append(nameServers, arg)
+	}
+
+	result, err := s.predelegationCheck(domain, nameServers, nil)
+	if err != nil {
+		return err
+	}
+	if s.ReturnErrorOnFail && !result.IsSuccessful() {
+		return fmt.Errorf("pre-delegation check for %s failed", domain)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachableDelegation is rejected by the pre-delegation check, because no name server listens on the glue address.
+const unreachableDelegation = "version: 5.0\naction: CHPROV\ndomain: example.de\nnserver: ns1.example.de 127.0.0.1\nholder: DENIC-1000011-HOLDER\nauthinfo: secret\n"
+
+func TestPrecheckBeforeConfirm(t *testing.T) {
+	s := newProtectedService()
+	s.Precheck = true
+
+	query, err := rri.ParseQuery(unreachableDelegation)
+	require.NoError(t, err)
+
+	// queries that would be rejected are not confirmed first
+	withConsoleMock(t, nil, func(m *consoleMock) {
+		_, err := s.sendQuery(query)
+		assert.EqualError(t, err, "pre-delegation check for example.de failed, disable the check to send the query anyway")
+		assert.NotContains(t, m.output.String(), "Type the domain name")
+	})
+}
+
+func TestPrecheckRawQuery(t *testing.T) {
+	s := New(nil, nil, nil)
+	withConsoleMock(t, nil, func(m *consoleMock) {
+		assert.NoError(t, s.precheckRawQuery(unreachableDelegation))
+
+		s.Precheck = true
+		assert.EqualError(t, s.precheckRawQuery(unreachableDelegation), "pre-delegation check for example.de failed, disable the check to send the query anyway")
+		assert.NoError(t, s.precheckRawQuery("version: 5.0\naction: INFO\ndomain: example.de\n"))
+		assert.NoError(t, s.precheckRawQuery("<?xml version=\"1.0\"?><registry-request>"))
+	})
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/DENICeG/go-rriclient/pkg/highlight"
+	"github.com/DENICeG/go-rriclient/pkg/preset"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/manifoldco/promptui"
+)
+
+const presetPreviewLines = 15
+
+// presetItem is a single entry of the interactive preset picker.
+type presetItem struct {
+	preset.Entry
+	// Category groups presets by type and directory like kv/domain.
+	Category string
+}
+
+func newPresetItem(entry preset.Entry) presetItem {
+	return presetItem{Entry: entry, Category: path.Join(string(entry.Type), entry.DirName)}
+}
+
+// newPresetItems returns the items of all presets in the order of data, which groups them by category.
+func newPresetItems(data *preset.Data) []presetItem {
+	items := make([]presetItem, len(data.Preset))
+	for i := range items {
+		items[i] = newPresetItem(data.Preset[i])
+	}
+	return items
+}
+
+// searchText returns the text the search input of the picker is matched against.
+func (item presetItem) searchText() string {
+	return item.Category + "/" + item.FileName
+}
+
+// fuzzyMatch returns true if all characters of pattern except spaces occur in str in the same order ignoring character casing.
+func fuzzyMatch(pattern, str string) bool {
+	remaining := []rune(strings.ToLower(str))
+	for _, r := range strings.ToLower(pattern) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+
+		index := -1
+		for i, c := range remaining {
+			if c == r {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return false
+		}
+		remaining = remaining[index+1:]
+	}
+	return true
+}
+
+// presetPreview returns the first lines of a preset with syntax highlighting.
+func (s *Service) presetPreview(item presetItem) string {
+	content, err := item.Read()
+	if err != nil {
+		return err.Error()
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) > presetPreviewLines {
+		lines = append(lines[:presetPreviewLines], "...")
+	}
+	preview := strings.Join(lines, "\n")
+
+	format := highlight.YAML
+	if item.Type == rri.FormatXML {
+		format = highlight.XML
+	}
+	highlighted, err := s.highlightOutput(preview, format)
+	if err != nil {
+		return preview
+	}
+	return highlighted
+}
+
+// pickPreset shows all presets in a fuzzy searchable list with a preview of the selected preset.
+func (s *Service) pickPreset() (*preset.Entry, error) {
+	if len(s.presets.Preset) == 0 {
+		return nil, fmt.Errorf("no presets available")
+	}
+
+	items := newPresetItems(s.presets)
+
+	funcMap := template.FuncMap{"preview": s.presetPreview}
+	for name, f := range promptui.FuncMap {
+		funcMap[name] = f
+	}
+
+	ui := promptui.Select{
+		Label: "Search preset",
+		Items: items,
+		Size:  10,
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ . }}",
+			Active:   "> {{ .Category | faint }} {{ .FileName | cyan }}{{ if not .IsBuiltin }} {{ .Source | faint }}{{ end }}",
+			Inactive: "  {{ .Category | faint }} {{ .FileName }}{{ if not .IsBuiltin }} {{ .Source | faint }}{{ end }}",
+			Selected: "{{ .Category | faint }} {{ .FileName }}",
+			Details:  "\n{{ preview . }}",
+			FuncMap:  funcMap,
+		},
+		Searcher: func(input string, index int) bool {
+			return fuzzyMatch(input, items[index].searchText())
+		},
+		StartInSearchMode: true,
+		HideSelected:      true,
+	}
+
+	index, _, err := ui.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	return &items[index].Entry, nil
+}
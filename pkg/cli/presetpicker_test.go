@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/preset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		str     string
+		match   bool
+	}{
+		{"", "kv/domain/domain_check", true},
+		{"dochk", "kv/domain/domain_check", true},
+		{"DoChk", "kv/domain/domain_check", true},
+		{"kv info", "kv/handle/handle_info", true},
+		{"xml", "kv/domain/domain_check", false},
+		{"kchd", "kv/domain/domain_check", false},
+		{"checkk", "kv/domain/domain_check", false},
+		{"dömain", "kv/dömain/info", true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.match, fuzzyMatch(test.pattern, test.str), "%q in %q", test.pattern, test.str)
+	}
+}
+
+func TestNewPresetItems(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"xml/domain/domain_check.xml", "kv/handle/handle_info", "kv/domain/domain_info", "kv/domain/domain_check", "kv/login"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte("version: 5.0\n"), 0600))
+	}
+	data, err := preset.Load(preset.DirSources(dir)...)
+	require.NoError(t, err)
+
+	items := newPresetItems(data)
+	searchTexts := make([]string, 0, len(items))
+	for _, item := range items {
+		searchTexts = append(searchTexts, item.searchText())
+	}
+	assert.Equal(t, []string{
+		"kv/login",
+		"kv/domain/domain_check",
+		"kv/domain/domain_info",
+		"kv/handle/handle_info",
+		"xml/domain/domain_check.xml",
+	}, searchTexts)
+
+	// presets at the top of a type directory are grouped by type only
+	assert.Equal(t, "kv", items[0].Category)
+	assert.Equal(t, "kv/domain", items[1].Category)
+	assert.Equal(t, "xml/domain", items[4].Category)
+}
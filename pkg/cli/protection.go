@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
+
+// destructiveActions need to be confirmed before they are sent to protected environments.
+var destructiveActions = []rri.QueryAction{rri.ActionDelete, rri.ActionTransit, rri.ActionChangeHolder, rri.ActionChangeProvider}
+
+// secretQueryFields are not shown in confirmation summaries.
+var secretQueryFields = []rri.QueryFieldName{rri.QueryFieldNamePassword, rri.QueryFieldNameAuthInfo}
+
+func isDestructiveAction(action rri.QueryAction) bool {
+	return slices.Contains(destructiveActions, action.Normalize())
+}
+
+func queryDomain(query *rri.Query) string {
+	if domain := query.FirstField(rri.QueryFieldNameDomainIDN); len(domain) > 0 {
+		return domain
+	}
+	return query.FirstField(rri.QueryFieldNameDomainACE)
+}
+
+// protectedTarget returns the name of the environment or the address of the current connection.
+func (s *Service) protectedTarget() string {
+	if len(s.EnvName) > 0 {
+		return s.EnvName
+	}
+	return s.rriClient.RemoteAddress()
+}
+
+// confirmQuery shows a summary of destructive queries for protected environments and requires typing the domain name to send it.
+func (s *Service) confirmQuery(query *rri.Query) error {
+	if !s.Protected || s.AssumeYes || !isDestructiveAction(query.Action()) {
+		return nil
+	}
+
+	console.Printlnf("%sProtected environment %q, the following query is destructive:%s", s.colorProtected, s.protectedTarget(), s.colorEnd)
+	for _, field := range query.Fields() {
+		value := field.Value
+		if slices.Contains(secretQueryFields, field.Name.Normalize()) {
+			value = "******"
+		}
+		console.Printlnf("  %s: %s", field.Name, value)
+	}
+
+	domain := queryDomain(query)
+	if len(domain) == 0 {
+		// nothing to identify the query by, confirm the environment instead
+		return s.confirmProtected("environment name", s.protectedTarget())
+	}
+	return s.confirmProtected("domain name", domain)
+}
+
+// confirmRawQuery is like confirmQuery for raw KV or XML queries. Queries that cannot be parsed might be destructive and require the environment name.
+func (s *Service) confirmRawQuery(raw string) error {
+	if !s.Protected || s.AssumeYes {
+		return nil
+	}
+
+	query, err := rri.ParseQuery(strings.TrimSpace(raw))
+	if err != nil {
+		console.Printlnf("%sProtected environment %q, the query cannot be checked: %s%s", s.colorProtected, s.protectedTarget(), err.Error(), s.colorEnd)
+		return s.confirmProtected("environment name", s.protectedTarget())
+	}
+	return s.confirmQuery(query)
+}
+
+// confirmBatch lists all destructive queries of a query file for protected environments and requires a single confirmation for all of them.
+func (s *Service) confirmBatch(queries []batchQuery) error {
+	if !s.Protected || s.AssumeYes {
+		return nil
+	}
+
+	destructive := make([]string, 0)
+	var domain string
+	for i, query := range queries {
+		if query.err == nil && isDestructiveAction(query.action) {
+			destructive = append(destructive, fmt.Sprintf("#%d %s %s", i+1, query.action, query.domain))
+			domain = query.domain
+		}
+	}
+	if len(destructive) == 0 {
+		return nil
+	}
+
+	console.Printlnf("%sProtected environment %q, the file contains %d destructive queries:%s", s.colorProtected, s.protectedTarget(), len(destructive), s.colorEnd)
+	for _, line := range destructive {
+		console.Printlnf("  %s", line)
+	}
+
+	if len(destructive) == 1 && len(domain) > 0 {
+		return s.confirmProtected("domain name", domain)
+	}
+	return s.confirmProtected("environment name", s.protectedTarget())
+}
+
+// confirmProtected prompts to type expected and returns an error on mismatch.
+func (s *Service) confirmProtected(label, expected string) error {
+	console.Printf("%sType the %s %q to confirm> %s", s.colorProtected, label, expected, s.colorEnd)
+	answer, err := console.ReadLine()
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(answer), expected) {
+		return fmt.Errorf("aborted, %s does not match", label)
+	}
+	return nil
+}
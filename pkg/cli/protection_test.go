@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// consoleMock answers ReadLine with predefined lines and records all console output.
+type consoleMock struct {
+	console.Input
+	console.Output
+	lines  []string
+	output strings.Builder
+}
+
+func (m *consoleMock) ReadLine() (string, error) {
+	if len(m.lines) == 0 {
+		return "", io.EOF
+	}
+	line := m.lines[0]
+	m.lines = m.lines[1:]
+	return line, nil
+}
+
+func (m *consoleMock) Print(str string) (int, error) {
+	return m.output.WriteString(str)
+}
+
+// withConsoleMock runs f while console input is read from lines and asserts that all lines have been consumed.
+func withConsoleMock(t *testing.T, lines []string, f func(m *consoleMock)) {
+	oldInput, oldOutput := console.DefaultInput, console.DefaultOutput
+	defer func() {
+		console.DefaultInput, console.DefaultOutput = oldInput, oldOutput
+	}()
+
+	m := &consoleMock{Input: oldInput, Output: oldOutput, lines: lines}
+	console.DefaultInput, console.DefaultOutput = m, m
+	f(m)
+	assert.Empty(t, m.lines, "not all input lines have been read")
+}
+
+func newProtectedService() *Service {
+	s := New(nil, nil, nil)
+	s.EnvName = "prod"
+	s.Protected = true
+	return s
+}
+
+func TestIsDestructiveAction(t *testing.T) {
+	for _, action := range []rri.QueryAction{rri.ActionDelete, "delete", rri.ActionTransit, rri.ActionChangeHolder, "chprov"} {
+		assert.True(t, isDestructiveAction(action), action)
+	}
+	for _, action := range []rri.QueryAction{rri.ActionInfo, rri.ActionCreate, rri.ActionUpdate, rri.ActionRestore, rri.ActionCreateAuthInfo1, ""} {
+		assert.False(t, isDestructiveAction(action), action)
+	}
+}
+
+func TestConfirmQuery(t *testing.T) {
+	s := newProtectedService()
+	withConsoleMock(t, nil, func(m *consoleMock) {
+		assert.NoError(t, s.confirmQuery(rri.NewInfoDomainQuery("denic.de")))
+		assert.Empty(t, m.output.String())
+	})
+
+	withConsoleMock(t, []string{" DENIC.de "}, func(m *consoleMock) {
+		assert.NoError(t, s.confirmQuery(rri.NewDeleteDomainQuery("denic.de")))
+		assert.Contains(t, m.output.String(), `Type the domain name "denic.de" to confirm`)
+	})
+
+	withConsoleMock(t, []string{"denic.com"}, func(m *consoleMock) {
+		assert.EqualError(t, s.confirmQuery(rri.NewDeleteDomainQuery("denic.de")), "aborted, domain name does not match")
+	})
+
+	// the secret of CHPROV is not shown in the summary
+	withConsoleMock(t, []string{"denic.de"}, func(m *consoleMock) {
+		assert.NoError(t, s.confirmQuery(rri.NewChangeProviderQuery("denic.de", "top-secret", rri.DomainData{})))
+		assert.Contains(t, m.output.String(), "authinfo: ******")
+		assert.NotContains(t, m.output.String(), "top-secret")
+	})
+
+	withConsoleMock(t, nil, func(m *consoleMock) {
+		s.AssumeYes = true
+		assert.NoError(t, s.confirmQuery(rri.NewDeleteDomainQuery("denic.de")))
+		s.AssumeYes = false
+		s.Protected = false
+		assert.NoError(t, s.confirmQuery(rri.NewDeleteDomainQuery("denic.de")))
+	})
+}
+
+func TestConfirmRawQuery(t *testing.T) {
+	s := newProtectedService()
+	withConsoleMock(t, nil, func(m *consoleMock) {
+		assert.NoError(t, s.confirmRawQuery("version: 5.0\naction: info\ndomain: denic.de\n"))
+	})
+
+	data, err := os.ReadFile("../../examples/xml/domain/domain_chprov.xml")
+	require.NoError(t, err)
+	withConsoleMock(t, []string{"de-example.de"}, func(m *consoleMock) {
+		assert.NoError(t, s.confirmRawQuery(string(data)))
+		assert.NotContains(t, m.output.String(), "secret")
+	})
+
+	// queries that cannot be parsed might be destructive
+	withConsoleMock(t, []string{"prod"}, func(m *consoleMock) {
+		assert.NoError(t, s.confirmRawQuery("<?xml version=\"1.0\"?><registry-request><domain:delete>"))
+		assert.Contains(t, m.output.String(), `Protected environment "prod", the query cannot be checked`)
+	})
+	withConsoleMock(t, []string{""}, func(m *consoleMock) {
+		assert.EqualError(t, s.confirmRawQuery("<?xml version=\"1.0\"?><registry-request><domain:delete>"), "aborted, environment name does not match")
+	})
+}
+
+func TestConfirmBatch(t *testing.T) {
+	s := newProtectedService()
+	withConsoleMock(t, nil, func(m *consoleMock) {
+		assert.NoError(t, s.confirmBatch([]batchQuery{
+			{action: rri.ActionInfo, domain: "denic.de"},
+			{action: rri.ActionDelete, domain: "denic.de", err: io.ErrUnexpectedEOF},
+		}))
+	})
+
+	// a single destructive query is confirmed with its domain name
+	withConsoleMock(t, []string{"denic.de"}, func(m *consoleMock) {
+		assert.NoError(t, s.confirmBatch([]batchQuery{
+			{action: rri.ActionInfo, domain: "denic.com"},
+			{action: rri.ActionDelete, domain: "denic.de"},
+		}))
+		assert.Contains(t, m.output.String(), "#2 DELETE denic.de")
+	})
+
+	withConsoleMock(t, []string{"prod"}, func(m *consoleMock) {
+		assert.NoError(t, s.confirmBatch([]batchQuery{
+			{action: rri.ActionDelete, domain: "denic.de"},
+			{action: rri.ActionTransit, domain: "denic.com"},
+		}))
+		assert.Contains(t, m.output.String(), "the file contains 2 destructive queries")
+	})
+
+	withConsoleMock(t, []string{"denic.de"}, func(m *consoleMock) {
+		assert.EqualError(t, s.confirmBatch([]batchQuery{
+			{action: rri.ActionDelete, domain: "denic.de"},
+			{action: rri.ActionTransit, domain: "denic.com"},
+		}), "aborted, environment name does not match")
+	})
+}
+
+func TestConfirmBulk(t *testing.T) {
+	s := newProtectedService()
+	withConsoleMock(t, nil, func(m *consoleMock) {
+		assert.NoError(t, s.confirmBulk(rri.BulkActionUpdate, 10))
+		assert.NoError(t, s.confirmBulk(rri.BulkActionDelete, 0))
+	})
+
+	withConsoleMock(t, []string{"prod"}, func(m *consoleMock) {
+		assert.NoError(t, s.confirmBulk(rri.BulkActionDelete, 10))
+		assert.Contains(t, m.output.String(), "bulk delete will send 10 destructive queries")
+	})
+
+	withConsoleMock(t, []string{"denic.de"}, func(m *consoleMock) {
+		assert.EqualError(t, s.confirmBulk(rri.BulkActionChangeHolder, 1), "aborted, environment name does not match")
+	})
+}
+
+func TestConfirmTransfer(t *testing.T) {
+	info, err := rri.ParseResponse("RESULT: success\nDomain: denic.de\n")
+	require.NoError(t, err)
+
+	s := newProtectedService()
+	confirm := s.confirmTransfer("denic.de", "top-secret", rri.DomainData{})
+	withConsoleMock(t, []string{"denic.de"}, func(m *consoleMock) {
+		assert.True(t, confirm(info))
+		assert.NotContains(t, m.output.String(), "top-secret")
+	})
+	withConsoleMock(t, []string{"y"}, func(m *consoleMock) {
+		assert.False(t, confirm(info))
+	})
+
+	s.AssumeYes = true
+	withConsoleMock(t, nil, func(m *consoleMock) {
+		assert.True(t, confirm(info))
+	})
+
+	s.Protected = false
+	withConsoleMock(t, nil, func(m *consoleMock) {
+		assert.True(t, confirm(info))
+	})
+	s.AssumeYes = false
+	withConsoleMock(t, []string{"yes"}, func(m *consoleMock) {
+		assert.True(t, confirm(info))
+	})
+	withConsoleMock(t, []string{""}, func(m *consoleMock) {
+		assert.False(t, confirm(info))
+	})
+}
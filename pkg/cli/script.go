@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/commandline"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/DENICeG/go-rriclient/pkg/script"
+)
+
+// scriptExecutor executes the commands of a script in the command line environment of the service.
+type scriptExecutor struct {
+	s   *Service
+	cli *commandline.Environment
+}
+
+func (e scriptExecutor) ExecCommand(cmd []string) (*rri.Response, error) {
+	e.s.lastResponse = nil
+	err := e.cli.ExecCommand(cmd[0], cmd[1:])
+	return e.s.lastResponse, err
+}
+
+func (e scriptExecutor) SendQuery(query string) (*rri.Response, error) {
+	if err := e.s.precheckRawQuery(query); err != nil {
+		return nil, err
+	}
+	if err := e.s.confirmRawQuery(query); err != nil {
+		return nil, err
+	}
+
+	raw, err := e.s.rriClient.SendRaw(query)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := e.s.printRawResponse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.s.ReturnErrorOnFail && !res.IsSuccessful() {
+		return nil, fmt.Errorf("RRI returned result 'failed'")
+	}
+
+	return res, nil
+}
+
+func (e scriptExecutor) ResolveVariable(name string) (string, bool, error) {
+	return e.s.resolveVariable(name)
+}
+
+// cmdRun executes a script file with the commands of cli.
+func (s *Service) cmdRun(cli *commandline.Environment, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("missing script file")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	sc, err := script.Parse(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	stats, err := sc.Run(scriptExecutor{s, cli}, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	console.Printlnf("%sscript finished: %d commands, %d assertions passed%s", s.colorSuccessResponse, stats.Commands, stats.Assertions, s.colorEnd)
+	return nil
+}
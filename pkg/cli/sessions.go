@@ -0,0 +1,306 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/commandline"
+	"github.com/DENICeG/go-rriclient/internal/env"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
+
+// ConnectFunc opens a new RRI connection for an environment.
+type ConnectFunc func(envi env.Environment) (*rri.Client, error)
+
+// session is a named connection of the interactive command line.
+type session struct {
+	name       string
+	envName    string
+	address    string
+	protected  bool
+	output     OutputMode
+	colorTheme string
+	client     *rri.Client
+}
+
+// historyName returns the name the command history of the session is stored with.
+func (ses *session) historyName() string {
+	if len(ses.envName) > 0 {
+		return ses.envName
+	}
+	return ses.address
+}
+
+// currentSession returns the active session and registers the client passed to New as first session.
+func (s *Service) currentSession() *session {
+	if len(s.sessions) == 0 {
+		ses := &session{envName: s.EnvName, protected: s.Protected, output: s.Output, colorTheme: s.colorTheme, client: s.rriClient}
+		if s.rriClient != nil {
+			ses.address = s.rriClient.RemoteAddress()
+		}
+		ses.name = ses.historyName()
+		s.sessions = append(s.sessions, ses)
+		s.session = ses
+	}
+	return s.session
+}
+
+func (s *Service) findSession(name string) *session {
+	s.currentSession()
+	for _, ses := range s.sessions {
+		if ses.name == name {
+			return ses
+		}
+	}
+	return nil
+}
+
+// Close closes the connections of all sessions and the transcript.
+func (s *Service) Close() error {
+	firstErr := s.StopTranscript()
+	if s.rriClient == nil {
+		return firstErr
+	}
+
+	s.currentSession()
+	for _, ses := range s.sessions {
+		if err := ses.client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.sessions = nil
+	s.session = nil
+	return firstErr
+}
+
+// openSession connects to the environment envName or to host if envName is empty. The environment selection is shown if both are empty.
+func (s *Service) openSession(envName, host, user string) (*session, error) {
+	if s.Connect == nil || s.EnvReader == nil {
+		return nil, fmt.Errorf("switching connections is not supported")
+	}
+
+	var password string
+	var cmd []string
+	envi, credentials, err := RetrieveEnvironment(s.EnvReader, &host, &envName, &user, &password, &cmd, CredentialOptions{PasswordFD: -1})
+	if err != nil {
+		return nil, err
+	}
+	if len(envi.Address) == 0 {
+		return nil, fmt.Errorf("missing RRI server address")
+	}
+
+	// an output mode passed on the command line applies to all sessions
+	output := s.ForcedOutput
+	if len(output) == 0 && len(envi.Output) > 0 {
+		if output, err = ParseOutputMode(envi.Output); err != nil {
+			return nil, err
+		}
+	}
+	if len(output) == 0 {
+		output = OutputKV
+	}
+
+	ses := &session{envName: envi.Name, address: envi.Address, protected: envi.Protected, output: output, colorTheme: envi.ColorTheme}
+	ses.name = ses.historyName()
+	if len(envi.Name) == 0 && len(envi.User) > 0 {
+		ses.name = envi.User + "@" + envi.Address
+	}
+	if s.findSession(ses.name) != nil {
+		return nil, fmt.Errorf("session %q is already open, use 'session use %s' to switch to it", ses.name, ses.name)
+	}
+
+	ses.client, err = s.Connect(envi)
+	if err != nil {
+		return nil, err
+	}
+
+	s.attachClient(ses.client)
+
+	if credentials != nil {
+		if err := ses.client.LoginWith(credentials); err != nil {
+			ses.client.Close() //nolint
+			return nil, err
+		}
+	}
+
+	return ses, nil
+}
+
+// useSession makes ses the active session and applies its output mode, color theme and command history.
+func (s *Service) useSession(ses *session) {
+	if err := s.saveHistory(); err != nil {
+		s.ErrorPrinter(fmt.Errorf("failed to save history: %w", err))
+	}
+
+	// keep changes made with the output command for the next switch back
+	if s.session != nil {
+		s.session.output = s.Output
+		s.session.colorTheme = s.colorTheme
+	}
+
+	s.session = ses
+	s.rriClient = ses.client
+	s.EnvName = ses.envName
+	s.Protected = ses.protected
+	s.Output = ses.output
+	if err := s.SetColorTheme(ses.colorTheme); err != nil {
+		s.ErrorPrinter(err)
+	}
+	s.lastResponse = nil
+
+	if s.EnvReader != nil {
+		s.commandHistory = &commandHistory{}
+		if err := s.LoadHistory(s.EnvReader.Dir(), ses.historyName()); err != nil {
+			s.ErrorPrinter(fmt.Errorf("failed to load history: %w", err))
+		}
+	}
+}
+
+// replaceSession opens a new session and closes the current one on success.
+func (s *Service) replaceSession(envName, host, user string) error {
+	current := s.currentSession()
+	ses, err := s.openSession(envName, host, user)
+	if err != nil {
+		return err
+	}
+
+	index := slices.Index(s.sessions, current)
+	s.sessions[index] = ses
+	s.useSession(ses)
+	if err := current.client.Close(); err != nil {
+		s.ErrorPrinter(fmt.Errorf("failed to close connection: %w", err))
+	}
+
+	console.Printlnf("connected to %s", ses.address)
+	return nil
+}
+
+func (s *Service) cmdEnv(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("too many arguments")
+	}
+
+	var envName string
+	if len(args) > 0 {
+		envName = args[0]
+	}
+	return s.replaceSession(envName, "", "")
+}
+
+func (s *Service) cmdConnect(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("missing RRI host")
+	}
+	if len(args) > 2 {
+		return fmt.Errorf("too many arguments")
+	}
+
+	var user string
+	if len(args) > 1 {
+		user = args[1]
+	}
+	return s.replaceSession("", args[0], user)
+}
+
+func (s *Service) cmdSession(args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list":
+		current := s.currentSession()
+		for _, ses := range s.sessions {
+			marker := " "
+			if ses == current {
+				marker = "*"
+			}
+			user := ""
+			if ses.client.IsLoggedIn() {
+				user = ses.client.CurrentUser() + "@"
+			}
+			console.Printlnf("%s %s (%s%s)", marker, ses.name, user, ses.address)
+		}
+		return nil
+
+	case "use":
+		if len(args) < 2 {
+			return fmt.Errorf("missing session name")
+		}
+		ses := s.findSession(args[1])
+		if ses == nil {
+			return fmt.Errorf("session %q does not exist", args[1])
+		}
+		s.useSession(ses)
+		return nil
+
+	case "open":
+		var envName string
+		if len(args) > 1 {
+			envName = args[1]
+		}
+		ses, err := s.openSession(envName, "", "")
+		if err != nil {
+			return err
+		}
+		s.sessions = append(s.sessions, ses)
+		s.useSession(ses)
+		console.Printlnf("connected to %s", ses.address)
+		return nil
+
+	case "close":
+		ses := s.currentSession()
+		if len(args) > 1 {
+			if ses = s.findSession(args[1]); ses == nil {
+				return fmt.Errorf("session %q does not exist", args[1])
+			}
+		}
+		if len(s.sessions) == 1 {
+			return fmt.Errorf("cannot close the last session, use 'exit' instead")
+		}
+
+		index := slices.Index(s.sessions, ses)
+		s.sessions = slices.Delete(s.sessions, index, index+1)
+		if ses == s.session {
+			s.useSession(s.sessions[max(0, index-1)])
+		}
+		return ses.client.Close()
+
+	default:
+		return fmt.Errorf("unknown session command %q", args[0])
+	}
+}
+
+// envNameCompletion completes the names of all environments.
+func (s *Service) envNameCompletion() commandline.ArgCompletion {
+	return argCompletionFunc(func([]string, int) []commandline.CompletionOption {
+		if s.EnvReader == nil {
+			return nil
+		}
+		names, err := s.EnvReader.ListEnvironments()
+		if err != nil {
+			return nil
+		}
+		return commandline.PrepareCompletionOptions(names, false)
+	})
+}
+
+func (s *Service) sessionCompletion(currentCommand []string, entryIndex int) []commandline.CompletionOption {
+	switch {
+	case entryIndex == 1:
+		return commandline.PrepareCompletionOptions([]string{"list", "use", "open", "close"}, false)
+
+	case entryIndex == 2 && currentCommand[1] == "open":
+		return s.envNameCompletion().GetCompletionOptions(currentCommand, entryIndex)
+
+	case entryIndex == 2 && (currentCommand[1] == "use" || currentCommand[1] == "close"):
+		s.currentSession()
+		names := make([]string, 0, len(s.sessions))
+		for _, ses := range s.sessions {
+			names = append(names, ses.name)
+		}
+		return commandline.PrepareCompletionOptions(names, false)
+	}
+	return nil
+}
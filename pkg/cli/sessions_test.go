@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/internal/env"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestEnvironment(t *testing.T, reader *env.Reader, envName string, envi env.Environment) {
+	reader.EnterEnvHandler = func(e any) error {
+		*e.(*env.Environment) = envi
+		return nil
+	}
+	defer func() { reader.EnterEnvHandler = nil }()
+
+	var created env.Environment
+	require.NoError(t, reader.CreateOrReadEnvironment(envName, &created))
+}
+
+// withSessionService runs f with a service connected to the environment test and the environments staging and prod on a mock server.
+func withSessionService(t *testing.T, f func(s *Service, address string)) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		reader := env.NewReaderForDir(t.TempDir())
+		createTestEnvironment(t, reader, "test", env.Environment{Address: server.Address()})
+		createTestEnvironment(t, reader, "staging", env.Environment{Address: server.Address(), Output: "yaml"})
+		createTestEnvironment(t, reader, "prod", env.Environment{Address: server.Address(), Output: "json", ColorTheme: ColorThemeNone, Protected: true})
+
+		client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true})
+		require.NoError(t, err)
+
+		s := New(client, nil, nil)
+		defer s.Close()
+		s.EnvName = "test"
+		s.EnvReader = reader
+		s.Connect = func(envi env.Environment) (*rri.Client, error) {
+			return rri.NewClient(envi.Address, &rri.ClientConfig{Insecure: true})
+		}
+		require.NoError(t, s.LoadHistory(reader.Dir(), "test"))
+
+		f(s, server.Address())
+	})
+}
+
+func sessionNames(s *Service) []string {
+	names := make([]string, 0, len(s.sessions))
+	for _, ses := range s.sessions {
+		names = append(names, ses.name)
+	}
+	return names
+}
+
+func TestSessionOpenAndUse(t *testing.T) {
+	withSessionService(t, func(s *Service, address string) {
+		require.NoError(t, s.cmdSession([]string{"open", "prod"}))
+		assert.Equal(t, []string{"test", "prod"}, sessionNames(s))
+		assert.Equal(t, "prod", s.EnvName)
+		assert.True(t, s.Protected)
+		assert.Equal(t, OutputJSON, s.Output)
+		assert.Equal(t, ColorThemeNone, s.colorTheme)
+
+		assert.EqualError(t, s.cmdSession([]string{"open", "prod"}), `session "prod" is already open, use 'session use prod' to switch to it`)
+		assert.EqualError(t, s.cmdSession([]string{"use", "staging"}), `session "staging" does not exist`)
+
+		// the output mode changed during a session is restored when switching back
+		require.NoError(t, s.cmdOutput([]string{"table"}))
+		require.NoError(t, s.cmdSession([]string{"use", "test"}))
+		assert.Equal(t, "test", s.EnvName)
+		assert.False(t, s.Protected)
+		assert.Equal(t, OutputKV, s.Output)
+		assert.NotEqual(t, ColorThemeNone, s.colorTheme)
+
+		require.NoError(t, s.cmdSession([]string{"use", "prod"}))
+		assert.Equal(t, OutputTable, s.Output)
+		assert.Equal(t, ColorThemeNone, s.colorTheme)
+
+		// an output mode passed on the command line takes precedence over the environment
+		s.ForcedOutput = OutputKV
+		require.NoError(t, s.cmdSession([]string{"open", "staging"}))
+		assert.Equal(t, OutputKV, s.Output)
+	})
+}
+
+func TestSessionReplace(t *testing.T) {
+	withSessionService(t, func(s *Service, address string) {
+		require.NoError(t, s.cmdSession([]string{"open", "staging"}))
+		require.NoError(t, s.cmdSession([]string{"use", "test"}))
+
+		require.NoError(t, s.cmdEnv([]string{"prod"}))
+		assert.Equal(t, []string{"prod", "staging"}, sessionNames(s))
+		assert.Equal(t, s.sessions[0], s.session)
+		assert.Equal(t, OutputJSON, s.Output)
+
+		require.NoError(t, s.cmdConnect([]string{address}))
+		assert.Equal(t, []string{address, "staging"}, sessionNames(s))
+		assert.Empty(t, s.EnvName)
+		assert.False(t, s.Protected)
+
+		assert.EqualError(t, s.cmdEnv([]string{"unknown"}), `environment "unknown" not found`)
+		assert.Equal(t, []string{address, "staging"}, sessionNames(s))
+	})
+}
+
+func TestSessionClose(t *testing.T) {
+	withSessionService(t, func(s *Service, address string) {
+		require.NoError(t, s.cmdSession([]string{"open", "staging"}))
+		require.NoError(t, s.cmdSession([]string{"open", "prod"}))
+
+		assert.EqualError(t, s.cmdSession([]string{"close", "unknown"}), `session "unknown" does not exist`)
+
+		// closing another session keeps the current one
+		require.NoError(t, s.cmdSession([]string{"close", "test"}))
+		assert.Equal(t, []string{"staging", "prod"}, sessionNames(s))
+		assert.Equal(t, "prod", s.EnvName)
+
+		// closing the current session switches to the previous one
+		require.NoError(t, s.cmdSession([]string{"close"}))
+		assert.Equal(t, []string{"staging"}, sessionNames(s))
+		assert.Equal(t, "staging", s.EnvName)
+		assert.Equal(t, OutputYAML, s.Output)
+
+		assert.EqualError(t, s.cmdSession([]string{"close"}), "cannot close the last session, use 'exit' instead")
+	})
+}
+
+func TestSessionHistory(t *testing.T) {
+	withSessionService(t, func(s *Service, address string) {
+		s.commandHistory.Put([]string{"info", "denic.de"})
+
+		require.NoError(t, s.cmdSession([]string{"open", "prod"}))
+		assert.Empty(t, s.commandHistory.list)
+		s.commandHistory.Put([]string{"check", "denic.de"})
+
+		require.NoError(t, s.cmdSession([]string{"use", "test"}))
+		assert.Equal(t, [][]string{{"info", "denic.de"}}, s.commandHistory.list)
+
+		require.NoError(t, s.cmdSession([]string{"use", "prod"}))
+		assert.Equal(t, [][]string{{"check", "denic.de"}}, s.commandHistory.list)
+	})
+}
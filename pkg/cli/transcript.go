@@ -0,0 +1,292 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-console/v2/commandline"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
+
+// TranscriptFormat denotes how a session transcript is written.
+type TranscriptFormat string
+
+const (
+	// TranscriptMarkdown writes a readable document with all commands, queries and responses, e.g. to attach to support tickets.
+	TranscriptMarkdown TranscriptFormat = "markdown"
+	// TranscriptJSONL writes one JSON document per command.
+	TranscriptJSONL TranscriptFormat = "jsonl"
+	// TranscriptQueries writes all sent queries separated by =-= lines, so the file can be processed again with --file. Censored auth info secrets are written as ${authinfo} placeholder.
+	TranscriptQueries TranscriptFormat = "queries"
+)
+
+// censoredAuthInfo matches auth info secrets that have been censored by rri.CensorRawMessage.
+var censoredAuthInfo = regexp.MustCompile(`(?mi)^(authinfo:[ \t]+)\*{6}[ \t]*$`)
+
+// TranscriptFormats returns all supported transcript formats.
+func TranscriptFormats() []TranscriptFormat {
+	return []TranscriptFormat{TranscriptMarkdown, TranscriptJSONL, TranscriptQueries}
+}
+
+// ParseTranscriptFormat parses a transcript format from string. The format is derived from the extension of file if str is empty.
+func ParseTranscriptFormat(str, file string) (TranscriptFormat, error) {
+	if len(str) == 0 {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".md", ".markdown":
+			return TranscriptMarkdown, nil
+		case ".jsonl", ".json":
+			return TranscriptJSONL, nil
+		default:
+			return TranscriptQueries, nil
+		}
+	}
+
+	for _, format := range TranscriptFormats() {
+		if strings.EqualFold(str, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("invalid transcript format %q", str)
+}
+
+type transcriptExchange struct {
+	Time     time.Time `json:"time"`
+	Query    string    `json:"query"`
+	Response string    `json:"response"`
+	STID     string    `json:"stid,omitempty"`
+}
+
+// transcriptEntry contains a command and all queries sent for it. Queries sent outside of commands, e.g. for query files passed with --file, are written as entries without command.
+type transcriptEntry struct {
+	Time    time.Time            `json:"time"`
+	Command string               `json:"command,omitempty"`
+	Queries []transcriptExchange `json:"queries,omitempty"`
+	Error   string               `json:"error,omitempty"`
+}
+
+// transcript records all commands and queries of a session to a file.
+type transcript struct {
+	mutex  sync.Mutex
+	file   *os.File
+	name   string
+	format TranscriptFormat
+	entry  *transcriptEntry
+}
+
+func openTranscript(file string, format TranscriptFormat) (*transcript, error) {
+	// responses may contain personal data of domain holders
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &transcript{file: f, name: file, format: format}, nil
+}
+
+func (t *transcript) beginCommand(cmd []string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.entry = &transcriptEntry{Time: time.Now(), Command: commandline.GetCommandString(censorCommand(cmd))}
+}
+
+func (t *transcript) endCommand(err error) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.entry == nil {
+		return nil
+	}
+
+	entry := t.entry
+	t.entry = nil
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return t.write(entry)
+}
+
+// record adds a query and its response to the current command.
+func (t *transcript) record(query, response string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	exchange := transcriptExchange{Time: time.Now(), Query: strings.TrimSpace(rri.CensorRawMessage(query)), Response: strings.TrimSpace(response)}
+	if res, err := rri.ParseResponse(response); err == nil {
+		exchange.STID = res.STID()
+	}
+
+	if t.entry != nil {
+		t.entry.Queries = append(t.entry.Queries, exchange)
+		return
+	}
+	if err := t.write(&transcriptEntry{Time: exchange.Time, Queries: []transcriptExchange{exchange}}); err != nil {
+		console.Printlnf("ERROR: failed to write transcript: %s", err.Error())
+	}
+}
+
+func (t *transcript) write(entry *transcriptEntry) error {
+	var sb strings.Builder
+
+	switch t.format {
+	case TranscriptMarkdown:
+		command := "queries"
+		if len(entry.Command) > 0 {
+			command = "`" + entry.Command + "`"
+		}
+		fmt.Fprintf(&sb, "### %s %s\n\n", entry.Time.Format(time.RFC3339), command)
+		for _, exchange := range entry.Queries {
+			fmt.Fprintf(&sb, "Query sent at %s:\n\n```\n%s\n```\n\n", exchange.Time.Format(time.RFC3339), exchange.Query)
+			if len(exchange.STID) > 0 {
+				fmt.Fprintf(&sb, "Response with STID %s:\n\n", exchange.STID)
+			} else {
+				sb.WriteString("Response:\n\n")
+			}
+			fmt.Fprintf(&sb, "```\n%s\n```\n\n", exchange.Response)
+		}
+		if len(entry.Error) > 0 {
+			fmt.Fprintf(&sb, "Error: %s\n\n", entry.Error)
+		}
+
+	case TranscriptJSONL:
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		sb.Write(data)
+		sb.WriteString("\n")
+
+	case TranscriptQueries:
+		for _, exchange := range entry.Queries {
+			query, err := rri.ParseQuery(exchange.Query)
+			if err != nil {
+				continue
+			}
+			// logins are performed by the client and the censored password cannot be replayed
+			if action := query.Action().Normalize(); action == rri.ActionLogin || action == rri.ActionLogout {
+				continue
+			}
+			// query files are expanded on replay, so literal $ are escaped and the secret is prompted for
+			kv := strings.ReplaceAll(strings.TrimSpace(query.EncodeKV()), "$", "$$")
+			sb.WriteString(censoredAuthInfo.ReplaceAllString(kv, "${1}$${authinfo}"))
+			sb.WriteString("\n=-=\n")
+		}
+	}
+
+	_, err := t.file.WriteString(sb.String())
+	return err
+}
+
+func (t *transcript) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.entry != nil && len(t.entry.Queries) > 0 {
+		if err := t.write(t.entry); err != nil {
+			t.file.Close() //nolint
+			return err
+		}
+	}
+	t.entry = nil
+	return t.file.Close()
+}
+
+// StartTranscript records all following commands, queries and responses to file. The format is derived from the file extension if empty.
+func (s *Service) StartTranscript(file string, format string) error {
+	if s.transcript != nil {
+		return fmt.Errorf("already recording to %s", s.transcript.name)
+	}
+
+	transcriptFormat, err := ParseTranscriptFormat(format, file)
+	if err != nil {
+		return err
+	}
+
+	s.transcript, err = openTranscript(file, transcriptFormat)
+	return err
+}
+
+// StopTranscript stops recording and closes the transcript file.
+func (s *Service) StopTranscript() error {
+	if s.transcript == nil {
+		return nil
+	}
+
+	t := s.transcript
+	s.transcript = nil
+	return t.Close()
+}
+
+// execCommand executes cmd and records it to the transcript.
+func (s *Service) execCommand(cli *commandline.Environment, cmd []string) error {
+	t := s.transcript
+	if t == nil {
+		return cli.ExecCommand(cmd[0], cmd[1:])
+	}
+
+	t.beginCommand(cmd)
+	err := cli.ExecCommand(cmd[0], cmd[1:])
+	if err := t.endCommand(err); err != nil {
+		s.ErrorPrinter(fmt.Errorf("failed to write transcript: %w", err))
+	}
+	return err
+}
+
+func (s *Service) cmdRecord(args []string) error {
+	if len(args) == 0 {
+		if s.transcript == nil {
+			console.Println("not recording")
+		} else {
+			console.Printlnf("recording to %s as %s", s.transcript.name, s.transcript.format)
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "start":
+		if len(args) < 2 {
+			return fmt.Errorf("missing transcript file")
+		}
+		var format string
+		if len(args) > 2 {
+			format = args[2]
+		}
+		if err := s.StartTranscript(args[1], format); err != nil {
+			return err
+		}
+		console.Printlnf("recording to %s as %s", s.transcript.name, s.transcript.format)
+		return nil
+
+	case "stop":
+		if s.transcript == nil {
+			return fmt.Errorf("not recording")
+		}
+		name := s.transcript.name
+		if err := s.StopTranscript(); err != nil {
+			return err
+		}
+		console.Printlnf("transcript has been written to %s", name)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown record command %q", args[0])
+	}
+}
+
+func (s *Service) recordCompletion(currentCommand []string, entryIndex int) []commandline.CompletionOption {
+	switch {
+	case entryIndex == 1:
+		return commandline.PrepareCompletionOptions([]string{"start", "stop"}, false)
+	case entryIndex == 2 && currentCommand[1] == "start":
+		return commandline.NewLocalFileSystemArgCompletion(true).GetCompletionOptions(currentCommand, entryIndex)
+	case entryIndex == 3 && currentCommand[1] == "start":
+		return newEnumArgCompletion(TranscriptFormats()).GetCompletionOptions(currentCommand, entryIndex)
+	}
+	return nil
+}
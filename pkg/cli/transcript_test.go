@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTranscriptEntries(t *testing.T, file string) []transcriptEntry {
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+
+	entries := make([]transcriptEntry, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry transcriptEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	require.NoError(t, scanner.Err())
+	return entries
+}
+
+// echoDomainHandler answers all queries with the domain of the query.
+func echoDomainHandler(user string, session *rri.Session, query *rri.Query) (*rri.Response, error) {
+	fields := rri.NewResponseFieldList()
+	fields.Add(rri.ResponseFieldNameDomain, query.FirstField(rri.QueryFieldNameDomainIDN))
+	return rri.NewResponse(rri.ResultSuccess, fields), nil
+}
+
+func TestTranscriptCensorsXMLLogin(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "transcript.jsonl")
+	s := New(nil, nil, nil)
+	require.NoError(t, s.StartTranscript(file, ""))
+
+	data, err := os.ReadFile("../../examples/xml/auth/auth_login.xml")
+	require.NoError(t, err)
+	s.rawExchangeHandler(string(data), "RESULT: success\n")
+	require.NoError(t, s.StopTranscript())
+
+	entries := readTranscriptEntries(t, file)
+	require.Len(t, entries, 1)
+	require.Len(t, entries[0].Queries, 1)
+	assert.Contains(t, entries[0].Queries[0].Query, "<password>******</password>")
+	assert.NotContains(t, entries[0].Queries[0].Query, "<password>1234</password>")
+}
+
+func TestTranscriptParallelSessions(t *testing.T) {
+	withMockService(t, echoDomainHandler, func(s *Service) {
+		file := filepath.Join(t.TempDir(), "transcript.jsonl")
+		require.NoError(t, s.StartTranscript(file, ""))
+
+		// sessions opened for bulk commands share the handlers of the client
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			session, err := s.rriClient.NewSession()
+			require.NoError(t, err)
+			defer session.Close()
+
+			wg.Add(1)
+			go func(prefix string) {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					_, err := session.SendQuery(rri.NewInfoDomainQuery(prefix + strings.Repeat("x", j+1) + ".de"))
+					assert.NoError(t, err)
+				}
+			}(string(rune('a' + i)))
+		}
+
+		s.transcript.beginCommand([]string{"bulk", "info", "domains.csv"})
+		wg.Wait()
+		require.NoError(t, s.transcript.endCommand(nil))
+		require.NoError(t, s.StopTranscript())
+
+		exchanges := 0
+		for _, entry := range readTranscriptEntries(t, file) {
+			for _, exchange := range entry.Queries {
+				query, err := rri.ParseQuery(exchange.Query)
+				require.NoError(t, err)
+				if query.Action() == rri.ActionLogin {
+					assert.Equal(t, "******", query.FirstField(rri.QueryFieldNamePassword))
+					continue
+				}
+				response, err := rri.ParseResponse(exchange.Response)
+				require.NoError(t, err)
+				assert.Equal(t, query.FirstField(rri.QueryFieldNameDomainIDN), response.FirstField(rri.ResponseFieldNameDomain))
+				exchanges++
+			}
+		}
+		assert.Equal(t, 40, exchanges)
+	})
+}
+
+func TestTranscriptSkipsKeepAlive(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+		var keepAlives atomic.Int32
+		server.Handler = func(user string, session *rri.Session, query *rri.Query) (*rri.Response, error) {
+			if query.Action() == rri.ActionCheck {
+				keepAlives.Add(1)
+			}
+			return echoDomainHandler(user, session, query)
+		}
+
+		client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true, KeepAliveInterval: 10 * time.Millisecond})
+		require.NoError(t, err)
+		defer client.Close()
+		s := New(client, nil, nil)
+		require.NoError(t, client.Login("DENIC-1000011-TEST", "secret"))
+
+		file := filepath.Join(t.TempDir(), "transcript.jsonl")
+		require.NoError(t, s.StartTranscript(file, ""))
+		time.Sleep(100 * time.Millisecond)
+		_, err = client.SendQuery(rri.NewInfoDomainQuery("denic.de"))
+		require.NoError(t, err)
+		require.NoError(t, s.StopTranscript())
+		assert.Positive(t, keepAlives.Load())
+
+		entries := readTranscriptEntries(t, file)
+		require.Len(t, entries, 1)
+		require.Len(t, entries[0].Queries, 1)
+		assert.Contains(t, entries[0].Queries[0].Query, "action: INFO")
+	})
+}
+
+func TestTranscriptCensorsCommands(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "transcript.jsonl")
+	s := New(nil, nil, nil)
+	require.NoError(t, s.StartTranscript(file, ""))
+
+	s.transcript.beginCommand([]string{"chprov", "denic.de", "top-secret"})
+	s.rawExchangeHandler(rri.NewChangeProviderQuery("denic.de", "top-secret", rri.DomainData{}).EncodeKV(), "RESULT: success\n")
+	require.NoError(t, s.transcript.endCommand(nil))
+	s.transcript.beginCommand([]string{"raw", `version: 5.0\naction: LOGIN\nuser: DENIC-1000011-TEST\npassword: top-secret`})
+	require.NoError(t, s.transcript.endCommand(nil))
+	require.NoError(t, s.StopTranscript())
+
+	entries := readTranscriptEntries(t, file)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "chprov denic.de ******", entries[0].Command)
+	assert.Contains(t, entries[0].Queries[0].Query, "authinfo: ******")
+	assert.NotContains(t, entries[1].Command, "top-secret")
+}
+
+func TestTranscriptQueriesPlaceholders(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "transcript.txt")
+	s := New(nil, nil, nil)
+	require.NoError(t, s.StartTranscript(file, ""))
+
+	s.rawExchangeHandler("version: 5.0\naction: LOGIN\nuser: DENIC-1000011-TEST\npassword: top-secret\n", "RESULT: success\n")
+	s.rawExchangeHandler(rri.NewChangeProviderQuery("denic.de", "top-secret", rri.DomainData{}).EncodeKV(), "RESULT: success\n")
+	s.rawExchangeHandler("version: 5.0\naction: INFO\ndomain: denic.de\nremarks: $5\n", "RESULT: success\n")
+	require.NoError(t, s.StopTranscript())
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "LOGIN")
+	assert.NotContains(t, string(data), "top-secret")
+	assert.Contains(t, string(data), "authinfo: ${authinfo}\n")
+	assert.Contains(t, string(data), "$$5")
+
+	// the secret is provided when the file is replayed
+	s.SetVariables(map[string]string{"authinfo": "top-secret"})
+	expanded, err := s.expandVariables(string(data))
+	require.NoError(t, err)
+	assert.Contains(t, expanded, "authinfo: top-secret\n")
+	assert.Contains(t, expanded, "remarks: $5\n")
+}
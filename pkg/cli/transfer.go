@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
+
+const defaultTransferTimeout = 5 * time.Minute
+
+// cmdTransfer guides through a provider transfer. Missing arguments are prompted for.
+func (s *Service) cmdTransfer(args []string) error {
+	if !s.rriClient.IsLoggedIn() {
+		return fmt.Errorf("you need to log in before transferring domains")
+	}
+
+	args, dataFile, err := s.splitDataFileArg(args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 1 {
+		console.Print("Domain> ")
+		domain, err := console.ReadLine()
+		if err != nil {
+			return err
+		}
+		args = append(args, strings.TrimSpace(domain))
+	}
+
+	if len(args) < 2 {
+		console.Print("AuthInfo secret> ")
+		secret, err := console.ReadPassword()
+		if err != nil {
+			return err
+		}
+		args = append(args, secret)
+	}
+
+	domain, domainData, err := s.readDomainData(args, 2, dataFile)
+	if err != nil {
+		return err
+	}
+	s.completion.PutDomain(domain)
+	secret := args[1]
+
+	if err := s.precheckQuery(rri.NewChangeProviderQuery(domain, secret, domainData)); err != nil {
+		return err
+	}
+
+	timeout, err := readTransferTimeout()
+	if err != nil {
+		return err
+	}
+
+	var deleteOtherMessages bool
+	if timeout != 0 {
+		if deleteOtherMessages, err = readDeleteOtherMessages(); err != nil {
+			return err
+		}
+	}
+
+	opts := rri.TransferOptions{
+		Timeout:             timeout,
+		Confirm:             s.confirmTransfer(domain, secret, domainData),
+		Progress:            s.printTransferStep,
+		DeleteOtherMessages: deleteOtherMessages,
+		OtherMessage: func(msg *rri.QueueMessage) {
+			if deleteOtherMessages {
+				console.Printlnf("deleting queue message %s (%s) for %s that blocks the queue", msg.ID, msg.Type, msg.Domain)
+				return
+			}
+			console.Printlnf("queue message %s (%s) for %s blocks the queue, delete it with queue-delete from another client", msg.ID, msg.Type, msg.Domain)
+		},
+	}
+	if timeout == 0 {
+		opts.Timeout = -1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	result, err := rri.TransferDomain(ctx, s.rriClient, domain, secret, domainData, opts)
+	if result != nil && result.ChangeProvider != nil {
+		s.lastResponse = result.ChangeProvider
+		if printErr := s.printResponse(result.ChangeProvider); printErr != nil {
+			s.ErrorPrinter(printErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if result.Message != nil {
+		console.Printlnf("received queue message %s (%s):", result.Message.ID, result.Message.Type)
+		if err := s.printResponse(result.Message.Response); err != nil {
+			return err
+		}
+	}
+
+	console.Printlnf("%s%s has been transferred%s", s.colorSuccessResponse, domain, s.colorEnd)
+	return nil
+}
+
+func readTransferTimeout() (time.Duration, error) {
+	for {
+		console.Printf("Wait for queue message [%s, 0 to skip]> ", defaultTransferTimeout)
+		str, err := console.ReadLine()
+		if err != nil {
+			return 0, err
+		}
+
+		str = strings.TrimSpace(str)
+		switch str {
+		case "":
+			return defaultTransferTimeout, nil
+		case "0":
+			return 0, nil
+		}
+
+		timeout, err := time.ParseDuration(str)
+		if err == nil && timeout > 0 {
+			return timeout, nil
+		}
+		console.Println("invalid duration, use values like 90s or 10m")
+	}
+}
+
+// readDeleteOtherMessages asks whether queue messages of other domains are deleted while waiting, because they block the queue.
+func readDeleteOtherMessages() (bool, error) {
+	console.Print("Delete queue messages of other domains while waiting? [y/N]> ")
+	answer, err := console.ReadLine()
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// confirmTransfer shows the current domain data and asks before CHPROV is sent. Protected environments require the domain name instead.
+func (s *Service) confirmTransfer(domain, secret string, domainData rri.DomainData) func(info *rri.Response) bool {
+	return func(info *rri.Response) bool {
+		s.completion.harvestResponse(info)
+		console.Println("current domain data:")
+		if err := s.printResponse(info); err != nil {
+			s.ErrorPrinter(err)
+		}
+
+		if s.Protected {
+			if err := s.confirmQuery(rri.NewChangeProviderQuery(domain, secret, domainData)); err != nil {
+				s.ErrorPrinter(err)
+				return false
+			}
+			return true
+		}
+		if s.AssumeYes {
+			return true
+		}
+
+		console.Printf("Send CHPROV for %s? [y/N]: ", domain)
+		answer, err := console.ReadLine()
+		if err != nil {
+			return false
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	}
+}
+
+func (s *Service) printTransferStep(step rri.TransferStep) {
+	switch step {
+	case rri.TransferStepInfo:
+		console.Println("checking domain ...")
+	case rri.TransferStepChangeProvider:
+		console.Println("sending CHPROV ...")
+	case rri.TransferStepWait:
+		console.Println("waiting for queue message, press Ctrl+C to stop ...")
+	}
+}
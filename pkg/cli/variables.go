@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/DENICeG/go-console/v2"
+	"github.com/DENICeG/go-rriclient/pkg/parser"
+	"golang.org/x/term"
+)
+
+// SetVariables sets the values for ${NAME} placeholders in query files and presets.
+func (s *Service) SetVariables(vars map[string]string) {
+	s.variables = vars
+}
+
+// expandVariables replaces all placeholders in input. Values are looked up in the variables set via SetVariables, the built-in variables, the environment variables and are finally prompted for.
+func (s *Service) expandVariables(input string) (string, error) {
+	resolved := make(map[string]string)
+	return parser.ExpandVariables(input, func(name string) (string, bool, error) {
+		// resolve every variable only once so that e.g. ${uuid} is consistent within a file
+		if value, ok := resolved[name]; ok {
+			return value, true, nil
+		}
+
+		value, ok, err := s.resolveVariable(name)
+		if err != nil || !ok {
+			return "", ok, err
+		}
+
+		resolved[name] = value
+		return value, true, nil
+	})
+}
+
+func (s *Service) resolveVariable(name string) (string, bool, error) {
+	if value, ok := s.variables[name]; ok {
+		return value, true, nil
+	}
+
+	switch name {
+	case "regacc":
+		if s.rriClient != nil && s.rriClient.IsLoggedIn() {
+			regAccID, err := s.rriClient.CurrentRegAccID()
+			if err == nil {
+				return strconv.Itoa(regAccID), true, nil
+			}
+		}
+	case "uuid":
+		uuid, err := newUUID()
+		return uuid, err == nil, err
+	case "today":
+		return time.Now().Format("20060102"), true, nil
+	}
+
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true, nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		// no interactive terminal to prompt on
+		return "", false, nil
+	}
+
+	console.Printf("Value for ${%s}: ", name)
+	value, err := console.ReadLine()
+	if err != nil {
+		return "", false, err
+	}
+
+	return value, true, nil
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
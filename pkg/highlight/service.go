@@ -4,6 +4,7 @@ import (
 	"bytes"
 
 	"github.com/alecthomas/chroma/v2/quick"
+	"github.com/alecthomas/chroma/v2/styles"
 )
 
 const (
@@ -11,6 +12,8 @@ const (
 	YAML Format = "yaml"
 	// XML Format needed for the lexer to enable highlighting
 	XML Format = "xml"
+	// DefaultStyle is the style used if no other style is set
+	DefaultStyle = "monokai"
 )
 
 // Format Type for the lexer to enable highlighting
@@ -18,10 +21,15 @@ type Format string
 
 // Transform function to highlight an input string. Can return error
 func Transform(input string, format Format) (string, error) {
+	return TransformWithStyle(input, format, DefaultStyle)
+}
+
+// TransformWithStyle highlights an input string with the given style like monokai or github.
+func TransformWithStyle(input string, format Format, style string) (string, error) {
 	var buf []byte
 	buffer := bytes.NewBuffer(buf)
 
-	err := quick.Highlight(buffer, input, string(format), "terminal16m", "monokai")
+	err := quick.Highlight(buffer, input, string(format), "terminal16m", style)
 	if err != nil {
 		return "", err
 	}
@@ -29,3 +37,9 @@ func Transform(input string, format Format) (string, error) {
 	result := buffer.String()
 	return result, nil
 }
+
+// IsStyle returns whether a style with the given name exists.
+func IsStyle(style string) bool {
+	_, ok := styles.Registry[style]
+	return ok
+}
@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VariableResolver returns the value of the variable with the given name and whether it is defined.
+type VariableResolver func(name string) (string, bool, error)
+
+// ExpandVariables replaces all placeholders in input with the values returned by resolve.
+//
+// Placeholders have the form ${NAME} or ${NAME:-default}, where the default value is used if resolve does not know the variable. Names consist of letters, digits, _, - and . like ${response.holder}. Use $$ to write a literal $. A single $ that is not followed by { is left untouched.
+func ExpandVariables(input string, resolve VariableResolver) (string, error) {
+	var sb strings.Builder
+	missing := make([]string, 0)
+
+	err := scanVariables(input, func(literal string) {
+		sb.WriteString(literal)
+	}, func(name, defaultValue string, hasDefault bool) error {
+		value, ok, err := resolve(name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if !hasDefault {
+				missing = append(missing, name)
+				return nil
+			}
+			value = defaultValue
+		}
+		sb.WriteString(value)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined variables: %s", strings.Join(missing, ", "))
+	}
+
+	return sb.String(), nil
+}
+
+// Variables returns the names of all placeholders in input in order of their first occurrence.
+func Variables(input string) ([]string, error) {
+	names := make([]string, 0)
+	known := make(map[string]bool)
+
+	err := scanVariables(input, func(string) {}, func(name, _ string, _ bool) error {
+		if !known[name] {
+			known[name] = true
+			names = append(names, name)
+		}
+		return nil
+	})
+
+	return names, err
+}
+
+func scanVariables(input string, onLiteral func(literal string), onVariable func(name, defaultValue string, hasDefault bool) error) error {
+	for {
+		index := strings.IndexByte(input, '$')
+		if index < 0 || index == len(input)-1 {
+			onLiteral(input)
+			return nil
+		}
+
+		onLiteral(input[:index])
+		input = input[index:]
+
+		switch input[1] {
+		case '$':
+			onLiteral("$")
+			input = input[2:]
+			continue
+		case '{':
+		default:
+			onLiteral("$")
+			input = input[1:]
+			continue
+		}
+
+		end := strings.IndexByte(input, '}')
+		if end < 0 {
+			return fmt.Errorf("unterminated variable placeholder")
+		}
+
+		name, defaultValue, hasDefault := strings.Cut(input[2:end], ":-")
+		name = strings.TrimSpace(name)
+		if !IsValidVariableName(name) {
+			return fmt.Errorf("invalid variable name '%s'", name)
+		}
+
+		if err := onVariable(name, defaultValue, hasDefault); err != nil {
+			return err
+		}
+		input = input[end+1:]
+	}
+}
+
+// IsValidVariableName returns whether name can be used for ${name} placeholders. Names start with a letter or underscore followed by letters, digits, '_', '-' or '.'.
+func IsValidVariableName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i, r := range name {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
+		isDigit := r >= '0' && r <= '9'
+		if !isLetter && !(isDigit && i > 0) && !((r == '-' || r == '.') && i > 0) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandVariables(t *testing.T) {
+	vars := map[string]string{"regacc": "1000022", "domain": "denic.de", "response.holder": "DENIC-1000022-HOLDER"}
+	resolve := func(name string) (string, bool, error) {
+		value, ok := vars[name]
+		return value, ok, nil
+	}
+
+	result, err := parser.ExpandVariables("Holder: DENIC-${regacc}-PERSON\nDomain: ${domain}\nCtid: ${ctid:-cba-1}\nPassword: $$ecret$", resolve)
+	require.NoError(t, err)
+	assert.Equal(t, "Holder: DENIC-1000022-PERSON\nDomain: denic.de\nCtid: cba-1\nPassword: $ecret$", result)
+
+	result, err = parser.ExpandVariables("Holder: ${response.holder}", resolve)
+	require.NoError(t, err)
+	assert.Equal(t, "Holder: DENIC-1000022-HOLDER", result)
+
+	_, err = parser.ExpandVariables("Domain: ${unknown} ${other}", resolve)
+	assert.EqualError(t, err, "undefined variables: unknown, other")
+
+	_, err = parser.ExpandVariables("Domain: ${domain", resolve)
+	assert.Error(t, err)
+
+	_, err = parser.ExpandVariables("Domain: ${.domain}", resolve)
+	assert.Error(t, err)
+
+	_, err = parser.ExpandVariables("Domain: ${}", resolve)
+	assert.Error(t, err)
+}
+
+func TestVariables(t *testing.T) {
+	names, err := parser.Variables("${a} ${b:-x} $$ {c} ${a}")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+}
+
+func TestIsValidVariableName(t *testing.T) {
+	for _, name := range []string{"domain", "_x", "holder-1", "response.holder", "A9"} {
+		assert.True(t, parser.IsValidVariableName(name), name)
+	}
+	for _, name := range []string{"", "1x", "-x", ".x", "a b", "a$", "dömain"} {
+		assert.False(t, parser.IsValidVariableName(name), name)
+	}
+}
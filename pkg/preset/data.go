@@ -1,6 +1,11 @@
 package preset
 
-import "strings"
+import (
+	"io/fs"
+	"strings"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
 
 // Data holds a map of all available presets.
 type Data struct {
@@ -11,7 +16,8 @@ type Data struct {
 // Get returns the entry for the given name.
 // returns nil if the entry does not exist.
 func (d *Data) Get(name string) *Entry {
-	for _, entry := range d.Preset {
+	for i := 0; i < len(d.Preset); i++ {
+		entry := d.Preset[i]
 		if strings.EqualFold(entry.FileName, name) {
 			return &entry
 		}
@@ -20,9 +26,33 @@ func (d *Data) Get(name string) *Entry {
 	return nil
 }
 
+// Add adds entry to the presets. An existing preset with the same type and file name is replaced.
+func (d *Data) Add(entry Entry) {
+	entries := make([]Entry, 0, len(d.Preset)+1)
+	for i := 0; i < len(d.Preset); i++ {
+		entries = append(entries, d.Preset[i])
+	}
+	*d = *newData(append(entries, entry))
+}
+
 // Entry represents a single preset entry.
 type Entry struct {
-	Type     string
+	Type     rri.Format
 	DirName  string
 	FileName string
+	// Source is the name of the source the preset was loaded from.
+	Source string
+	// Path is the path of the preset file in the file system of its source.
+	Path string
+	fsys fs.FS
+}
+
+// Read returns the content of the preset file.
+func (e Entry) Read() ([]byte, error) {
+	return fs.ReadFile(e.fsys, e.Path)
+}
+
+// IsBuiltin returns whether the preset is one of the embedded examples.
+func (e Entry) IsBuiltin() bool {
+	return e.Source == BuiltinSource
 }
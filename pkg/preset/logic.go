@@ -1,51 +1,168 @@
 package preset
 
 import (
-	"embed"
+	"fmt"
 	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
 )
 
-// Load loads all presets from the embedded binary
-func Load(embedFS embed.FS, dirInfo []fs.DirEntry) (*Data, error) {
-	result := &Data{
-		Preset: make(map[int]Entry),
-	}
+// BuiltinSource is the source name of the presets embedded into the binary.
+const BuiltinSource = "built-in"
 
-	counter := 0
+// Source is a file system to load presets from.
+type Source struct {
+	Name string
+	FS   fs.FS
+}
 
-	for i, info := range dirInfo {
-		directories, err := embedFS.ReadDir("examples/" + info.Name())
-		if err != nil {
-			return nil, err
+// DirSources returns a source for every existing directory in dirs.
+func DirSources(dirs ...string) []Source {
+	sources := make([]Source, 0, len(dirs))
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
 		}
-		for _, dir := range directories {
-			files, err := embedFS.ReadDir("examples/" + info.Name() + "/" + dir.Name())
+		sources = append(sources, Source{Name: dir, FS: os.DirFS(dir)})
+	}
+	return sources
+}
+
+// TypeOf returns the query format of a preset file. Files with .xml extension are XML presets, otherwise the format is detected from content.
+func TypeOf(fileName string, content []byte) rri.Format {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".xml":
+		return rri.FormatXML
+	case ".kv":
+		return rri.FormatKV
+	default:
+		return rri.DetectFormat(string(content))
+	}
+}
+
+// Load loads all presets from the given sources. Presets of later sources replace presets of earlier sources with the same type and file name.
+func Load(sources ...Source) (*Data, error) {
+	var entries []Entry
+
+	for _, source := range sources {
+		err := fs.WalkDir(source.FS, ".", func(filePath string, d fs.DirEntry, err error) error {
 			if err != nil {
-				return nil, err
+				return err
 			}
 
-			for _, file := range files {
-				presetType := "xml"
-				if i == 0 {
-					presetType = "kv"
-				}
-
-				entry := Entry{
-					Type:     presetType,
-					FileName: file.Name(),
-					DirName:  dir.Name(),
+			// skip hidden files and directories like .git
+			if filePath != "." && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return fs.SkipDir
 				}
+				return nil
+			}
 
-				result.Preset[counter] = entry
-				counter++
+			if d.IsDir() {
+				return nil
 			}
 
-			if i == 0 {
-				result.XMLStartIndex = counter
+			content, err := fs.ReadFile(source.FS, filePath)
+			if err != nil {
+				return err
 			}
+
+			entries = append(entries, newEntry(source, filePath, TypeOf(d.Name(), content)))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load presets from %s: %w", source.Name, err)
 		}
+	}
+
+	return newData(entries), nil
+}
+
+func newEntry(source Source, filePath string, presetType rri.Format) Entry {
+	// directories named by the preset type like kv/domain only group presets
+	dirName := path.Dir(filePath)
+	if dirName == "." {
+		dirName = ""
+	}
+	if parts := strings.SplitN(dirName, "/", 2); strings.EqualFold(parts[0], string(presetType)) {
+		dirName = strings.Join(parts[1:], "/")
+	}
 
+	return Entry{
+		Type:     presetType,
+		DirName:  dirName,
+		FileName: path.Base(filePath),
+		Source:   source.Name,
+		Path:     filePath,
+		fsys:     source.FS,
+	}
+}
+
+// newData indexes entries with kv presets first, each type sorted by directory and file name.
+func newData(entries []Entry) *Data {
+	type key struct {
+		presetType rri.Format
+		fileName   string
+	}
+
+	unique := make([]Entry, 0, len(entries))
+	indices := make(map[key]int)
+	for _, entry := range entries {
+		k := key{entry.Type, strings.ToLower(entry.FileName)}
+		if i, ok := indices[k]; ok {
+			unique[i] = entry
+			continue
+		}
+		indices[k] = len(unique)
+		unique = append(unique, entry)
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		if unique[i].Type != unique[j].Type {
+			return unique[i].Type == rri.FormatKV
+		}
+		if unique[i].DirName != unique[j].DirName {
+			return unique[i].DirName < unique[j].DirName
+		}
+		return unique[i].FileName < unique[j].FileName
+	})
+
+	result := &Data{
+		Preset:        make(map[int]Entry),
+		XMLStartIndex: len(unique),
+	}
+	for i, entry := range unique {
+		result.Preset[i] = entry
+		if entry.Type == rri.FormatXML && i < result.XMLStartIndex {
+			result.XMLStartIndex = i
+		}
+	}
+
+	return result
+}
+
+// Save writes content as new preset named name to the preset directory dir and returns its entry.
+func Save(dir, name string, presetType rri.Format, content []byte) (Entry, error) {
+	if len(name) == 0 || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return Entry{}, fmt.Errorf("invalid preset name %q", name)
+	}
+
+	if presetType == rri.FormatXML && path.Ext(name) == "" {
+		name += ".xml"
+	}
+
+	filePath := path.Join(string(presetType), name)
+	if err := os.MkdirAll(filepath.Join(dir, string(presetType)), 0o755); err != nil {
+		return Entry{}, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(filePath)), content, 0o644); err != nil {
+		return Entry{}, err
 	}
 
-	return result, nil
+	return newEntry(Source{Name: dir, FS: os.DirFS(dir)}, filePath, presetType), nil
 }
@@ -0,0 +1,74 @@
+package preset_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DENICeG/go-rriclient/pkg/preset"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	builtin := fstest.MapFS{
+		"kv/domain/domain_info":      {Data: []byte("version: 5.0\naction: info")},
+		"kv/domain/domain_check":     {Data: []byte("version: 5.0\naction: check")},
+		"xml/domain/domain_info.xml": {Data: []byte("<registry-request/>")},
+	}
+	user := fstest.MapFS{
+		"domain_info":       {Data: []byte("version: 5.0\naction: info\ndomain: denic.de")},
+		"team/transfer":     {Data: []byte("<?xml version=\"1.0\"?><registry-request/>")},
+		".git/config":       {Data: []byte("[core]")},
+		"contact/create.kv": {Data: []byte("version: 5.0\naction: create")},
+	}
+
+	data, err := preset.Load(preset.Source{Name: preset.BuiltinSource, FS: builtin}, preset.Source{Name: "user", FS: user})
+	require.NoError(t, err)
+	require.Len(t, data.Preset, 5)
+	assert.Equal(t, 3, data.XMLStartIndex)
+
+	entry := data.Get("DOMAIN_INFO")
+	require.NotNil(t, entry)
+	assert.Equal(t, rri.FormatKV, entry.Type)
+	assert.Equal(t, "user", entry.Source)
+	content, err := entry.Read()
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "denic.de")
+
+	entry = data.Get("domain_info.xml")
+	require.NotNil(t, entry)
+	assert.Equal(t, rri.FormatXML, entry.Type)
+	assert.Equal(t, "domain", entry.DirName)
+	assert.True(t, entry.IsBuiltin())
+
+	entry = data.Get("transfer")
+	require.NotNil(t, entry)
+	assert.Equal(t, rri.FormatXML, entry.Type)
+	assert.Equal(t, "team", entry.DirName)
+
+	assert.Nil(t, data.Get("config"))
+}
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	data, err := preset.Load(preset.DirSources(dir, filepath.Join(dir, "missing"))...)
+	require.NoError(t, err)
+	require.Empty(t, data.Preset)
+
+	entry, err := preset.Save(dir, "my_info", rri.FormatXML, []byte("<registry-request/>"))
+	require.NoError(t, err)
+	assert.Equal(t, "my_info.xml", entry.FileName)
+	data.Add(entry)
+	require.NotNil(t, data.Get("my_info.xml"))
+
+	content, err := os.ReadFile(filepath.Join(dir, "xml", "my_info.xml"))
+	require.NoError(t, err)
+	assert.Equal(t, "<registry-request/>", string(content))
+
+	_, err = preset.Save(dir, "../escape", rri.FormatKV, nil)
+	assert.Error(t, err)
+}
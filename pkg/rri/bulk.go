@@ -0,0 +1,259 @@
+package rri
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// BulkAction denotes the operation applied to every domain by BulkDomains.
+type BulkAction string
+
+const (
+	// BulkActionUpdate sends an UPDATE query with the data of every row, e.g. to move domains to new name servers.
+	BulkActionUpdate BulkAction = "update"
+	// BulkActionChangeHolder sends a CHHOLDER query with the data of every row.
+	BulkActionChangeHolder BulkAction = "chholder"
+	// BulkActionDelete sends a DELETE query for every row.
+	BulkActionDelete BulkAction = "delete"
+)
+
+// BulkActions returns all supported bulk actions.
+func BulkActions() []BulkAction {
+	return []BulkAction{BulkActionUpdate, BulkActionChangeHolder, BulkActionDelete}
+}
+
+// ParseBulkAction parses a bulk action from string.
+func ParseBulkAction(str string) (BulkAction, error) {
+	for _, action := range BulkActions() {
+		if strings.EqualFold(str, string(action)) {
+			return action, nil
+		}
+	}
+	return "", fmt.Errorf("invalid bulk action %q", str)
+}
+
+// Query returns the query of the action for row.
+func (a BulkAction) Query(row BulkRow) *Query {
+	switch a {
+	case BulkActionUpdate:
+		return NewUpdateDomainQuery(row.Domain, row.Data)
+	case BulkActionChangeHolder:
+		return NewChangeHolderQuery(row.Domain, row.Data)
+	case BulkActionDelete:
+		return NewDeleteDomainQuery(row.Domain)
+	default:
+		return nil
+	}
+}
+
+// BulkRow denotes a domain and its new data for BulkDomains.
+type BulkRow struct {
+	Domain string
+	Data   DomainData
+}
+
+// ReadBulkCSV reads rows for BulkDomains from a CSV document.
+//
+// The first line names the columns domain, holder, general-request, abuse-contact and nserver. Only domain is required. Cells may contain multiple values separated by spaces or semicolons and columns may be repeated. Lines starting with # are ignored.
+func ReadBulkCSV(r io.Reader) ([]BulkRow, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("missing header line")
+		}
+		return nil, err
+	}
+
+	columns := make([]string, len(header))
+	hasDomain := false
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "domain":
+			hasDomain = true
+		case "holder":
+		case "general-request", "generalrequest":
+			name = "general-request"
+		case "abuse-contact", "abusecontact":
+			name = "abuse-contact"
+		case "nserver", "nameserver", "nameservers":
+			name = "nserver"
+		default:
+			return nil, fmt.Errorf("unknown column %q", header[i])
+		}
+		columns[i] = name
+	}
+	if !hasDomain {
+		return nil, fmt.Errorf("missing domain column")
+	}
+
+	rows := make([]BulkRow, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		var row BulkRow
+		for i, cell := range record {
+			if i >= len(columns) {
+				return nil, fmt.Errorf("line %d: more cells than columns", line)
+			}
+
+			values := strings.FieldsFunc(cell, func(r rune) bool { return r == ';' || r == ' ' || r == '\t' })
+			if columns[i] == "domain" {
+				row.Domain = strings.Join(values, "")
+				continue
+			}
+			if columns[i] == "nserver" {
+				row.Data.NameServers = append(row.Data.NameServers, values...)
+				continue
+			}
+
+			for _, value := range values {
+				handle, err := ParseDenicHandle(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %q: %s", line, value, err.Error())
+				}
+				switch columns[i] {
+				case "holder":
+					row.Data.HolderHandles = append(row.Data.HolderHandles, handle)
+				case "general-request":
+					row.Data.GeneralRequestHandles = append(row.Data.GeneralRequestHandles, handle)
+				case "abuse-contact":
+					row.Data.AbuseContactHandles = append(row.Data.AbuseContactHandles, handle)
+				}
+			}
+		}
+
+		if len(row.Domain) == 0 {
+			return nil, fmt.Errorf("line %d: missing domain", line)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// BulkDomainsOptions configures BulkDomains.
+type BulkDomainsOptions struct {
+	// Client denotes the logged in session to send queries with.
+	Client *Client
+	// Sessions denotes the number of parallel sessions including Client. Additional sessions are opened with Client.NewSession and closed afterwards. Defaults to 1.
+	Sessions int
+	// RateLimit denotes the maximum number of queries per second across all sessions. Unlimited if zero.
+	RateLimit float64
+	// Skip returns whether a domain has already been processed, e.g. by an interrupted run. Skipped domains are reported with Skipped set.
+	Skip func(domain string) bool
+	// Progress is called with the result of every processed domain. Calls are not concurrent.
+	Progress func(result BulkResult)
+}
+
+// BulkResult holds the outcome of a single row of BulkDomains.
+type BulkResult struct {
+	Domain string
+	Result Result
+	STID   string
+	// Errors contains the business messages of failed queries.
+	Errors []BusinessMessage
+	// Err denotes a technical error or an invalid row that prevented sending the query.
+	Err error
+	// Skipped denotes a domain that has not been processed, because Skip returned true.
+	Skipped bool
+}
+
+// IsSuccessful returns true if the query of the row has been sent and succeeded.
+func (r BulkResult) IsSuccessful() bool {
+	return r.Err == nil && !r.Skipped && r.Result.Normalize() == ResultSuccess
+}
+
+// BulkDomains sends the query of action for every row and returns the results in the same order.
+//
+// Rows are validated before anything is sent. Queries are distributed across opts.Sessions parallel sessions. A canceled context stops sending further queries, unsent rows are reported with the context error.
+func BulkDomains(ctx context.Context, action BulkAction, rows []BulkRow, opts BulkDomainsOptions) ([]BulkResult, error) {
+	if action.Query(BulkRow{}) == nil {
+		return nil, fmt.Errorf("invalid bulk action %q", action)
+	}
+
+	results := make([]BulkResult, len(rows))
+	queries := make([]*Query, len(rows))
+	pending := make([]int, 0, len(rows))
+	for i, row := range rows {
+		results[i] = BulkResult{Domain: row.Domain}
+		if opts.Skip != nil && opts.Skip(row.Domain) {
+			results[i].Skipped = true
+			continue
+		}
+
+		queries[i] = action.Query(row)
+		if _, err := ParseDomain(row.Domain); err != nil {
+			results[i].Err = err
+		} else if errs := ValidateQuery(queries[i]); len(errs) > 0 {
+			results[i].Err = errors.Join(errs...)
+		}
+		if results[i].Err != nil {
+			results[i].Result = ResultFailure
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	var mutex sync.Mutex
+	report := func(i int) {
+		if opts.Progress != nil {
+			mutex.Lock()
+			defer mutex.Unlock()
+			opts.Progress(results[i])
+		}
+	}
+	for i := range results {
+		if results[i].Err != nil {
+			report(i)
+		}
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	err := dispatchQueries(ctx, len(pending), poolOptions{Client: opts.Client, Sessions: opts.Sessions, RateLimit: opts.RateLimit}, func(client *Client, j int) {
+		i := pending[j]
+		results[i] = sendBulkQuery(client, rows[i].Domain, queries[i])
+		report(i)
+	}, func(j int, err error) {
+		i := pending[j]
+		results[i].Result = ResultFailure
+		results[i].Err = err
+	})
+
+	return results, err
+}
+
+func sendBulkQuery(client *Client, domain string, query *Query) BulkResult {
+	result := BulkResult{Domain: domain}
+
+	response, err := client.SendQuery(query)
+	if err != nil {
+		result.Result = ResultFailure
+		result.Err = err
+		return result
+	}
+
+	result.Result = response.Result()
+	result.STID = response.STID()
+	if !response.IsSuccessful() {
+		result.Errors = response.ErrorMessages()
+	}
+	return result
+}
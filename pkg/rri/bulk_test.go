@@ -0,0 +1,97 @@
+package rri_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBulkCSV(t *testing.T) {
+	rows, err := rri.ReadBulkCSV(strings.NewReader(`# moved to new name servers
+domain,holder,nserver
+denic.de,DENIC-1000011-HOLDER,ns1.denic.de;ns2.denic.de
+dömäin.de,DENIC-1000011-HOLDER DENIC-1000011-HOLDER2,ns1.denic.de
+`))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	assert.Equal(t, "denic.de", rows[0].Domain)
+	require.Len(t, rows[0].Data.HolderHandles, 1)
+	assert.Equal(t, "DENIC-1000011-HOLDER", rows[0].Data.HolderHandles[0].String())
+	assert.Equal(t, []string{"ns1.denic.de", "ns2.denic.de"}, rows[0].Data.NameServers)
+
+	assert.Equal(t, "dömäin.de", rows[1].Domain)
+	assert.Len(t, rows[1].Data.HolderHandles, 2)
+
+	_, err = rri.ReadBulkCSV(strings.NewReader("holder\nDENIC-1000011-HOLDER\n"))
+	assert.EqualError(t, err, "missing domain column")
+
+	_, err = rri.ReadBulkCSV(strings.NewReader("domain,owner\ndenic.de,foo\n"))
+	assert.EqualError(t, err, `unknown column "owner"`)
+
+	_, err = rri.ReadBulkCSV(strings.NewReader("domain,holder\ndenic.de,foo\n"))
+	assert.Error(t, err)
+}
+
+func TestBulkDomains(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		var mutex sync.Mutex
+		updated := make([]string, 0)
+		server.Handler = func(user string, session *rri.Session, query *rri.Query) (*rri.Response, error) {
+			if query.Action() == rri.ActionUpdate {
+				domain := query.FirstField(rri.QueryFieldNameDomainIDN)
+				if domain == "failed.de" {
+					return rri.NewResponseWithError(rri.ResultFailure, nil, rri.NewBusinessMessage(53200000001, "domain not found")), nil
+				}
+				mutex.Lock()
+				updated = append(updated, domain)
+				mutex.Unlock()
+			}
+			return rri.NewResponse(rri.ResultSuccess, nil), nil
+		}
+
+		client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true})
+		require.NoError(t, err)
+		defer client.Close()
+		require.NoError(t, client.Login("DENIC-1000011-TEST", "secret"))
+
+		rows, err := rri.ReadBulkCSV(strings.NewReader(`domain,holder,nserver
+denic.de,DENIC-1000011-HOLDER,ns1.denic.de
+done.de,DENIC-1000011-HOLDER,ns1.denic.de
+failed.de,DENIC-1000011-HOLDER,ns1.denic.de
+invalid.de,,ns1.denic.de
+`))
+		require.NoError(t, err)
+
+		var progress []string
+		results, err := rri.BulkDomains(context.Background(), rri.BulkActionUpdate, rows, rri.BulkDomainsOptions{
+			Client:   client,
+			Sessions: 2,
+			Skip:     func(domain string) bool { return domain == "done.de" },
+			Progress: func(result rri.BulkResult) { progress = append(progress, result.Domain) },
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 4)
+
+		assert.True(t, results[0].IsSuccessful())
+		assert.True(t, results[1].Skipped)
+		assert.False(t, results[1].IsSuccessful())
+
+		assert.False(t, results[2].IsSuccessful())
+		require.Len(t, results[2].Errors, 1)
+		assert.Equal(t, int64(53200000001), results[2].Errors[0].ID())
+
+		assert.False(t, results[3].IsSuccessful())
+		assert.EqualError(t, results[3].Err, "missing holder field")
+
+		assert.Equal(t, []string{"denic.de"}, updated)
+		assert.ElementsMatch(t, []string{"denic.de", "failed.de", "invalid.de"}, progress)
+	})
+}
@@ -0,0 +1,199 @@
+package rri
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DomainStatusFree denotes a domain that is available for registration.
+	DomainStatusFree DomainStatus = "free"
+	// DomainStatusConnect denotes a registered domain that is connected to the DNS.
+	DomainStatusConnect DomainStatus = "connect"
+	// DomainStatusFailed denotes a domain that could not be checked.
+	DomainStatusFailed DomainStatus = "failed"
+)
+
+// DomainStatus represents the domain status as returned by a CHECK query.
+type DomainStatus string
+
+// Normalize returns the normalized representation of the given DomainStatus.
+func (s DomainStatus) Normalize() DomainStatus {
+	return DomainStatus(strings.ToLower(string(s)))
+}
+
+// CheckDomainsOptions configures CheckDomains.
+type CheckDomainsOptions struct {
+	// Client denotes the logged in session to send queries with.
+	Client *Client
+	// Sessions denotes the number of parallel sessions including Client. Additional sessions are opened with Client.NewSession and closed afterwards. Defaults to 1.
+	Sessions int
+	// RateLimit denotes the maximum number of queries per second across all sessions. Unlimited if zero.
+	RateLimit float64
+}
+
+// CheckDomainResult holds the availability of a single domain as returned by CheckDomains.
+type CheckDomainResult struct {
+	// Domain denotes the IDN domain name.
+	Domain string
+	// DomainACE denotes the ACE domain name.
+	DomainACE string
+	Status    DomainStatus
+	STID      string
+	// Errors contains the business messages of failed queries.
+	Errors []BusinessMessage
+	// Err denotes a technical error that prevented the check.
+	Err error
+}
+
+// IsAvailable returns true if the domain is free for registration.
+func (r CheckDomainResult) IsAvailable() bool {
+	return r.Err == nil && r.Status == DomainStatusFree
+}
+
+// CheckDomains sends a CHECK query for every domain name and returns the results in the same order.
+//
+// Queries are distributed across opts.Sessions parallel sessions. A canceled context stops sending further queries, unsent domains are reported with the context error.
+func CheckDomains(ctx context.Context, names []string, opts CheckDomainsOptions) ([]CheckDomainResult, error) {
+	results := make([]CheckDomainResult, len(names))
+	for i, name := range names {
+		results[i] = CheckDomainResult{Domain: name}
+	}
+
+	err := dispatchQueries(ctx, len(names), poolOptions{Client: opts.Client, Sessions: opts.Sessions, RateLimit: opts.RateLimit}, func(client *Client, i int) {
+		results[i] = checkDomain(client, names[i])
+	}, func(i int, err error) {
+		results[i].Status = DomainStatusFailed
+		results[i].Err = err
+	})
+
+	return results, err
+}
+
+func checkDomain(client *Client, name string) CheckDomainResult {
+	if _, err := ParseDomain(name); err != nil {
+		return CheckDomainResult{Domain: name, Status: DomainStatusFailed, Err: err}
+	}
+
+	query := NewCheckDomainQuery(name)
+	result := CheckDomainResult{
+		Domain:    query.FirstField(QueryFieldNameDomainIDN),
+		DomainACE: query.FirstField(QueryFieldNameDomainACE),
+	}
+
+	response, err := client.SendQuery(query)
+	if err != nil {
+		result.Status = DomainStatusFailed
+		result.Err = err
+		return result
+	}
+
+	result.STID = response.STID()
+	if domain := response.FirstField(ResponseFieldNameDomain); len(domain) > 0 {
+		result.Domain = domain
+	}
+	if ace := response.FirstField(ResponseFieldNameDomainACE); len(ace) > 0 {
+		result.DomainACE = ace
+	}
+
+	if !response.IsSuccessful() {
+		result.Status = DomainStatusFailed
+		result.Errors = response.ErrorMessages()
+		return result
+	}
+
+	result.Status = DomainStatus(response.FirstField(ResponseFieldNameStatus)).Normalize()
+	return result
+}
+
+// poolOptions configures the session pool of dispatchQueries.
+type poolOptions struct {
+	Client    *Client
+	Sessions  int
+	RateLimit float64
+}
+
+// dispatchQueries calls process for every index in [0, count) distributed across a pool of sessions.
+//
+// skip is called for every index that has not been processed due to a canceled context.
+func dispatchQueries(ctx context.Context, count int, opts poolOptions, process func(client *Client, i int), skip func(i int, err error)) error {
+	if opts.Client == nil {
+		return fmt.Errorf("missing client")
+	}
+
+	sessionCount := opts.Sessions
+	if sessionCount <= 0 {
+		sessionCount = 1
+	}
+	if sessionCount > count {
+		sessionCount = count
+	}
+
+	clients := []*Client{opts.Client}
+	defer func() {
+		// only close the additional sessions
+		for _, client := range clients[1:] {
+			client.Close()
+		}
+	}()
+
+	for len(clients) < sessionCount {
+		session, err := opts.Client.NewSession()
+		if err != nil {
+			return fmt.Errorf("failed to open session: %s", err.Error())
+		}
+		clients = append(clients, session)
+	}
+
+	var limiter <-chan time.Time
+	if opts.RateLimit > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.RateLimit))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client *Client) {
+			defer wg.Done()
+			for i := range jobs {
+				process(client, i)
+			}
+		}(client)
+	}
+
+	var err error
+	i := 0
+DispatchLoop:
+	for ; i < count; i++ {
+		if limiter != nil {
+			select {
+			case <-ctx.Done():
+				break DispatchLoop
+			case <-limiter:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			break DispatchLoop
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if i < count {
+		err = ctx.Err()
+		for ; i < count; i++ {
+			skip(i, err)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,82 @@
+package rri_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDomains(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		server.Handler = func(user string, session *rri.Session, query *rri.Query) (*rri.Response, error) {
+			if query.Action() == rri.ActionCheck {
+				domain := query.FirstField(rri.QueryFieldNameDomainIDN)
+				if domain == "invalid.de" {
+					return rri.NewResponseWithError(rri.ResultFailure, nil, rri.NewBusinessMessage(53000000001, "invalid domain")), nil
+				}
+				fields := rri.NewResponseFieldList()
+				fields.Add(rri.ResponseFieldNameDomain, domain)
+				fields.Add(rri.ResponseFieldNameDomainACE, query.FirstField(rri.QueryFieldNameDomainACE))
+				if domain == "denic.de" {
+					fields.Add(rri.ResponseFieldNameStatus, "connect")
+				} else {
+					fields.Add(rri.ResponseFieldNameStatus, "free")
+				}
+				return rri.NewResponse(rri.ResultSuccess, fields), nil
+			}
+			return rri.NewResponse(rri.ResultSuccess, nil), nil
+		}
+
+		client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true})
+		require.NoError(t, err)
+		defer client.Close()
+		require.NoError(t, client.Login("DENIC-1000011-TEST", "secret"))
+
+		names := []string{"denic.de", "xn--dmin-moa0i.de", "invalid.de", "free.de"}
+		results, err := rri.CheckDomains(context.Background(), names, rri.CheckDomainsOptions{Client: client, Sessions: 3, RateLimit: 100})
+		require.NoError(t, err)
+		require.Len(t, results, 4)
+
+		assert.Equal(t, "denic.de", results[0].Domain)
+		assert.Equal(t, rri.DomainStatusConnect, results[0].Status)
+		assert.False(t, results[0].IsAvailable())
+
+		assert.Equal(t, "dömäin.de", results[1].Domain)
+		assert.Equal(t, "xn--dmin-moa0i.de", results[1].DomainACE)
+		assert.True(t, results[1].IsAvailable())
+
+		assert.Equal(t, rri.DomainStatusFailed, results[2].Status)
+		require.Len(t, results[2].Errors, 1)
+		assert.Equal(t, int64(53000000001), results[2].Errors[0].ID())
+
+		assert.True(t, results[3].IsAvailable())
+		assert.True(t, client.IsLoggedIn())
+	})
+}
+
+func TestCheckDomainsCanceled(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true})
+		require.NoError(t, err)
+		defer client.Close()
+		require.NoError(t, client.Login("DENIC-1000011-TEST", "secret"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		results, err := rri.CheckDomains(ctx, []string{"denic.de", "free.de"}, rri.CheckDomainsOptions{Client: client})
+		assert.ErrorIs(t, err, context.Canceled)
+		require.Len(t, results, 2)
+		for _, result := range results {
+			assert.Equal(t, rri.DomainStatusFailed, result.Status)
+			assert.ErrorIs(t, result.Err, context.Canceled)
+		}
+	})
+}
@@ -2,10 +2,23 @@ package rri
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSessionExpired is passed to the SessionExpiredHandler when a keep-alive query detected a lost session. It is also returned for queries if a lost session cannot be restored.
+	ErrSessionExpired = errors.New("session expired")
+)
+
+const (
+	// businessMessageIDLoginRequired is returned by RRI for queries sent without an active session.
+	businessMessageIDLoginRequired = 83000000010
 )
 
 // TLSDialer is the callback function to open a new TLS connection. Maps tls.Dial by default.
@@ -22,22 +35,40 @@ type QueryProcessor func(*Query) *Query
 // RawQueryPrinter is called to print a raw outgoing or incoming query string.
 type RawQueryPrinter func(msg string, isOutgoing bool)
 
+// RawExchangeHandler is called with a raw query and the raw response received for it. Keep-alive queries are not reported.
+type RawExchangeHandler func(query, response string)
+
 // ErrorPrinter is called to print uncritical errors.
 type ErrorPrinter func(err error)
 
-// Client represents a stateful connection to a specific RRI Server.
+// SessionExpiredHandler is called from the keep-alive routine when the RRI session has been lost. The session is restored with the next query. It is called from a background goroutine and must not interact with the user directly.
+type SessionExpiredHandler func(err error)
+
+// KeepAliveQueryFactory returns the query that is sent periodically to keep an idle session alive.
+type KeepAliveQueryFactory func() *Query
+
+// Client represents a stateful connection to a specific RRI Server. The exported handlers must be set before the first query is sent, because they are read by the keep-alive routine afterwards.
 type Client struct {
-	connection        TLSConnection
-	dialer            TLSDialer
-	tlsConfig         *tls.Config
-	RawQueryPrinter   RawQueryPrinter
-	InnerErrorPrinter ErrorPrinter
-	address           string
-	currentUser       string
-	lastUser          string
-	lastPass          string
-	XMLMode           bool
-	NoAutoRetry       bool
+	connection            TLSConnection
+	dialer                TLSDialer
+	tlsConfig             *tls.Config
+	RawQueryPrinter       RawQueryPrinter
+	RawExchangeHandler    RawExchangeHandler
+	InnerErrorPrinter     ErrorPrinter
+	SessionExpiredHandler SessionExpiredHandler
+	keepAliveInterval     time.Duration
+	keepAliveQuery        KeepAliveQueryFactory
+	idleTimeout           time.Duration
+	lastActivity          time.Time
+	stopMaintenance       chan struct{}
+	maintenanceStarted    bool
+	config                ClientConfig
+	mutex                 sync.Mutex
+	address               string
+	currentUser           string
+	credentials           CredentialProvider
+	XMLMode               bool
+	NoAutoRetry           bool
 }
 
 // ClientConfig can be used to further configure the RRI client.
@@ -48,6 +79,14 @@ type ClientConfig struct {
 	Insecure bool
 	// MinTLSVersion denotes the minimum accepted TLS version.
 	MinTLSVersion uint16
+	// Certificates denotes the client certificates presented to the RRI server.
+	Certificates []tls.Certificate
+	// KeepAliveInterval enables a background routine that sends KeepAliveQuery when the session has been idle for the given duration. Disabled if zero.
+	KeepAliveInterval time.Duration
+	// KeepAliveQuery returns the query to send as heartbeat. Sends a CHECK for denic.de by default.
+	KeepAliveQuery KeepAliveQueryFactory
+	// IdleTimeout closes the connection after no query has been sent for the given duration. The session is transparently restored with the next query. Disabled if zero.
+	IdleTimeout time.Duration
 }
 
 // NewClient returns a new Client object for the given RRI Server.
@@ -70,6 +109,11 @@ func NewClient(address string, conf *ClientConfig) (*Client, error) {
 	if actualConf.MinTLSVersion <= 0 {
 		actualConf.MinTLSVersion = tls.VersionTLS13
 	}
+	if actualConf.KeepAliveQuery == nil {
+		actualConf.KeepAliveQuery = func() *Query {
+			return NewCheckDomainQuery("denic.de")
+		}
+	}
 
 	client := &Client{
 		address: address,
@@ -77,7 +121,12 @@ func NewClient(address string, conf *ClientConfig) (*Client, error) {
 		tlsConfig: &tls.Config{
 			MinVersion:         actualConf.MinTLSVersion,
 			InsecureSkipVerify: actualConf.Insecure,
+			Certificates:       actualConf.Certificates,
 		},
+		keepAliveInterval: actualConf.KeepAliveInterval,
+		keepAliveQuery:    actualConf.KeepAliveQuery,
+		idleTimeout:       actualConf.IdleTimeout,
+		config:            actualConf,
 	}
 
 	if err := client.setupConnection(); err != nil {
@@ -87,7 +136,43 @@ func NewClient(address string, conf *ClientConfig) (*Client, error) {
 	return client, nil
 }
 
+// NewSession opens an additional connection to the same RRI server with the same configuration. If this client is logged in, the new session is logged in with the same credentials.
+func (client *Client) NewSession() (*Client, error) {
+	client.mutex.Lock()
+	credentials := client.credentials
+	loggedIn := client.IsLoggedIn()
+	client.mutex.Unlock()
+
+	if loggedIn && credentials == nil {
+		return nil, fmt.Errorf("credentials of the current session are unknown")
+	}
+
+	session, err := NewClient(client.address, &client.config)
+	if err != nil {
+		return nil, err
+	}
+
+	session.RawQueryPrinter = client.RawQueryPrinter
+	session.RawExchangeHandler = client.RawExchangeHandler
+	session.InnerErrorPrinter = client.InnerErrorPrinter
+	session.SessionExpiredHandler = client.SessionExpiredHandler
+	session.NoAutoRetry = client.NoAutoRetry
+
+	if loggedIn {
+		if err := session.LoginWith(credentials); err != nil {
+			session.Close()
+			return nil, err
+		}
+	}
+
+	return session, nil
+}
+
+// Connection returns the underlying connection or nil if the client is currently disconnected.
 func (client *Client) Connection() TLSConnection {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
 	return client.connection
 }
 
@@ -103,6 +188,130 @@ func (client *Client) setupConnection() error {
 
 	client.connection = conn
 	client.currentUser = ""
+	client.lastActivity = time.Now()
+
+	return nil
+}
+
+// ensureConnection establishes a new connection if necessary and restores a previously authenticated session.
+func (client *Client) ensureConnection() error {
+	if client.connection != nil {
+		return nil
+	}
+
+	hadSession := client.IsLoggedIn()
+	if err := client.setupConnection(); err != nil {
+		return err
+	}
+
+	if hadSession {
+		return client.restoreSession()
+	}
+
+	return nil
+}
+
+// restoreSession logs in again with the credential provider of the last successful login. ErrSessionExpired is returned if the password is unknown, because the login has been sent with SendQuery or SendRaw.
+func (client *Client) restoreSession() error {
+	if client.credentials == nil {
+		return fmt.Errorf("%w: the password of the LOGIN query has not been retained, log in again", ErrSessionExpired)
+	}
+
+	if err := client.loginWith(client.credentials); err != nil {
+		return fmt.Errorf("failed to restore session: %s", err.Error())
+	}
+
+	return nil
+}
+
+// startMaintenance starts the keep-alive and idle timeout routine once. It is called with the first query so that handlers assigned after NewClient are visible to the routine.
+func (client *Client) startMaintenance() {
+	if client.maintenanceStarted {
+		return
+	}
+	client.maintenanceStarted = true
+
+	period := client.maintenancePeriod()
+	if period <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	client.stopMaintenance = stop
+
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				client.maintain()
+			}
+		}
+	}()
+}
+
+// maintenancePeriod returns the tick interval of the maintenance routine or zero if it is not required.
+func (client *Client) maintenancePeriod() time.Duration {
+	period := client.keepAliveInterval
+	if client.idleTimeout > 0 && (period <= 0 || client.idleTimeout < period) {
+		period = client.idleTimeout
+	}
+
+	// tick faster than the configured durations to not exceed them by more than half
+	return period / 2
+}
+
+// maintain is called periodically to enforce the idle timeout and send keep-alive queries.
+func (client *Client) maintain() {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if client.connection == nil {
+		return
+	}
+
+	idle := time.Since(client.lastActivity)
+
+	if client.idleTimeout > 0 && idle >= client.idleTimeout {
+		// ignore close errors (connection will be re-established on demand)
+		client.closeConnection()
+		return
+	}
+
+	if client.keepAliveInterval > 0 && idle >= client.keepAliveInterval && client.IsLoggedIn() {
+		if err := client.keepAlive(); err != nil {
+			// drop connection so the session is restored with the next query
+			client.closeConnection()
+			if client.SessionExpiredHandler != nil {
+				client.SessionExpiredHandler(err)
+			}
+		}
+	}
+}
+
+// keepAlive sends a single keep-alive query without automatic retry and returns ErrSessionExpired if the session is gone. Keep-alive queries are not passed to RawQueryPrinter, because they are sent in the background.
+func (client *Client) keepAlive() error {
+	query := client.keepAliveQuery()
+
+	rawResponse, err := client.sendAndReceive(PrepareMessage(query.EncodeKV()))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrSessionExpired, err.Error())
+	}
+
+	response, err := ParseResponse(rawResponse)
+	if err != nil {
+		return fmt.Errorf("%w: received malformed response: %s", ErrSessionExpired, err.Error())
+	}
+
+	for _, msg := range response.ErrorMessages() {
+		if msg.ID() == businessMessageIDLoginRequired {
+			return fmt.Errorf("%w: %s", ErrSessionExpired, msg.Message())
+		}
+	}
 
 	return nil
 }
@@ -137,8 +346,16 @@ func (client *Client) CurrentRegAccID() (int, error) {
 	return regAccID, nil
 }
 
-// Close closes the underlying connection.
+// Close stops the keep-alive routine and closes the underlying connection.
 func (client *Client) Close() error {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if client.stopMaintenance != nil {
+		close(client.stopMaintenance)
+		client.stopMaintenance = nil
+	}
+
 	if client.connection != nil {
 		// TODO send LOGOUT while connected?
 		return client.closeConnection()
@@ -170,8 +387,29 @@ func (client *Client) closeConnection() error {
 }
 
 // Login sends a login request to the server and checks for a success result.
+//
+// The password is retained in memory to restore lost sessions. Use LoginWith to obtain credentials on demand instead.
 func (client *Client) Login(username, password string) error {
-	r, err := client.SendQuery(NewLoginQuery(username, password))
+	return client.LoginWith(StaticCredentials(username, password))
+}
+
+// LoginWith requests credentials from provider, sends a login request and checks for a success result.
+//
+// The provider is asked again whenever a lost session needs to be restored.
+func (client *Client) LoginWith(provider CredentialProvider) error {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	return client.loginWith(provider)
+}
+
+func (client *Client) loginWith(provider CredentialProvider) error {
+	username, password, err := provider.Credentials()
+	if err != nil {
+		return fmt.Errorf("failed to obtain credentials: %s", err.Error())
+	}
+
+	r, err := client.sendQuery(NewLoginQuery(username, password))
 	if err != nil {
 		return err
 	}
@@ -180,7 +418,9 @@ func (client *Client) Login(username, password string) error {
 		return fmt.Errorf("login failed")
 	}
 
-	return err
+	// replace credentials extracted from the query to not retain the password
+	client.credentials = provider
+	return nil
 }
 
 // Logout sends a logout request to the server.
@@ -192,7 +432,16 @@ func (client *Client) Logout() error {
 // SendQuery sends a query to the server and returns the response.
 //
 // Only technical errors are returned. You need to check Response.Result to check for RRI error responses.
+//
+// The password of a LOGIN query is not retained, so NewSession fails and queries return ErrSessionExpired once the connection has been lost. Use Login or LoginWith instead.
 func (client *Client) SendQuery(query *Query) (*Response, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	return client.sendQuery(query)
+}
+
+func (client *Client) sendQuery(query *Query) (*Response, error) {
 	if !client.IsLoggedIn() && query.Action() != ActionLogin {
 		return nil, fmt.Errorf("need to log in before sending action %s", query.Action())
 	}
@@ -206,12 +455,11 @@ func (client *Client) SendQuery(query *Query) (*Response, error) {
 			// after action logout the connection and session are closed
 			client.connection = nil
 			client.currentUser = ""
-			client.lastUser = ""
-			client.lastPass = ""
+			client.credentials = nil
 		}()
 	}
 
-	rawResponse, err := client.SendRaw(query.EncodeKV())
+	rawResponse, err := client.sendRaw(query.EncodeKV())
 	if err != nil {
 		if err == io.EOF && query.Action() == ActionLogout {
 			// the server will immediately close the connection once LOGOUT is received
@@ -228,14 +476,8 @@ func (client *Client) SendQuery(query *Query) (*Response, error) {
 
 	if query.Action() == ActionLogin && response.IsSuccessful() {
 		client.currentUser = query.FirstField(QueryFieldNameUser)
-		// save credentials to restore session after lost connections
-		client.lastUser = client.currentUser
-		pwField := query.Field(QueryFieldNamePassword)
-
-		client.lastPass = ""
-		if len(pwField) > 0 {
-			client.lastPass = query.Field(QueryFieldNamePassword)[0]
-		}
+		// the password of LOGIN queries is not retained, only LoginWith keeps its provider to restore sessions
+		client.credentials = nil
 	}
 
 	return response, nil
@@ -245,8 +487,17 @@ func (client *Client) SendQuery(query *Query) (*Response, error) {
 //
 // This method should be used with caution as it does not update the client login state.
 func (client *Client) SendRaw(msg string) (string, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	return client.sendRaw(msg)
+}
+
+func (client *Client) sendRaw(msg string) (string, error) {
+	client.startMaintenance()
+
 	// ensure connection is established
-	if err := client.setupConnection(); err != nil {
+	if err := client.ensureConnection(); err != nil {
 		return "", err
 	}
 
@@ -267,6 +518,7 @@ func (client *Client) SendRaw(msg string) (string, error) {
 		}
 
 		// try re-establishing lost connection once
+		hadSession := client.IsLoggedIn()
 		if client.connection != nil {
 			// ignore close errors (connection will be discarded anyway)
 			client.closeConnection()
@@ -277,9 +529,9 @@ func (client *Client) SendRaw(msg string) (string, error) {
 		}
 
 		// restore authenticated session if it existed before
-		if len(client.lastUser) > 0 && len(client.lastPass) > 0 {
-			if err = client.Login(client.lastUser, client.lastPass); err != nil {
-				return "", fmt.Errorf("failed to restore session: %s", err.Error())
+		if hadSession {
+			if err = client.restoreSession(); err != nil {
+				return "", err
 			}
 		}
 
@@ -297,11 +549,16 @@ func (client *Client) SendRaw(msg string) (string, error) {
 	if client.RawQueryPrinter != nil {
 		client.RawQueryPrinter(response, false)
 	}
+	if client.RawExchangeHandler != nil {
+		client.RawExchangeHandler(msg, response)
+	}
 
 	return response, nil
 }
 
 func (client *Client) sendAndReceive(msg []byte) (string, error) {
+	client.lastActivity = time.Now()
+
 	n, err := client.connection.Write(msg)
 	if err != nil {
 		return "", err
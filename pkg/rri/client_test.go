@@ -4,7 +4,9 @@ import (
 	"crypto/tls"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/DENICeG/go-rriclient/pkg/rri"
 	"github.com/stretchr/testify/assert"
@@ -147,6 +149,92 @@ func TestClientAutoRetry(t *testing.T) {
 	conn.AssertComplete()
 }
 
+func TestClientKeepAlive(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		var checkCount atomic.Int32
+		server.Handler = func(user string, session *rri.Session, query *rri.Query) (*rri.Response, error) {
+			if query.Action() == rri.ActionCheck {
+				checkCount.Add(1)
+			}
+			return rri.NewResponse(rri.ResultSuccess, nil), nil
+		}
+
+		client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true, KeepAliveInterval: 40 * time.Millisecond})
+		require.NoError(t, err)
+		defer client.Close()
+
+		// keep-alive is only sent for authenticated sessions
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, int32(0), checkCount.Load())
+
+		require.NoError(t, client.Login("DENIC-1000011-TEST", "secret"))
+		time.Sleep(150 * time.Millisecond)
+		assert.GreaterOrEqual(t, checkCount.Load(), int32(1))
+		assert.True(t, client.IsLoggedIn())
+	})
+}
+
+func TestClientKeepAliveSessionExpired(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		var expired atomic.Bool
+		server.Handler = func(user string, session *rri.Session, query *rri.Query) (*rri.Response, error) {
+			if expired.Load() {
+				return rri.NewResponseWithError(rri.ResultFailure, nil, rri.NewBusinessMessage(83000000010, "Please login first")), nil
+			}
+			return rri.NewResponse(rri.ResultSuccess, nil), nil
+		}
+
+		client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true, KeepAliveInterval: 40 * time.Millisecond})
+		require.NoError(t, err)
+		defer client.Close()
+
+		expiredErrors := make(chan error, 10)
+		client.SessionExpiredHandler = func(err error) {
+			expiredErrors <- err
+		}
+
+		require.NoError(t, client.Login("DENIC-1000011-TEST", "secret"))
+		expired.Store(true)
+
+		select {
+		case err := <-expiredErrors:
+			assert.ErrorIs(t, err, rri.ErrSessionExpired)
+		case <-time.After(time.Second):
+			require.Fail(t, "session expiry has not been reported")
+		}
+
+		// next query restores the session
+		expired.Store(false)
+		response, err := client.SendQuery(rri.NewInfoDomainQuery("denic.de"))
+		require.NoError(t, err)
+		assert.True(t, response.IsSuccessful())
+	})
+}
+
+func TestClientIdleTimeout(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true, IdleTimeout: 40 * time.Millisecond})
+		require.NoError(t, err)
+		defer client.Close()
+
+		require.NoError(t, client.Login("DENIC-1000011-TEST", "secret"))
+		time.Sleep(150 * time.Millisecond)
+		assert.Nil(t, client.Connection())
+		assert.True(t, client.IsLoggedIn())
+
+		response, err := client.SendQuery(rri.NewInfoDomainQuery("denic.de"))
+		require.NoError(t, err)
+		assert.True(t, response.IsSuccessful())
+		assert.NotNil(t, client.Connection())
+	})
+}
+
 type mockReadWriteCloser struct {
 	ReadResponses  []readResponse
 	ReadIndex      int
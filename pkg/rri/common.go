@@ -62,21 +62,25 @@ func ReadBytes(r io.Reader, count int) ([]byte, error) {
 	return buffer, nil
 }
 
-// CensorRawMessage replaces passwords in a raw query with '******'.
+// censoredKVFields matches the values of KV fields with passwords or auth info secrets.
+var censoredKVFields = regexp.MustCompile(`(?mi)^((?:password|authinfo):[ \t]+)[^\r\n]*`)
+
+// CensorRawMessage replaces passwords and auth info secrets in a raw KV or XML query with '******'.
 func CensorRawMessage(msg string) string {
-	if strings.Contains(msg, "<xml") {
+	if DetectFormat(msg) == FormatXML {
 		doc := etree.NewDocument()
-		err := doc.ReadFromString(msg)
-		if err == nil {
-			return DocToString(doc)
+		if err := doc.ReadFromString(msg); err == nil {
+			for _, path := range []string{"//password", "//authInfo"} {
+				for _, elem := range doc.FindElements(path) {
+					elem.SetText("******")
+				}
+			}
+			result, _ := doc.WriteToString()
+			return result
 		}
 	}
 
-	pattern := regexp.MustCompile("([\r\n]|^)(password:[ \t]+)([^\r\n]*)([\r\n]|$)")
-	return pattern.ReplaceAllStringFunc(msg, func(matchStr string) string {
-		m := pattern.FindStringSubmatch(matchStr)
-		return m[1] + m[2] + "******" + m[4]
-	})
+	return censoredKVFields.ReplaceAllString(msg, "${1}******")
 }
 
 // DocToString converts an XML document to a string, replacing passwords with 'XXX'.
@@ -91,9 +95,10 @@ func DocToString(doc *etree.Document) string {
 	return result
 }
 
-// IsDomainName checks if a string represents a domain name.
+// IsDomainName checks if a string represents a domain name permitted by DENIC. Use ParseDomain to get the reason for rejected names.
 func IsDomainName(str string) bool {
-	return strings.HasSuffix(str, ".de")
+	_, err := ParseDomain(str)
+	return err == nil
 }
 
 // IsHandle checks if a string represents a DENIC handle.
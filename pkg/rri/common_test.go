@@ -96,6 +96,10 @@ func TestCensorRawMessage(t *testing.T) {
 		{description: "actual password 2", input: "version: 5.0\naction: LOGIN\npassword: secret-password\nuser: DENIC-1000011-RRI", expected: "version: 5.0\naction: LOGIN\npassword: ******\nuser: DENIC-1000011-RRI"},
 		{description: "actual password 3", input: "version: 5.0\naction: LOGIN\nuser: DENIC-1000011-RRI\npassword: secret-password", expected: "version: 5.0\naction: LOGIN\nuser: DENIC-1000011-RRI\npassword: ******"},
 		{description: "actual password 4", input: "password: secret-password\nversion: 5.0\npassword: secret-password\naction: LOGIN\nuser: DENIC-1000011-RRI\npassword: secret-password", expected: "password: ******\nversion: 5.0\npassword: ******\naction: LOGIN\nuser: DENIC-1000011-RRI\npassword: ******"},
+		{description: "consecutive passwords", input: "Password: secret-password\r\npassword: secret-password\r\n", expected: "Password: ******\r\npassword: ******\r\n"},
+		{description: "authinfo", input: "version: 5.0\naction: CHPROV\ndomain: denic.de\nauthinfo: secret\nauthinfohash: 4213d924", expected: "version: 5.0\naction: CHPROV\ndomain: denic.de\nauthinfo: ******\nauthinfohash: 4213d924"},
+		{description: "xml password", input: "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<registry-request xmlns=\"http://registry.denic.de/global/5.0\"><login><user>DENIC-1000011-RRI</user><password>secret</password></login></registry-request>", expected: "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<registry-request xmlns=\"http://registry.denic.de/global/5.0\"><login><user>DENIC-1000011-RRI</user><password>******</password></login></registry-request>"},
+		{description: "xml authinfo", input: "<?xml version=\"1.0\"?><registry-request xmlns:domain=\"http://registry.denic.de/domain/5.0\"><domain:chprov><domain:handle>denic.de</domain:handle><domain:authInfo>secret</domain:authInfo></domain:chprov></registry-request>", expected: "<?xml version=\"1.0\"?><registry-request xmlns:domain=\"http://registry.denic.de/domain/5.0\"><domain:chprov><domain:handle>denic.de</domain:handle><domain:authInfo>******</domain:authInfo></domain:chprov></registry-request>"},
 	}
 
 	for _, tc := range tt {
@@ -0,0 +1,653 @@
+package rri
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+const (
+	xmlNamespaceBase           = "http://registry.denic.de/"
+	xmlSchemaInstanceNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// xmlActionTags maps query actions to the tag of the XML action element.
+var xmlActionTags = map[QueryAction]string{
+	ActionLogin:           "login",
+	ActionLogout:          "logout",
+	ActionCheck:           "check",
+	ActionInfo:            "info",
+	ActionCreate:          "create",
+	ActionUpdate:          "update",
+	ActionChangeHolder:    "chholder",
+	ActionDelete:          "delete",
+	ActionRestore:         "restore",
+	ActionTransit:         "transit",
+	ActionCreateAuthInfo1: "createAuthInfo1",
+	ActionCreateAuthInfo2: "createAuthInfo2",
+	ActionDeleteAuthInfo1: "deleteAuthInfo1",
+	ActionChangeProvider:  "chprov",
+	ActionQueueRead:       "queue-read",
+	ActionQueueDelete:     "delete",
+}
+
+// xmlTagNames maps query field names to XML tags and attributes that differ in more than character casing.
+var xmlTagNames = map[QueryFieldName]string{
+	QueryFieldNamePostalCode:            "postalCode",
+	QueryFieldNameCountryCode:           "countryCode",
+	QueryFieldNameAuthInfo:              "authInfo",
+	QueryFieldNameAuthInfoHash:          "hash",
+	QueryFieldNameAuthInfoExpire:        "expire",
+	QueryFieldNameMsgType:               "msgType",
+	QueryFieldNameVerificationResult:    "verificationResult",
+	QueryFieldNameVerificationReference: "verificationReference",
+	QueryFieldNameVerificationTimestamp: "verificationTimestamp",
+	QueryFieldNameVerificationEvidence:  "verificationEvidence",
+	QueryFieldNameVerificationMethod:    "verificationMethod",
+	QueryFieldNameTrustFramework:        "trustFramework",
+}
+
+func xmlTagName(fieldName QueryFieldName) string {
+	if tag, ok := xmlTagNames[fieldName.Normalize()]; ok {
+		return tag
+	}
+	return string(fieldName.Normalize())
+}
+
+func queryFieldNameFromXML(tag string) QueryFieldName {
+	for fieldName, t := range xmlTagNames {
+		if t == tag {
+			return fieldName
+		}
+	}
+	return QueryFieldName(tag).Normalize()
+}
+
+// xmlObjectType returns the object type like domain or contact denoted by the namespace of element.
+func xmlObjectType(element *etree.Element) string {
+	uri := element.NamespaceURI()
+	if strings.HasPrefix(uri, xmlNamespaceBase) {
+		parts := strings.Split(strings.TrimPrefix(uri, xmlNamespaceBase), "/")
+		if parts[0] != "global" {
+			return parts[0]
+		}
+		return ""
+	}
+	return element.Space
+}
+
+// ActionFromXML returns the query action for an XML action element with the given object type and tag like domain and createAuthInfo1.
+func ActionFromXML(objectType, tag string) QueryAction {
+	if objectType == "msg" && tag == "delete" {
+		return ActionQueueDelete
+	}
+
+	for action, actionTag := range xmlActionTags {
+		if action != ActionQueueDelete && strings.EqualFold(actionTag, tag) {
+			return action
+		}
+	}
+	return QueryAction(tag).Normalize()
+}
+
+// ParseQueryXML parses a single XML encoded query. Domain and contact data, DNS entries and verification information are mapped to the corresponding key-value fields and sections.
+func ParseQueryXML(str string) (*Query, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(str); err != nil {
+		return nil, fmt.Errorf("malformed xml: %s", err.Error())
+	}
+
+	root := doc.Root()
+	if root == nil || root.Tag != "registry-request" {
+		return nil, fmt.Errorf("registry-request element is missing")
+	}
+
+	version := LatestVersion
+	if ns := root.SelectAttrValue("xmlns", ""); strings.HasPrefix(ns, xmlNamespaceBase+"global/") {
+		version = Version(strings.TrimPrefix(ns, xmlNamespaceBase+"global/"))
+	}
+
+	var actionElement *etree.Element
+	var ctid string
+	for _, child := range root.ChildElements() {
+		if child.Tag == "ctid" {
+			ctid = strings.TrimSpace(child.Text())
+			continue
+		}
+		if actionElement != nil {
+			return nil, fmt.Errorf("registry-request must contain exactly one action element")
+		}
+		actionElement = child
+	}
+	if actionElement == nil {
+		return nil, fmt.Errorf("action element is missing")
+	}
+
+	objectType := xmlObjectType(actionElement)
+	action := ActionFromXML(objectType, actionElement.Tag)
+	if !action.IsKnown() {
+		return nil, fmt.Errorf("unknown action '%s'", actionElement.FullTag())
+	}
+
+	fields := NewQueryFieldList()
+	var sections []*kvSection
+	if err := readXMLQueryFields(objectType, actionElement, &fields, &sections); err != nil {
+		return nil, err
+	}
+
+	for _, attr := range actionElement.Attr {
+		if attr.Space == "xmlns" || attr.Key == "xmlns" || attr.Space == "xsi" {
+			continue
+		}
+		fields.Add(queryFieldNameFromXML(attr.Key), attr.Value)
+	}
+
+	if len(ctid) > 0 {
+		fields.Add(QueryFieldNameCTID, ctid)
+	}
+
+	return NewQuery(version, action, fields, sections), nil
+}
+
+func readXMLQueryFields(objectType string, parent *etree.Element, fields *QueryFieldList, sections *[]*kvSection) error {
+	for _, child := range parent.ChildElements() {
+		text := strings.TrimSpace(child.Text())
+
+		switch child.Tag {
+		case "handle":
+			switch objectType {
+			case "domain":
+				fields.Add(QueryFieldNameDomainIDN, text)
+			case "regacc":
+				fields.Add(QueryFieldNameRegAcc, text)
+			default:
+				fields.Add(QueryFieldNameHandle, text)
+			}
+
+		case "contact":
+			fields.Add(QueryFieldName(child.SelectAttrValue("role", "contact")).Normalize(), text)
+
+		case "postal":
+			if err := readXMLQueryFields(objectType, child, fields, sections); err != nil {
+				return err
+			}
+
+		case "dnsentry":
+			fieldName, value, err := readXMLDNSEntry(child)
+			if err != nil {
+				return err
+			}
+			fields.Add(fieldName, value)
+
+		case "verificationInformation":
+			section := &kvSection{header: string(QueryEntityVerificationInformation)}
+			for _, verificationChild := range child.ChildElements() {
+				if verificationChild.Tag == "verifiedClaims" {
+					for _, claim := range verificationChild.SelectElements("claim") {
+						section.fields.Add(QueryFieldNameVerifiedClaim, strings.TrimSpace(claim.Text()))
+					}
+					continue
+				}
+				section.fields.Add(queryFieldNameFromXML(verificationChild.Tag), strings.TrimSpace(verificationChild.Text()))
+			}
+			*sections = append(*sections, section)
+
+		default:
+			fields.Add(queryFieldNameFromXML(child.Tag), text)
+		}
+	}
+
+	return nil
+}
+
+// readXMLDNSEntry converts a dnsentry element to a nserver, nsentry or dnskey field.
+func readXMLDNSEntry(element *etree.Element) (QueryFieldName, string, error) {
+	entryType := element.SelectAttrValue("xsi:type", "NS")
+	if index := strings.Index(entryType, ":"); index >= 0 {
+		entryType = entryType[index+1:]
+	}
+
+	var owner string
+	if ownerElement := element.SelectElement("owner"); ownerElement != nil {
+		owner = strings.TrimSpace(ownerElement.Text())
+	}
+
+	rdata := element.SelectElement("rdata")
+	if rdata == nil {
+		return "", "", fmt.Errorf("dnsentry without rdata")
+	}
+	values := func(tags ...string) []string {
+		var result []string
+		for _, child := range rdata.ChildElements() {
+			for _, tag := range tags {
+				if child.Tag == tag {
+					result = append(result, strings.TrimSpace(child.Text()))
+				}
+			}
+		}
+		return result
+	}
+
+	switch strings.ToUpper(entryType) {
+	case "NS":
+		return QueryFieldNameNameServer, strings.Join(values("nameserver", "address", "addressV6"), " "), nil
+	case "A":
+		return QueryFieldNameNSEntry, strings.Join(append([]string{owner, "IN", "A"}, values("address")...), " "), nil
+	case "AAAA":
+		return QueryFieldNameNSEntry, strings.Join(append([]string{owner, "IN", "AAAA"}, values("addressV6")...), " "), nil
+	case "DNSKEY":
+		return QueryFieldNameDNSKey, strings.Join(values("flags", "protocol", "algorithm", "publicKey"), " "), nil
+	default:
+		return "", "", fmt.Errorf("unsupported dnsentry type %q", entryType)
+	}
+}
+
+// splitQueryEntities separates fields following an entity marker like [VerificationInformation] from the remaining fields.
+func splitQueryEntities(fields QueryFieldList) (QueryFieldList, []*kvSection) {
+	mainFields := NewQueryFieldList()
+	var sections []*kvSection
+	for _, f := range fields {
+		if f.Name == QueryFieldNameEntity {
+			sections = append(sections, &kvSection{header: strings.Trim(f.Value, "[]")})
+			continue
+		}
+		if len(sections) > 0 {
+			sections[len(sections)-1].fields.Add(f.Name, f.Value)
+		} else {
+			mainFields.Add(f.Name, f.Value)
+		}
+	}
+	return mainFields, sections
+}
+
+// xmlQueryEncoder builds the XML representation of a query and keeps track of the used namespaces.
+type xmlQueryEncoder struct {
+	doc        *etree.Document
+	root       *etree.Element
+	namespaces map[string]bool
+}
+
+func (e *xmlQueryEncoder) createElement(parent *etree.Element, namespace, tag, text string) *etree.Element {
+	if len(namespace) > 0 {
+		e.namespaces[namespace] = true
+		tag = namespace + ":" + tag
+	}
+	element := parent.CreateElement(tag)
+	if len(text) > 0 {
+		element.SetText(text)
+	}
+	return element
+}
+
+func (e *xmlQueryEncoder) createDNSEntry(parent *etree.Element, entryType, owner string) *etree.Element {
+	e.namespaces["xsi"] = true
+	entry := e.createElement(parent, "dnsentry", "dnsentry", "")
+	entry.CreateAttr("xsi:type", "dnsentry:"+entryType)
+	e.createElement(entry, "dnsentry", "owner", owner)
+	return e.createElement(entry, "dnsentry", "rdata", "")
+}
+
+// EncodeXML returns the XML representation of the query as used for RRI communication.
+func (q *Query) EncodeXML() (string, error) {
+	action := q.Action()
+	actionTag, ok := xmlActionTags[action]
+	if !ok {
+		return "", fmt.Errorf("unknown action '%s'", action)
+	}
+
+	fields, sections := splitQueryEntities(q.fields)
+	sections = append(sections, q.sections...)
+
+	var objectType string
+	switch {
+	case action == ActionLogin || action == ActionLogout:
+		objectType = ""
+	case action == ActionQueueRead || action == ActionQueueDelete:
+		objectType = "msg"
+	case len(fields.Values(QueryFieldNameDomainIDN)) > 0:
+		objectType = "domain"
+	case len(fields.Values(QueryFieldNameRegAcc)) > 0:
+		objectType = "regacc"
+	case len(fields.Values(QueryFieldNameHandle)) > 0:
+		objectType = "contact"
+	default:
+		return "", fmt.Errorf("cannot determine object type of %s query", action)
+	}
+
+	version := q.Version()
+	if len(version) == 0 {
+		version = LatestVersion
+	}
+
+	e := &xmlQueryEncoder{doc: etree.NewDocument(), namespaces: make(map[string]bool)}
+	e.doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8" standalone="yes"`)
+	e.root = e.doc.CreateElement("registry-request")
+	e.root.CreateAttr("xmlns", fmt.Sprintf("%sglobal/%s", xmlNamespaceBase, version))
+
+	actionElement := e.createElement(e.root, objectType, actionTag, "")
+	handled := map[QueryFieldName]bool{QueryFieldNameVersion: true, QueryFieldNameAction: true, QueryFieldNameCTID: true, QueryFieldNameDomainACE: true}
+
+	add := func(fieldName QueryFieldName, f func(value string) error) error {
+		handled[fieldName] = true
+		for _, value := range fields.Values(fieldName) {
+			if err := f(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	addElement := func(fieldName QueryFieldName, parent *etree.Element) error {
+		return add(fieldName, func(value string) error {
+			e.createElement(parent, objectType, xmlTagName(fieldName), value)
+			return nil
+		})
+	}
+	addAttr := func(fieldName QueryFieldName) error {
+		return add(fieldName, func(value string) error {
+			actionElement.CreateAttr(xmlTagName(fieldName), value)
+			return nil
+		})
+	}
+
+	var err error
+	switch objectType {
+	case "domain":
+		err = e.encodeDomainFields(actionElement, fields, add, addAttr)
+	case "contact":
+		err = e.encodeContactFields(actionElement, fields, addElement)
+	case "regacc":
+		err = add(QueryFieldNameRegAcc, func(value string) error {
+			e.createElement(actionElement, objectType, "handle", value)
+			return nil
+		})
+	case "msg":
+		for _, fieldName := range []QueryFieldName{QueryFieldNameMsgID, QueryFieldNameMsgType} {
+			if err = addAttr(fieldName); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// fields without special mapping are encoded as elements of the same name
+	for _, f := range fields {
+		if !handled[f.Name.Normalize()] {
+			e.createElement(actionElement, objectType, xmlTagName(f.Name), f.Value)
+		}
+	}
+
+	for _, section := range sections {
+		if !strings.EqualFold(section.header, string(QueryEntityVerificationInformation)) {
+			return "", fmt.Errorf("unsupported section [%s]", section.header)
+		}
+		e.encodeVerificationInformation(actionElement, section.fields)
+	}
+
+	if ctid := fields.FirstValue(QueryFieldNameCTID); len(ctid) > 0 {
+		e.root.CreateElement("ctid").SetText(ctid)
+	}
+
+	for _, namespace := range []string{"domain", "contact", "dnsentry", "verification", "msg", "regacc"} {
+		if e.namespaces[namespace] {
+			e.root.CreateAttr("xmlns:"+namespace, fmt.Sprintf("%s%s/%s", xmlNamespaceBase, namespace, version))
+		}
+	}
+	if e.namespaces["xsi"] {
+		e.root.CreateAttr("xmlns:xsi", xmlSchemaInstanceNamespace)
+	}
+
+	e.doc.Indent(2)
+	return e.doc.WriteToString()
+}
+
+func (e *xmlQueryEncoder) encodeDomainFields(actionElement *etree.Element, fields QueryFieldList, add func(QueryFieldName, func(string) error) error, addAttr func(QueryFieldName) error) error {
+	domain := fields.FirstValue(QueryFieldNameDomainIDN)
+
+	if err := add(QueryFieldNameDomainIDN, func(value string) error {
+		e.createElement(actionElement, "domain", "handle", value)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, role := range []QueryFieldName{QueryFieldNameHolder, QueryFieldNameAbuseContact, QueryFieldNameGeneralRequest} {
+		if err := add(role, func(value string) error {
+			e.createElement(actionElement, "domain", "contact", value).CreateAttr("role", string(role))
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := add(QueryFieldNameNameServer, func(value string) error {
+		parts := strings.Fields(value)
+		if len(parts) == 0 {
+			return fmt.Errorf("empty %s", QueryFieldNameNameServer)
+		}
+		rdata := e.createDNSEntry(actionElement, "NS", domain)
+		e.createElement(rdata, "dnsentry", "nameserver", parts[0])
+		for _, address := range parts[1:] {
+			if ip := net.ParseIP(address); ip != nil && ip.To4() == nil {
+				e.createElement(rdata, "dnsentry", "addressV6", address)
+			} else {
+				e.createElement(rdata, "dnsentry", "address", address)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := add(QueryFieldNameNSEntry, func(value string) error {
+		parts := strings.Fields(value)
+		index := -1
+		for i, part := range parts {
+			if strings.EqualFold(part, "IN") {
+				index = i
+				break
+			}
+		}
+		if index < 1 || len(parts) < index+3 {
+			return fmt.Errorf("invalid %s %q", QueryFieldNameNSEntry, value)
+		}
+
+		entryType := strings.ToUpper(parts[index+1])
+		tag := map[string]string{"A": "address", "AAAA": "addressV6", "NS": "nameserver"}[entryType]
+		if len(tag) == 0 {
+			return fmt.Errorf("unsupported %s type %q", QueryFieldNameNSEntry, entryType)
+		}
+		rdata := e.createDNSEntry(actionElement, entryType, parts[0])
+		for _, data := range parts[index+2:] {
+			e.createElement(rdata, "dnsentry", tag, data)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := add(QueryFieldNameAuthInfo, func(value string) error {
+		e.createElement(actionElement, "domain", xmlTagName(QueryFieldNameAuthInfo), value)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := add(QueryFieldNameDNSKey, func(value string) error {
+		parts := strings.Fields(value)
+		if len(parts) < 4 {
+			return fmt.Errorf("invalid %s %q", QueryFieldNameDNSKey, value)
+		}
+		rdata := e.createDNSEntry(actionElement, "DNSKEY", strings.TrimSuffix(domain, ".")+".")
+		e.createElement(rdata, "dnsentry", "flags", parts[0])
+		e.createElement(rdata, "dnsentry", "protocol", parts[1])
+		e.createElement(rdata, "dnsentry", "algorithm", parts[2])
+		e.createElement(rdata, "dnsentry", "publicKey", strings.Join(parts[3:], ""))
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, fieldName := range []QueryFieldName{QueryFieldNameAuthInfoHash, QueryFieldNameAuthInfoExpire, QueryFieldNameRecursive, QueryFieldNameDisconnect} {
+		if err := addAttr(fieldName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *xmlQueryEncoder) encodeContactFields(actionElement *etree.Element, fields QueryFieldList, addElement func(QueryFieldName, *etree.Element) error) error {
+	for _, fieldName := range []QueryFieldName{QueryFieldNameHandle, QueryFieldNameType, QueryFieldNameName, QueryFieldNameOrganisation} {
+		if err := addElement(fieldName, actionElement); err != nil {
+			return err
+		}
+	}
+
+	postalFields := []QueryFieldName{QueryFieldNameAddress, QueryFieldNamePostalCode, QueryFieldNameCity, QueryFieldNameCountryCode}
+	hasPostal := false
+	for _, fieldName := range postalFields {
+		hasPostal = hasPostal || len(fields.Values(fieldName)) > 0
+	}
+	if hasPostal {
+		postal := e.createElement(actionElement, "contact", "postal", "")
+		for _, fieldName := range postalFields {
+			if err := addElement(fieldName, postal); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, fieldName := range []QueryFieldName{QueryFieldNameEMail, QueryFieldNamePhone} {
+		if err := addElement(fieldName, actionElement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *xmlQueryEncoder) encodeVerificationInformation(actionElement *etree.Element, fields QueryFieldList) {
+	e.namespaces["xsi"] = true
+	info := e.createElement(actionElement, "verification", "verificationInformation", "")
+	info.CreateAttr("xsi:type", "verification:verificationInformationType")
+
+	if claims := fields.Values(QueryFieldNameVerifiedClaim); len(claims) > 0 {
+		claimsElement := e.createElement(info, "verification", "verifiedClaims", "")
+		for _, claim := range claims {
+			e.createElement(claimsElement, "verification", "claim", claim)
+		}
+	}
+
+	for _, f := range fields {
+		if f.Name.Normalize() != QueryFieldNameVerifiedClaim {
+			e.createElement(info, "verification", xmlTagName(f.Name), f.Value)
+		}
+	}
+}
+
+// ConvertQuery parses a query in KV or XML format and returns its representation in the given format.
+func ConvertQuery(query string, to Format) (string, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return "", err
+	}
+
+	switch to {
+	case FormatKV:
+		return q.EncodeKV(), nil
+	case FormatXML:
+		return q.EncodeXML()
+	default:
+		return "", fmt.Errorf("unsupported format %q", to)
+	}
+}
+
+// EncodeXML returns the XML representation of the response. Fields are written as elements of a data element, entities as contact elements with the entity name as role.
+func (r *Response) EncodeXML() (string, error) {
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8" standalone="yes"`)
+	root := doc.CreateElement("registry-response")
+	root.CreateAttr("xmlns", fmt.Sprintf("%sglobal/%s", xmlNamespaceBase, LatestVersion))
+	root.CreateAttr("xmlns:tr", fmt.Sprintf("%stransaction/%s", xmlNamespaceBase, LatestVersion))
+
+	transaction := root.CreateElement("tr:transaction")
+	transaction.CreateElement("tr:stid").SetText(r.STID())
+	transaction.CreateElement("tr:result").SetText(string(r.Result()))
+
+	for _, level := range []struct {
+		name     string
+		messages []BusinessMessage
+	}{{"info", r.InfoMessages()}, {"warning", r.WarningMessages()}, {"error", r.ErrorMessages()}} {
+		for _, msg := range level.messages {
+			msgElement := transaction.CreateElement("tr:message")
+			msgElement.CreateAttr("level", level.name)
+			msgElement.CreateElement("tr:code").SetText(fmt.Sprintf("%d", msg.ID()))
+			msgElement.CreateElement("tr:text").SetText(msg.Message())
+		}
+	}
+
+	data := transaction.CreateElement("tr:data")
+	var container *etree.Element
+	for _, f := range r.fields {
+		switch f.Name.Normalize() {
+		case ResponseFieldNameResult.Normalize(), ResponseFieldNameSTID.Normalize(), ResponseFieldNameInfo.Normalize(), ResponseFieldNameWarning.Normalize(), ResponseFieldNameError.Normalize():
+			continue
+		}
+		if container == nil {
+			container = data.CreateElement("result")
+		}
+		container.CreateElement(xmlTagName(QueryFieldName(f.Name))).SetText(f.Value)
+	}
+
+	for _, e := range r.entities {
+		if container == nil {
+			container = data.CreateElement("result")
+		}
+
+		if e.name.Normalize() == ResponseEntityName(QueryEntityVerificationInformation).Normalize() {
+			info := container.CreateElement(string(QueryEntityVerificationInformation))
+			if claims := e.fields.Values(ResponseFieldName(QueryFieldNameVerifiedClaim)); len(claims) > 0 {
+				claimsElement := info.CreateElement("verifiedClaims")
+				for _, claim := range claims {
+					claimsElement.CreateElement("claim").SetText(claim)
+				}
+			}
+			for _, f := range e.fields {
+				if QueryFieldName(f.Name).Normalize() != QueryFieldNameVerifiedClaim {
+					info.CreateElement(xmlTagName(QueryFieldName(f.Name))).SetText(f.Value)
+				}
+			}
+			continue
+		}
+
+		contact := container.CreateElement("contact")
+		contact.CreateAttr("role", string(e.name.Normalize()))
+		for _, f := range e.fields {
+			contact.CreateElement(xmlTagName(QueryFieldName(f.Name))).SetText(f.Value)
+		}
+	}
+
+	doc.Indent(2)
+	return doc.WriteToString()
+}
+
+// ConvertResponse parses a response in KV or XML format and returns its representation in the given format.
+func ConvertResponse(response string, to Format) (string, error) {
+	r, err := ParseResponse(response)
+	if err != nil {
+		return "", err
+	}
+
+	switch to {
+	case FormatKV:
+		return r.EncodeKV(), nil
+	case FormatXML:
+		return r.EncodeXML()
+	default:
+		return "", fmt.Errorf("unsupported format %q", to)
+	}
+}
@@ -0,0 +1,115 @@
+package rri_test
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// normalizedLines returns the sorted lines of the KV representation with lower case keys.
+func normalizedLines(q *rri.Query) []string {
+	var lines []string
+	for _, line := range strings.Split(q.EncodeKV(), "\n") {
+		if parts := strings.SplitN(line, ":", 2); len(parts) == 2 {
+			key, value := strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+			if key == string(rri.QueryFieldNameAction) {
+				value = strings.ToLower(value)
+			}
+			line = key + ": " + value
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestParseQueryXMLExamples(t *testing.T) {
+	files, err := filepath.Glob("../../examples/xml/*/*")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+
+			query, err := rri.ParseQuery(string(data))
+			require.NoError(t, err)
+			assert.True(t, query.Action().IsKnown())
+
+			xml, err := query.EncodeXML()
+			require.NoError(t, err)
+			reparsed, err := rri.ParseQueryXML(xml)
+			require.NoError(t, err)
+			assert.Equal(t, normalizedLines(query), normalizedLines(reparsed))
+		})
+	}
+}
+
+func TestConvertQueryKVExamples(t *testing.T) {
+	files, err := filepath.Glob("../../examples/kv/*/*")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+			query, err := rri.ParseQueryKV(string(data))
+			require.NoError(t, err)
+
+			xml, err := rri.ConvertQuery(string(data), rri.FormatXML)
+			require.NoError(t, err)
+			assert.Equal(t, rri.FormatXML, rri.DetectFormat(xml))
+
+			kv, err := rri.ConvertQuery(xml, rri.FormatKV)
+			require.NoError(t, err)
+			converted, err := rri.ParseQueryKV(kv)
+			require.NoError(t, err)
+			assert.Equal(t, normalizedLines(query), normalizedLines(converted))
+		})
+	}
+}
+
+func TestConvertResponse(t *testing.T) {
+	kv := `RESULT: success
+STID: 3d7e0e9a-5dd1-11ef-b5c2-005056a6b28d
+INFO: 1000 Command completed successfully
+Domain: de-example.de
+Holder: DENIC-1000002-MAX
+
+[HOLDER]
+Handle: DENIC-1000002-MAX
+Name: Max Mustermann
+
+[VERIFICATIONINFORMATION]
+VerifiedClaim: name
+VerificationResult: success`
+
+	xml, err := rri.ConvertResponse(kv, rri.FormatXML)
+	require.NoError(t, err)
+	assert.Contains(t, xml, `<contact role="holder">`)
+
+	response, err := rri.ParseResponseXML(xml)
+	require.NoError(t, err)
+	assert.True(t, response.IsSuccessful())
+	assert.Equal(t, "3d7e0e9a-5dd1-11ef-b5c2-005056a6b28d", response.STID())
+	assert.Equal(t, []rri.BusinessMessage{rri.NewBusinessMessage(1000, "Command completed successfully")}, response.InfoMessages())
+	assert.Equal(t, "de-example.de", response.FirstField("domain"))
+	assert.Equal(t, []string{"DENIC-1000002-MAX"}, response.Field("holder"))
+	require.Len(t, response.Entities(), 2)
+	assert.Equal(t, "Max Mustermann", response.Entities()[0].FirstField("name"))
+	assert.Equal(t, "name", response.Entities()[1].FirstField("verifiedclaim"))
+
+	converted, err := rri.ConvertResponse(xml, rri.FormatKV)
+	require.NoError(t, err)
+	assert.Contains(t, converted, "[HOLDER]")
+	assert.Contains(t, converted, "[VERIFICATIONINFORMATION]")
+}
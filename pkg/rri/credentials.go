@@ -0,0 +1,117 @@
+package rri
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// CredentialProvider supplies login credentials on demand. It is asked for every login, including automatic session restoration after a lost connection.
+type CredentialProvider interface {
+	Credentials() (user, password string, err error)
+}
+
+// CredentialProviderFunc maps a function to the CredentialProvider interface.
+type CredentialProviderFunc func() (user, password string, err error)
+
+// Credentials calls f.
+func (f CredentialProviderFunc) Credentials() (string, string, error) {
+	return f()
+}
+
+// StaticCredentials returns a CredentialProvider for fixed credentials. The password is retained in memory for the lifetime of the provider.
+func StaticCredentials(user, password string) CredentialProvider {
+	return CredentialProviderFunc(func() (string, string, error) {
+		return user, password, nil
+	})
+}
+
+// EnvCredentials returns a CredentialProvider that reads the password from the environment variable passwordVar on every login.
+func EnvCredentials(user, passwordVar string) CredentialProvider {
+	return CredentialProviderFunc(func() (string, string, error) {
+		password, ok := os.LookupEnv(passwordVar)
+		if !ok {
+			return "", "", fmt.Errorf("environment variable %q is not set", passwordVar)
+		}
+
+		return user, password, nil
+	})
+}
+
+// FileDescriptorCredentials returns a CredentialProvider that reads the password from an open file descriptor, for example passed by a parent process. The provider takes ownership of the file descriptor.
+//
+// Seekable descriptors are read again for every login. Pipes can only be consumed once, so their content is kept in memory to allow session restoration.
+func FileDescriptorCredentials(user string, fd uintptr) CredentialProvider {
+	file := os.NewFile(fd, fmt.Sprintf("fd%d", fd))
+
+	var (
+		mutex    sync.Mutex
+		consumed bool
+		cached   string
+	)
+
+	return CredentialProviderFunc(func() (string, string, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if file == nil {
+			return "", "", fmt.Errorf("invalid file descriptor %d", fd)
+		}
+
+		seekable := true
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			seekable = false
+		}
+
+		if consumed && !seekable {
+			return user, cached, nil
+		}
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read password from file descriptor %d: %s", fd, err.Error())
+		}
+
+		password := firstLine(data)
+		consumed = true
+		if !seekable {
+			cached = password
+		}
+
+		return user, password, nil
+	})
+}
+
+// CommandCredentials returns a CredentialProvider that executes an external helper command on every login. The first line written to stdout is used as password.
+func CommandCredentials(user, name string, args ...string) CredentialProvider {
+	return CredentialProviderFunc(func() (string, string, error) {
+		cmd := exec.Command(name, args...)
+		// allow helpers like pinentry to interact with the user
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = os.Stderr
+
+		out, err := cmd.Output()
+		if err != nil {
+			return "", "", fmt.Errorf("credential helper %q failed: %s", name, err.Error())
+		}
+
+		password := firstLine(out)
+		if len(password) == 0 {
+			return "", "", fmt.Errorf("credential helper %q returned an empty password", name)
+		}
+
+		return user, password, nil
+	})
+}
+
+func firstLine(data []byte) string {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+
+	return strings.TrimRight(string(data), "\r")
+}
@@ -0,0 +1,114 @@
+package rri_test
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticCredentials(t *testing.T) {
+	user, pass, err := rri.StaticCredentials("DENIC-1000011-TEST", "secret").Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, "DENIC-1000011-TEST", user)
+	assert.Equal(t, "secret", pass)
+}
+
+func TestEnvCredentials(t *testing.T) {
+	provider := rri.EnvCredentials("DENIC-1000011-TEST", "RRI_TEST_PASSWORD")
+
+	t.Setenv("RRI_TEST_PASSWORD", "secret")
+	user, pass, err := provider.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, "DENIC-1000011-TEST", user)
+	assert.Equal(t, "secret", pass)
+
+	os.Unsetenv("RRI_TEST_PASSWORD")
+	_, _, err = provider.Credentials()
+	assert.Error(t, err)
+}
+
+func TestCommandCredentials(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	user, pass, err := rri.CommandCredentials("DENIC-1000011-TEST", "sh", "-c", "printf 'secret\\nfoo'").Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, "DENIC-1000011-TEST", user)
+	assert.Equal(t, "secret", pass)
+
+	_, _, err = rri.CommandCredentials("DENIC-1000011-TEST", "sh", "-c", "exit 1").Credentials()
+	assert.Error(t, err)
+}
+
+func TestClientLoginWithRestoresSession(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true, IdleTimeout: 40 * time.Millisecond})
+		require.NoError(t, err)
+		defer client.Close()
+
+		requests := 0
+		require.NoError(t, client.LoginWith(rri.CredentialProviderFunc(func() (string, string, error) {
+			requests++
+			return "DENIC-1000011-TEST", "secret", nil
+		})))
+		assert.Equal(t, 1, requests)
+
+		// wait for idle timeout to force a new login
+		time.Sleep(150 * time.Millisecond)
+		response, err := client.SendQuery(rri.NewInfoDomainQuery("denic.de"))
+		require.NoError(t, err)
+		assert.True(t, response.IsSuccessful())
+		assert.Equal(t, 2, requests)
+	})
+}
+
+func TestClientLoginQueryDoesNotRetainPassword(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true})
+		require.NoError(t, err)
+		defer client.Close()
+
+		response, err := client.SendQuery(rri.NewLoginQuery("DENIC-1000011-TEST", "secret"))
+		require.NoError(t, err)
+		require.True(t, response.IsSuccessful())
+		assert.True(t, client.IsLoggedIn())
+
+		_, err = client.NewSession()
+		assert.EqualError(t, err, "credentials of the current session are unknown")
+	})
+}
+
+func TestClientLoginQueryIdleTimeout(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true, IdleTimeout: 20 * time.Millisecond})
+		require.NoError(t, err)
+		defer client.Close()
+
+		response, err := client.SendQuery(rri.NewLoginQuery("DENIC-1000011-TEST", "secret"))
+		require.NoError(t, err)
+		require.True(t, response.IsSuccessful())
+
+		// the session cannot be restored without the password, so the query is not sent unauthenticated
+		time.Sleep(100 * time.Millisecond)
+		_, err = client.SendQuery(rri.NewInfoDomainQuery("denic.de"))
+		assert.ErrorIs(t, err, rri.ErrSessionExpired)
+		assert.False(t, client.IsLoggedIn())
+
+		require.NoError(t, client.Login("DENIC-1000011-TEST", "secret"))
+		response, err = client.SendQuery(rri.NewInfoDomainQuery("denic.de"))
+		require.NoError(t, err)
+		assert.True(t, response.IsSuccessful())
+	})
+}
@@ -0,0 +1,52 @@
+//go:build unix
+
+package rri_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dupFD returns a duplicate of the file descriptor of file to pass ownership to FileDescriptorCredentials.
+func dupFD(t *testing.T, file *os.File) uintptr {
+	fd, err := syscall.Dup(int(file.Fd()))
+	require.NoError(t, err)
+	return uintptr(fd)
+}
+
+func TestFileDescriptorCredentialsFile(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "pass")
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.WriteString("secret\nignored")
+	require.NoError(t, err)
+
+	provider := rri.FileDescriptorCredentials("DENIC-1000011-TEST", dupFD(t, file))
+	for i := 0; i < 2; i++ {
+		user, pass, err := provider.Credentials()
+		require.NoError(t, err)
+		assert.Equal(t, "DENIC-1000011-TEST", user)
+		assert.Equal(t, "secret", pass)
+	}
+}
+
+func TestFileDescriptorCredentialsPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	_, err = w.WriteString("secret\r\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	provider := rri.FileDescriptorCredentials("DENIC-1000011-TEST", dupFD(t, r))
+	for i := 0; i < 2; i++ {
+		_, pass, err := provider.Credentials()
+		require.NoError(t, err)
+		assert.Equal(t, "secret", pass)
+	}
+}
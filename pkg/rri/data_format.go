@@ -0,0 +1,104 @@
+package rri
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DataFormat denotes a document format to serialize ContactData and DomainData.
+type DataFormat string
+
+const (
+	DataFormatJSON DataFormat = "json"
+	DataFormatYAML DataFormat = "yaml"
+)
+
+// ParseDataFormat parses a data format from string.
+func ParseDataFormat(str string) (DataFormat, error) {
+	switch strings.ToLower(str) {
+	case "json":
+		return DataFormatJSON, nil
+	case "yaml", "yml":
+		return DataFormatYAML, nil
+	default:
+		return "", fmt.Errorf("invalid data format")
+	}
+}
+
+// DataFormatFromFileName returns the data format denoted by the extension of fileName.
+func DataFormatFromFileName(fileName string) (DataFormat, bool) {
+	format, err := ParseDataFormat(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	if err != nil {
+		return "", false
+	}
+	return format, true
+}
+
+// DetectDataFormat returns DataFormatJSON for documents starting with a JSON object and DataFormatYAML otherwise.
+func DetectDataFormat(data []byte) DataFormat {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return DataFormatJSON
+	}
+	return DataFormatYAML
+}
+
+// Marshal serializes v in the given format.
+func (f DataFormat) Marshal(v any) ([]byte, error) {
+	switch f {
+	case DataFormatJSON:
+		return json.MarshalIndent(v, "", "  ")
+	case DataFormatYAML:
+		return yaml.Marshal(v)
+	default:
+		return nil, fmt.Errorf("unsupported data format %q", f)
+	}
+}
+
+// Unmarshal parses data in the given format into v. Unknown fields are rejected to detect typos early.
+func (f DataFormat) Unmarshal(data []byte, v any) error {
+	switch f {
+	case DataFormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(v)
+	case DataFormatYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(v); err != nil && err != io.EOF {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported data format %q", f)
+	}
+}
+
+// ParseContactData parses a ContactData document in the given format.
+func ParseContactData(data []byte, format DataFormat) (ContactData, error) {
+	var contactData ContactData
+	if err := format.Unmarshal(data, &contactData); err != nil {
+		return ContactData{}, err
+	}
+
+	if len(contactData.Type) == 0 {
+		return ContactData{}, fmt.Errorf("missing contact type")
+	}
+
+	return contactData, nil
+}
+
+// ParseDomainData parses a DomainData document in the given format.
+func ParseDomainData(data []byte, format DataFormat) (DomainData, error) {
+	var domainData DomainData
+	if err := format.Unmarshal(data, &domainData); err != nil {
+		return DomainData{}, err
+	}
+
+	return domainData, nil
+}
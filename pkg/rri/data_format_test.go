@@ -0,0 +1,70 @@
+package rri_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContactDataYAML(t *testing.T) {
+	contactData, err := rri.ParseContactData([]byte(`type: person
+name: Max Mustermann
+address: Kaiserstraße 75-77
+postalCode: "60329"
+city: Frankfurt am Main
+countryCode: DE
+email:
+  - max@example.de
+verificationInformation:
+  - verificationTimestamp: 2024-12-11T10:00:00+01:00
+    verificationResult: Success
+    verificationEvidence: idcard
+    verificationMethod: electronic_document
+    trustFramework: de_denic
+    verifiedClaim: [name, address]
+`), rri.DataFormatYAML)
+	require.NoError(t, err)
+	assert.Equal(t, rri.ContactTypePerson, contactData.Type)
+	assert.Equal(t, "60329", contactData.PostalCode)
+	assert.Equal(t, []string{"max@example.de"}, contactData.EMail)
+	require.Len(t, contactData.VerificationInformation, 1)
+	info := contactData.VerificationInformation[0]
+	assert.Equal(t, rri.VerificationResultSuccess, info.VerificationResult)
+	assert.Equal(t, []rri.VerificationClaim{rri.VerificationClaimName, rri.VerificationClaimAddress}, info.VerifiedClaim)
+	assert.True(t, time.Date(2024, 12, 11, 9, 0, 0, 0, time.UTC).Equal(info.VerificationTimestamp))
+}
+
+func TestParseContactDataInvalid(t *testing.T) {
+	_, err := rri.ParseContactData([]byte(`{"type": "alien", "name": "foo"}`), rri.DataFormatJSON)
+	assert.Error(t, err)
+
+	_, err = rri.ParseContactData([]byte(`{"type": "person", "nmae": "foo"}`), rri.DataFormatJSON)
+	assert.Error(t, err)
+
+	_, err = rri.ParseContactData([]byte(`name: foo`), rri.DataFormatYAML)
+	assert.Error(t, err)
+}
+
+func TestDomainDataRoundTrip(t *testing.T) {
+	domainData := rri.DomainData{
+		HolderHandles:         []rri.DenicHandle{rri.NewDenicHandle(1000001, "holder")},
+		GeneralRequestHandles: []rri.DenicHandle{rri.NewDenicHandle(1000001, "general")},
+		AbuseContactHandles:   []rri.DenicHandle{rri.NewDenicHandle(1000001, "abuse")},
+		NameServers:           []string{"ns1.denic.de", "ns2.denic.de"},
+	}
+
+	for _, format := range []rri.DataFormat{rri.DataFormatJSON, rri.DataFormatYAML} {
+		data, err := format.Marshal(domainData)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "DENIC-1000001-HOLDER")
+		assert.Equal(t, format, rri.DetectDataFormat(data))
+
+		parsed, err := rri.ParseDomainData(data, format)
+		require.NoError(t, err)
+		assert.Equal(t, domainData, parsed)
+	}
+}
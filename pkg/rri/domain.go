@@ -0,0 +1,142 @@
+package rri
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// maxDomainLabelLength denotes the maximum length of a label in ACE form.
+	maxDomainLabelLength = 63
+	// denicIDNCharacters contains all characters besides a-z, 0-9 and hyphen permitted by DENIC. The characters ŀ and ŉ of the original table are disallowed by IDNA2008.
+	denicIDNCharacters = "ßàáâãäåæçèéêëìíîïðñòóôõöøùúûüýþÿāăąćĉċčďđēĕėęěĝğġģĥħĩīĭįıĵķĸĺļľłńņňŋōŏőœŕŗřśŝşšţťŧũūŭůűųŵŷźżž"
+)
+
+// Domain is a .de domain name that is permitted by DENIC.
+type Domain struct {
+	idn string
+	ace string
+}
+
+// ParseDomain parses a domain name in IDN or ACE form.
+//
+// The name is converted to lower case and NFC and validated with the IDNA2008 registration profile. Only second level .de names are accepted. Their label may only contain a-z, 0-9, hyphens and the characters of the DENIC IDN table, must neither start nor end with a hyphen, must not contain hyphens at the third and fourth position and may be at most 63 characters long in ACE form.
+func ParseDomain(str string) (Domain, error) {
+	name := strings.TrimSuffix(strings.TrimSpace(str), ".")
+	if len(name) == 0 {
+		return Domain{}, fmt.Errorf("empty domain name")
+	}
+
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 || !strings.EqualFold(labels[len(labels)-1], "de") {
+		return Domain{}, fmt.Errorf("%q is not a .de domain name", str)
+	}
+	if len(labels) > 2 {
+		return Domain{}, fmt.Errorf("%q is not a second level domain, only names like example.de can be registered", str)
+	}
+
+	// only case and composition are normalized, compatibility mappings of lookups would silently change the registered name
+	name = norm.NFC.String(strings.ToLower(name))
+	label := strings.Split(name, ".")[0]
+
+	idn := name
+	if strings.HasPrefix(label, "xn--") {
+		var err error
+		if idn, err = idna.Registration.ToUnicode(name); err != nil {
+			return Domain{}, fmt.Errorf("%q is not a valid ACE name: %s", str, strings.TrimPrefix(err.Error(), "idna: "))
+		}
+		label = strings.Split(idn, ".")[0]
+	}
+
+	if err := validateDomainLabel(str, label); err != nil {
+		return Domain{}, err
+	}
+
+	// the length is checked before validation, which only reports an invalid label
+	if ace, err := idna.Punycode.ToASCII(idn); err == nil {
+		if length := len(strings.Split(ace, ".")[0]); length > maxDomainLabelLength {
+			return Domain{}, fmt.Errorf("%q is too long, the label has %d characters in ACE form but at most %d are allowed", str, length, maxDomainLabelLength)
+		}
+	}
+
+	ace, err := idna.Registration.ToASCII(idn)
+	if err != nil {
+		return Domain{}, fmt.Errorf("%q is not a valid IDN: %s", str, strings.TrimPrefix(err.Error(), "idna: "))
+	}
+
+	return Domain{idn: idn, ace: ace}, nil
+}
+
+// MustParseDomain is like ParseDomain but panics if the domain name is invalid.
+func MustParseDomain(str string) Domain {
+	domain, err := ParseDomain(str)
+	if err != nil {
+		panic(err)
+	}
+	return domain
+}
+
+// validateDomainLabel checks label for characters and hyphens not permitted by DENIC.
+func validateDomainLabel(str, label string) error {
+	if len(label) == 0 {
+		return fmt.Errorf("%q has an empty label", str)
+	}
+
+	for i, r := range []rune(label) {
+		if !isDenicDomainRune(r) {
+			return fmt.Errorf("%q contains %q (U+%04X) at position %d, which is not permitted by DENIC", str, r, r, i+1)
+		}
+	}
+
+	if strings.HasPrefix(label, "-") {
+		return fmt.Errorf("%q must not start with a hyphen", str)
+	}
+	if strings.HasSuffix(label, "-") {
+		return fmt.Errorf("%q must not end with a hyphen", str)
+	}
+	// ACE labels are decoded before, so this only matches names that look like other encodings
+	if utf8.RuneCountInString(label) >= 4 && string([]rune(label)[2:4]) == "--" {
+		return fmt.Errorf("%q must not contain hyphens at the third and fourth position", str)
+	}
+
+	return nil
+}
+
+func isDenicDomainRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || strings.ContainsRune(denicIDNCharacters, r)
+}
+
+// IDN returns the domain name in Unicode form like dönic.de.
+func (d Domain) IDN() string {
+	return d.idn
+}
+
+// ACE returns the domain name in ASCII compatible encoding like xn--dnic-5qa.de.
+func (d Domain) ACE() string {
+	return d.ace
+}
+
+// IsIDN returns true if the domain name contains characters besides a-z, 0-9 and hyphens.
+func (d Domain) IsIDN() bool {
+	return d.idn != d.ace
+}
+
+// IsEmpty returns true if d is the zero value.
+func (d Domain) IsEmpty() bool {
+	return len(d.ace) == 0
+}
+
+// String returns the domain name in Unicode form.
+func (d Domain) String() string {
+	return d.idn
+}
+
+// PutToQueryFields adds the IDN and ACE domain fields.
+func (d Domain) PutToQueryFields(fields *QueryFieldList) {
+	fields.Add(QueryFieldNameDomainIDN, d.idn)
+	fields.Add(QueryFieldNameDomainACE, d.ace)
+}
@@ -0,0 +1,89 @@
+package rri_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDomain(t *testing.T) {
+	tests := []struct {
+		input string
+		idn   string
+		ace   string
+	}{
+		{"denic.de", "denic.de", "denic.de"},
+		{" DENIC.DE. ", "denic.de", "denic.de"},
+		{"Dönic.de", "dönic.de", "xn--dnic-5qa.de"},
+		{"XN--DNIC-5QA.DE", "dönic.de", "xn--dnic-5qa.de"},
+		{"straße.de", "straße.de", "xn--strae-oqa.de"},
+		// decomposed input is composed to NFC
+		{"A\u0308x.de", "äx.de", "xn--x-zfa.de"},
+		{"x.de", "x.de", "x.de"},
+		{"123.de", "123.de", "123.de"},
+		{strings.Repeat("a", 63) + ".de", strings.Repeat("a", 63) + ".de", strings.Repeat("a", 63) + ".de"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			domain, err := rri.ParseDomain(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.idn, domain.IDN())
+			assert.Equal(t, test.ace, domain.ACE())
+			assert.Equal(t, test.idn != test.ace, domain.IsIDN())
+			assert.True(t, rri.IsDomainName(test.input))
+		})
+	}
+}
+
+func TestParseDomainErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"", "empty domain name"},
+		{"denic.com", `"denic.com" is not a .de domain name`},
+		{"de", `"de" is not a .de domain name`},
+		{"www.denic.de", `"www.denic.de" is not a second level domain, only names like example.de can be registered`},
+		{".de", `".de" has an empty label`},
+		{"bad_name.de", `"bad_name.de" contains '_' (U+005F) at position 4, which is not permitted by DENIC`},
+		{"日本.de", `"日本.de" contains '日' (U+65E5) at position 1, which is not permitted by DENIC`},
+		// compatibility characters are not mapped to other names
+		{"ǆx.de", `"ǆx.de" contains 'ǆ' (U+01C6) at position 1, which is not permitted by DENIC`},
+		{"-denic.de", `"-denic.de" must not start with a hyphen`},
+		{"denic-.de", `"denic-.de" must not end with a hyphen`},
+		{"ab--cd.de", `"ab--cd.de" must not contain hyphens at the third and fourth position`},
+		{"xn--.de", `"xn--.de" is not a valid ACE name: invalid label ""`},
+		{strings.Repeat("a", 64) + ".de", `"` + strings.Repeat("a", 64) + `.de" is too long, the label has 64 characters in ACE form but at most 63 are allowed`},
+		{strings.Repeat("ä", 58) + ".de", `"` + strings.Repeat("ä", 58) + `.de" is too long, the label has 64 characters in ACE form but at most 63 are allowed`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			_, err := rri.ParseDomain(test.input)
+			assert.EqualError(t, err, test.err)
+			assert.False(t, rri.IsDomainName(test.input))
+		})
+	}
+}
+
+func TestDomainQueryFields(t *testing.T) {
+	query := rri.NewInfoDomainQuery("DÖNIC.de")
+	assert.Equal(t, []string{"dönic.de"}, query.Field(rri.QueryFieldNameDomainIDN))
+	assert.Equal(t, []string{"xn--dnic-5qa.de"}, query.Field(rri.QueryFieldNameDomainACE))
+	assert.Empty(t, rri.ValidateQuery(query))
+
+	// invalid names are passed on and reported by ValidateQuery
+	query = rri.NewDeleteDomainQuery("bad_name.de")
+	assert.Equal(t, []string{"bad_name.de"}, query.Field(rri.QueryFieldNameDomainIDN))
+	errs := rri.ValidateQuery(query)
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], `invalid domain field: "bad_name.de" contains '_' (U+005F) at position 4, which is not permitted by DENIC`)
+
+	_, errs = rri.ValidateQueryString("version: 5.0\naction: info\ndomain: dönic.de\ndomain-ace: denic.de")
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], `domain-ace field "denic.de" does not match domain field "dönic.de"`)
+}
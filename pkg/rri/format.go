@@ -0,0 +1,36 @@
+package rri
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Format denotes the syntax of RRI queries and responses.
+type Format string
+
+const (
+	// FormatKV denotes the key-value syntax like "action: info".
+	FormatKV Format = "kv"
+	// FormatXML denotes the XML syntax introduced with RRI 3.0.
+	FormatXML Format = "xml"
+)
+
+// ParseFormat parses a format from string.
+func ParseFormat(str string) (Format, error) {
+	switch strings.ToLower(str) {
+	case "kv":
+		return FormatKV, nil
+	case "xml":
+		return FormatXML, nil
+	default:
+		return "", fmt.Errorf("invalid format")
+	}
+}
+
+// DetectFormat returns FormatXML for messages starting with an XML element or declaration and FormatKV otherwise.
+func DetectFormat(msg string) Format {
+	if strings.HasPrefix(strings.TrimSpace(msg), "<") {
+		return FormatXML
+	}
+	return FormatKV
+}
@@ -144,9 +144,9 @@ func WithMockServer(port int, f func(server *MockServer) error) error {
 		return err
 	}
 
-	var runError error
+	runError := make(chan error, 1)
 	go func() {
-		runError = server.Run()
+		runError <- server.Run()
 	}()
 	result := f(server)
 	server.Close()
@@ -154,7 +154,7 @@ func WithMockServer(port int, f func(server *MockServer) error) error {
 	if result != nil {
 		return result
 	}
-	return runError
+	return <-runError
 }
 
 func MustWithMockServer(f func(server *MockServer)) {
@@ -0,0 +1,483 @@
+package rri
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/idna"
+)
+
+const (
+	defaultDNSPort    = 53
+	defaultDNSTimeout = 5 * time.Second
+	// dnsTypeDNSKEY is not defined by dnsmessage and parsed as UnknownResource.
+	dnsTypeDNSKEY dnsmessage.Type = 48
+)
+
+// DNSResolver resolves name server addresses and sends queries directly to name servers for PredelegationCheck.
+type DNSResolver interface {
+	// LookupHost returns the IP addresses of host.
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	// Exchange sends query to the name server with the given IP address and returns its response.
+	Exchange(ctx context.Context, address string, query dnsmessage.Message) (*dnsmessage.Message, error)
+}
+
+// NetResolver is the default DNSResolver. It resolves host names with the system resolver and queries name servers via UDP, retrying truncated responses via TCP.
+type NetResolver struct {
+	// Resolver is used for LookupHost. Defaults to net.DefaultResolver.
+	Resolver *net.Resolver
+	// Port denotes the port of queried name servers. Defaults to 53.
+	Port int
+	// Timeout denotes the timeout of a single query. Defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+// LookupHost returns the IP addresses of host.
+func (r *NetResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return resolver.LookupHost(ctx, host)
+}
+
+// Exchange sends query to the name server with the given IP address and returns its response.
+func (r *NetResolver) Exchange(ctx context.Context, address string, query dnsmessage.Message) (*dnsmessage.Message, error) {
+	port := r.Port
+	if port <= 0 {
+		port = defaultDNSPort
+	}
+	server := net.JoinHostPort(address, strconv.Itoa(port))
+
+	query.Header.ID = uint16(rand.Uint32())
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.exchange(ctx, "udp", server, packed, query.Header.ID)
+	if err == nil && response.Header.Truncated {
+		response, err = r.exchange(ctx, "tcp", server, packed, query.Header.ID)
+	}
+	return response, err
+}
+
+func (r *NetResolver) exchange(ctx context.Context, network, server string, packed []byte, id uint16) (*dnsmessage.Message, error) {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = defaultDNSTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline) //nolint
+	}
+
+	var data []byte
+	if network == "tcp" {
+		// messages are prefixed with their length on stream connections
+		if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(packed)))); err != nil {
+			return nil, err
+		}
+		if _, err := conn.Write(packed); err != nil {
+			return nil, err
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return nil, err
+		}
+		data = make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, data); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := conn.Write(packed); err != nil {
+			return nil, err
+		}
+		data = make([]byte, 65535)
+		n, err := conn.Read(data)
+		if err != nil {
+			return nil, err
+		}
+		data = data[:n]
+	}
+
+	response := &dnsmessage.Message{}
+	if err := response.Unpack(data); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %s", server, err.Error())
+	}
+	if !response.Header.Response || response.Header.ID != id {
+		return nil, fmt.Errorf("unexpected response from %s", server)
+	}
+	return response, nil
+}
+
+// PredelegationOptions configures PredelegationCheck.
+type PredelegationOptions struct {
+	// Resolver is used for all lookups and queries. Defaults to NetResolver.
+	Resolver DNSResolver
+	// DNSKeys denotes the keys submitted with the query like "257 3 8 AwEAA...". All of them must be served by every name server.
+	DNSKeys []string
+	// DS denotes delegation signer records like "12345 8 2 49FD46E6...". Every record must match a DNSKEY served by the name servers.
+	DS []string
+}
+
+// NameServerCheck holds the answers of a single name server address.
+type NameServerCheck struct {
+	Name    string
+	Address string
+	// Serial denotes the SOA serial of the zone.
+	Serial uint32
+	// NameServers denotes the NS set of the zone in lower case without trailing dot.
+	NameServers []string
+	// DNSKeys denotes the DNSKEY set of the zone like "257 3 8 AwEAA...".
+	DNSKeys []string
+	// Err denotes why the name server could not be checked.
+	Err error
+}
+
+// PredelegationResult holds the outcome of PredelegationCheck.
+type PredelegationResult struct {
+	Domain      string
+	NameServers []NameServerCheck
+	// DS denotes the SHA-256 delegation signer records of the submitted keys, or of the served secure entry point keys if no keys have been submitted.
+	DS []string
+	// Errors denotes problems that will cause the registry to reject the delegation.
+	Errors []string
+	// Warnings denotes inconsistencies that do not prevent the delegation, like differing SOA serials during zone transfers.
+	Warnings []string
+}
+
+// IsSuccessful returns true if no errors have been found.
+func (r *PredelegationResult) IsSuccessful() bool {
+	return len(r.Errors) == 0
+}
+
+func (r *PredelegationResult) addError(format string, args ...any) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+func (r *PredelegationResult) addWarning(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// PredelegationCheck queries the name servers of data before they are sent with CREATE, UPDATE or CHPROV.
+//
+// Every name server address must answer authoritatively with the SOA of the domain and the same NS set as listed in data. Addresses are taken from the glue records of a name server entry or resolved otherwise. All name servers must serve the same DNSKEY set containing the submitted keys and matching the given DS records. An error is only returned for invalid arguments, problems of the name servers are reported in the result.
+func PredelegationCheck(ctx context.Context, domain string, data DomainData, opts PredelegationOptions) (*PredelegationResult, error) {
+	d, err := ParseDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	domain = d.IDN()
+	zone, err := dnsmessage.NewName(d.ACE() + ".")
+	if err != nil {
+		return nil, err
+	}
+
+	submittedKeys := make([][]byte, len(opts.DNSKeys))
+	for i, key := range opts.DNSKeys {
+		if submittedKeys[i], err = parseDNSKey(key); err != nil {
+			return nil, err
+		}
+	}
+	submittedDS := make([]string, len(opts.DS))
+	for i, ds := range opts.DS {
+		if submittedDS[i], err = normalizeDS(ds); err != nil {
+			return nil, err
+		}
+	}
+
+	resolver := opts.Resolver
+	if resolver == nil {
+		resolver = &NetResolver{}
+	}
+
+	result := &PredelegationResult{Domain: domain}
+	expectedNS := make([]string, 0)
+	for _, entry := range data.NameServers {
+		parts := strings.Fields(entry)
+		if len(parts) == 0 {
+			continue
+		}
+		name := normalizeDNSName(parts[0])
+		expectedNS = append(expectedNS, name)
+
+		addresses := parts[1:]
+		if len(addresses) == 0 {
+			if name == d.ACE() || strings.HasSuffix(name, "."+d.ACE()) {
+				result.addError("%s: missing glue record for name server inside the domain", name)
+				continue
+			}
+			if addresses, err = resolver.LookupHost(ctx, name); err != nil {
+				result.addError("%s: failed to resolve: %s", name, err.Error())
+				continue
+			}
+		}
+
+		for _, address := range addresses {
+			check := checkNameServer(ctx, resolver, zone, name, address)
+			if check.Err != nil {
+				result.addError("%s (%s): %s", name, address, check.Err.Error())
+			}
+			result.NameServers = append(result.NameServers, check)
+		}
+	}
+	slices.Sort(expectedNS)
+	expectedNS = slices.Compact(expectedNS)
+
+	var reference *NameServerCheck
+	for i, check := range result.NameServers {
+		if check.Err != nil {
+			continue
+		}
+
+		if !slices.Equal(check.NameServers, expectedNS) {
+			result.addError("%s (%s): NS set %s does not match %s", check.Name, check.Address, strings.Join(check.NameServers, " "), strings.Join(expectedNS, " "))
+		}
+
+		if reference == nil {
+			reference = &result.NameServers[i]
+			continue
+		}
+		if check.Serial != reference.Serial {
+			result.addWarning("%s (%s): SOA serial %d differs from %d of %s", check.Name, check.Address, check.Serial, reference.Serial, reference.Name)
+		}
+		if !slices.Equal(check.DNSKeys, reference.DNSKeys) {
+			result.addError("%s (%s): DNSKEY set differs from %s", check.Name, check.Address, reference.Name)
+		}
+	}
+	if reference == nil {
+		return result, nil
+	}
+
+	served := make([][]byte, 0)
+	for _, key := range reference.DNSKeys {
+		rdata, err := parseDNSKey(key)
+		if err == nil {
+			served = append(served, rdata)
+		}
+	}
+
+	for i, key := range submittedKeys {
+		if !slices.ContainsFunc(served, func(rdata []byte) bool { return string(rdata) == string(key) }) {
+			result.addError("DNSKEY %q is not served by the name servers", opts.DNSKeys[i])
+		}
+	}
+	if len(submittedKeys) == 0 && len(served) > 0 {
+		result.addWarning("zone is signed, but no DNSKEY has been submitted")
+	}
+
+	dsKeys := submittedKeys
+	if len(dsKeys) == 0 {
+		for _, rdata := range served {
+			// secure entry point flag
+			if binary.BigEndian.Uint16(rdata)&1 == 1 {
+				dsKeys = append(dsKeys, rdata)
+			}
+		}
+	}
+	for _, rdata := range dsKeys {
+		result.DS = append(result.DS, computeDS(zone.String(), rdata, 2))
+	}
+
+	for i, ds := range submittedDS {
+		digestType, _ := strconv.Atoi(strings.Fields(ds)[2])
+		matches := slices.ContainsFunc(served, func(rdata []byte) bool { return computeDS(zone.String(), rdata, digestType) == ds })
+		if !matches {
+			result.addError("DS %q does not match any served DNSKEY", opts.DS[i])
+		}
+	}
+
+	return result, nil
+}
+
+func checkNameServer(ctx context.Context, resolver DNSResolver, zone dnsmessage.Name, name, address string) NameServerCheck {
+	check := NameServerCheck{Name: name, Address: address}
+
+	soa, err := queryNameServer(ctx, resolver, address, zone, dnsmessage.TypeSOA)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	if !soa.Header.Authoritative {
+		check.Err = fmt.Errorf("not authoritative for %s", zone)
+		return check
+	}
+	hasSOA := false
+	for _, answer := range soa.Answers {
+		if body, ok := answer.Body.(*dnsmessage.SOAResource); ok {
+			check.Serial = body.Serial
+			hasSOA = true
+			break
+		}
+	}
+	if !hasSOA {
+		check.Err = fmt.Errorf("no SOA record for %s", zone)
+		return check
+	}
+
+	ns, err := queryNameServer(ctx, resolver, address, zone, dnsmessage.TypeNS)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	check.NameServers = make([]string, 0)
+	for _, answer := range ns.Answers {
+		if body, ok := answer.Body.(*dnsmessage.NSResource); ok {
+			check.NameServers = append(check.NameServers, normalizeDNSName(body.NS.String()))
+		}
+	}
+	slices.Sort(check.NameServers)
+
+	dnskey, err := queryNameServer(ctx, resolver, address, zone, dnsTypeDNSKEY)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	check.DNSKeys = make([]string, 0)
+	for _, answer := range dnskey.Answers {
+		if body, ok := answer.Body.(*dnsmessage.UnknownResource); ok && answer.Header.Type == dnsTypeDNSKEY && len(body.Data) > 4 {
+			check.DNSKeys = append(check.DNSKeys, formatDNSKey(body.Data))
+		}
+	}
+	slices.Sort(check.DNSKeys)
+
+	return check
+}
+
+func queryNameServer(ctx context.Context, resolver DNSResolver, address string, zone dnsmessage.Name, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	query := dnsmessage.Message{
+		Questions: []dnsmessage.Question{{Name: zone, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	// DNSKEY sets exceed the classic 512 byte limit
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(4096, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, err
+	}
+	query.Additionals = []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}}
+
+	response, err := resolver.Exchange(ctx, address, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s query failed: %s", dnsTypeName(qtype), err.Error())
+	}
+	if response.Header.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("%s query returned %s", dnsTypeName(qtype), strings.TrimPrefix(response.Header.RCode.String(), "RCode"))
+	}
+	return response, nil
+}
+
+func dnsTypeName(qtype dnsmessage.Type) string {
+	if qtype == dnsTypeDNSKEY {
+		return "DNSKEY"
+	}
+	return strings.TrimPrefix(qtype.String(), "Type")
+}
+
+// normalizeDNSName converts name to lower case ACE form. Name servers are not restricted to DENIC domains, so idna is used instead of ParseDomain.
+func normalizeDNSName(name string) string {
+	if ace, err := idna.ToASCII(name); err == nil {
+		name = ace
+	}
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+// parseDNSKey returns the wire format of a DNSKEY like "257 3 8 AwEAA...".
+func parseDNSKey(str string) ([]byte, error) {
+	parts := strings.Fields(str)
+	if len(parts) < 4 {
+		return nil, fmt.Errorf("invalid %s %q", QueryFieldNameDNSKey, str)
+	}
+	flags, err1 := strconv.ParseUint(parts[0], 10, 16)
+	protocol, err2 := strconv.ParseUint(parts[1], 10, 8)
+	algorithm, err3 := strconv.ParseUint(parts[2], 10, 8)
+	key, err4 := base64.StdEncoding.DecodeString(strings.Join(parts[3:], ""))
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return nil, fmt.Errorf("invalid %s %q", QueryFieldNameDNSKey, str)
+	}
+
+	rdata := binary.BigEndian.AppendUint16(nil, uint16(flags))
+	rdata = append(rdata, byte(protocol), byte(algorithm))
+	return append(rdata, key...), nil
+}
+
+func formatDNSKey(rdata []byte) string {
+	return fmt.Sprintf("%d %d %d %s", binary.BigEndian.Uint16(rdata), rdata[2], rdata[3], base64.StdEncoding.EncodeToString(rdata[4:]))
+}
+
+// normalizeDS returns a DS record like "12345 8 2 49FD46E6..." with upper case digest without spaces.
+func normalizeDS(str string) (string, error) {
+	parts := strings.Fields(str)
+	if len(parts) < 4 {
+		return "", fmt.Errorf("invalid DS %q", str)
+	}
+	keyTag, err1 := strconv.ParseUint(parts[0], 10, 16)
+	algorithm, err2 := strconv.ParseUint(parts[1], 10, 8)
+	digestType, err3 := strconv.ParseUint(parts[2], 10, 8)
+	digest, err4 := hex.DecodeString(strings.Join(parts[3:], ""))
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return "", fmt.Errorf("invalid DS %q", str)
+	}
+	return fmt.Sprintf("%d %d %d %s", keyTag, algorithm, digestType, strings.ToUpper(hex.EncodeToString(digest))), nil
+}
+
+// computeDS returns the DS record of a DNSKEY as described in RFC 4034 section 5.1.4.
+func computeDS(owner string, rdata []byte, digestType int) string {
+	var h hash.Hash
+	switch digestType {
+	case 1:
+		h = sha1.New()
+	case 2:
+		h = sha256.New()
+	case 4:
+		h = sha512.New384()
+	default:
+		return ""
+	}
+
+	// owner name in canonical wire format
+	for _, label := range strings.Split(strings.TrimSuffix(strings.ToLower(owner), "."), ".") {
+		h.Write([]byte{byte(len(label))})
+		h.Write([]byte(label))
+	}
+	h.Write([]byte{0})
+	h.Write(rdata)
+
+	return fmt.Sprintf("%d %d %d %s", dnsKeyTag(rdata), rdata[3], digestType, strings.ToUpper(hex.EncodeToString(h.Sum(nil))))
+}
+
+// dnsKeyTag calculates the key tag as described in RFC 4034 appendix B.
+func dnsKeyTag(rdata []byte) uint16 {
+	var ac uint32
+	for i, b := range rdata {
+		if i&1 == 0 {
+			ac += uint32(b) << 8
+		} else {
+			ac += uint32(b)
+		}
+	}
+	ac += ac >> 16 & 0xFFFF
+	return uint16(ac & 0xFFFF)
+}
@@ -0,0 +1,182 @@
+package rri_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const testDNSKey = "257 3 8 AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMzNXxeYCmZDRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJBjEVv5f2wwjM9XzcnOf+EPbtG9DMBmADjFDc2w/rljwvFw=="
+
+// stubZone denotes the answers of a stub name server for a single zone.
+type stubZone struct {
+	Authoritative bool
+	Serial        uint32
+	NameServers   []string
+	DNSKeys       []string
+}
+
+// startStubDNSServer answers SOA, NS and DNSKEY queries for zone via UDP and returns its port.
+func startStubDNSServer(t *testing.T, zone string, data stubZone) int {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buffer := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+
+			var query dnsmessage.Message
+			if err := query.Unpack(buffer[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			question := query.Questions[0]
+
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.Header.ID, Response: true, Authoritative: data.Authoritative},
+				Questions: query.Questions,
+			}
+			if !strings.EqualFold(question.Name.String(), zone) {
+				response.Header.RCode = dnsmessage.RCodeRefused
+			} else {
+				header := dnsmessage.ResourceHeader{Name: question.Name, Type: question.Type, Class: dnsmessage.ClassINET, TTL: 3600}
+				switch question.Type {
+				case dnsmessage.TypeSOA:
+					response.Answers = append(response.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.SOAResource{
+						NS:     dnsmessage.MustNewName(data.NameServers[0] + "."),
+						MBox:   dnsmessage.MustNewName("hostmaster." + zone),
+						Serial: data.Serial,
+					}})
+				case dnsmessage.TypeNS:
+					for _, ns := range data.NameServers {
+						response.Answers = append(response.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.NSResource{NS: dnsmessage.MustNewName(ns + ".")}})
+					}
+				case 48:
+					for _, key := range data.DNSKeys {
+						response.Answers = append(response.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.UnknownResource{Type: 48, Data: dnsKeyRData(t, key)}})
+					}
+				}
+			}
+
+			packed, err := response.Pack()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(packed, addr) //nolint
+		}
+	}()
+
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func dnsKeyRData(t *testing.T, key string) []byte {
+	var flags uint16
+	var protocol, algorithm uint8
+	var publicKey string
+	_, err := fmt.Sscanf(key, "%d %d %d %s", &flags, &protocol, &algorithm, &publicKey)
+	require.NoError(t, err)
+	data, err := base64.StdEncoding.DecodeString(publicKey)
+	require.NoError(t, err)
+	return append(binary.BigEndian.AppendUint16(nil, flags), append([]byte{protocol, algorithm}, data...)...)
+}
+
+// stubResolver forwards queries for fake name server addresses to stub servers.
+type stubResolver struct {
+	hosts map[string][]string
+	ports map[string]int
+}
+
+func (r *stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addresses, ok := r.hosts[host]; ok {
+		return addresses, nil
+	}
+	return nil, fmt.Errorf("no such host")
+}
+
+func (r *stubResolver) Exchange(ctx context.Context, address string, query dnsmessage.Message) (*dnsmessage.Message, error) {
+	port, ok := r.ports[address]
+	if !ok {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return (&rri.NetResolver{Port: port}).Exchange(ctx, "127.0.0.1", query)
+}
+
+func TestPredelegationCheck(t *testing.T) {
+	zone := stubZone{Authoritative: true, Serial: 2024121101, NameServers: []string{"ns1.denic.de", "ns2.example.com"}, DNSKeys: []string{testDNSKey}}
+	resolver := &stubResolver{
+		hosts: map[string][]string{"ns2.example.com": {"192.0.2.2"}},
+		ports: map[string]int{
+			"192.0.2.1": startStubDNSServer(t, "denic.de.", zone),
+			"192.0.2.2": startStubDNSServer(t, "denic.de.", zone),
+		},
+	}
+
+	data := rri.DomainData{NameServers: []string{"ns1.denic.de. 192.0.2.1", "NS2.example.com"}}
+	result, err := rri.PredelegationCheck(context.Background(), "denic.de", data, rri.PredelegationOptions{
+		Resolver: resolver,
+		DNSKeys:  []string{testDNSKey},
+		DS:       []string{"60489 8 2 7ca765e8ae76a969bef190bc11ddfe83266d2e818278ae27160def24da6cec03"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Empty(t, result.Warnings)
+	assert.True(t, result.IsSuccessful())
+	require.Len(t, result.NameServers, 2)
+	assert.Equal(t, "192.0.2.2", result.NameServers[1].Address)
+	assert.Equal(t, uint32(2024121101), result.NameServers[1].Serial)
+	assert.Equal(t, []string{"ns1.denic.de", "ns2.example.com"}, result.NameServers[1].NameServers)
+	assert.Equal(t, []string{testDNSKey}, result.NameServers[1].DNSKeys)
+	assert.Equal(t, []string{"60489 8 2 7CA765E8AE76A969BEF190BC11DDFE83266D2E818278AE27160DEF24DA6CEC03"}, result.DS)
+}
+
+func TestPredelegationCheckProblems(t *testing.T) {
+	resolver := &stubResolver{
+		ports: map[string]int{
+			"192.0.2.1": startStubDNSServer(t, "denic.de.", stubZone{Authoritative: true, Serial: 2, NameServers: []string{"ns1.denic.de", "ns2.denic.de"}, DNSKeys: []string{testDNSKey}}),
+			"192.0.2.2": startStubDNSServer(t, "denic.de.", stubZone{Authoritative: true, Serial: 1, NameServers: []string{"ns1.denic.de", "ns3.denic.de"}}),
+			"192.0.2.3": startStubDNSServer(t, "denic.de.", stubZone{Authoritative: false, Serial: 2, NameServers: []string{"ns1.denic.de", "ns2.denic.de"}}),
+			"192.0.2.4": startStubDNSServer(t, "other.de.", stubZone{Authoritative: true, Serial: 2, NameServers: []string{"ns1.other.de"}}),
+		},
+	}
+
+	data := rri.DomainData{NameServers: []string{"ns1.denic.de 192.0.2.1", "ns2.denic.de 192.0.2.2 192.0.2.3 192.0.2.4", "ns3.denic.de", "ns4.example.com"}}
+	result, err := rri.PredelegationCheck(context.Background(), "denic.de", data, rri.PredelegationOptions{
+		Resolver: resolver,
+		DS:       []string{"12345 8 2 00"},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsSuccessful())
+	assert.Equal(t, []string{
+		"ns2.denic.de (192.0.2.3): not authoritative for denic.de.",
+		"ns2.denic.de (192.0.2.4): SOA query returned Refused",
+		"ns3.denic.de: missing glue record for name server inside the domain",
+		"ns4.example.com: failed to resolve: no such host",
+		"ns1.denic.de (192.0.2.1): NS set ns1.denic.de ns2.denic.de does not match ns1.denic.de ns2.denic.de ns3.denic.de ns4.example.com",
+		"ns2.denic.de (192.0.2.2): NS set ns1.denic.de ns3.denic.de does not match ns1.denic.de ns2.denic.de ns3.denic.de ns4.example.com",
+		"ns2.denic.de (192.0.2.2): DNSKEY set differs from ns1.denic.de",
+		`DS "12345 8 2 00" does not match any served DNSKEY`,
+	}, result.Errors)
+	assert.Equal(t, []string{
+		"ns2.denic.de (192.0.2.2): SOA serial 1 differs from 2 of ns1.denic.de",
+		"zone is signed, but no DNSKEY has been submitted",
+	}, result.Warnings)
+
+	_, err = rri.PredelegationCheck(context.Background(), "denic.com", data, rri.PredelegationOptions{Resolver: resolver})
+	assert.EqualError(t, err, `"denic.com" is not a .de domain name`)
+
+	_, err = rri.PredelegationCheck(context.Background(), "denic.de", data, rri.PredelegationOptions{Resolver: resolver, DNSKeys: []string{"257 3 8"}})
+	assert.EqualError(t, err, `invalid dnskey "257 3 8"`)
+}
@@ -86,6 +86,16 @@ const (
 	QueryFieldNameVerificationMethod QueryFieldName = "verificationmethod"
 	// QueryFieldNameTrustFramework denotes the query field name for trust framework.
 	QueryFieldNameTrustFramework QueryFieldName = "trustframework"
+	// QueryFieldNameCTID denotes the query field name for the client transaction id.
+	QueryFieldNameCTID QueryFieldName = "ctid"
+	// QueryFieldNameRegAcc denotes the query field name for a registrar account handle.
+	QueryFieldNameRegAcc QueryFieldName = "regacc"
+	// QueryFieldNameRecursive denotes the query field name to request contact details in INFO queries.
+	QueryFieldNameRecursive QueryFieldName = "recursive"
+	// QueryFieldNameNSEntry denotes the query field name for DNS resource records.
+	QueryFieldNameNSEntry QueryFieldName = "nsentry"
+	// QueryFieldNameDNSKey denotes the query field name for DNSKEY records.
+	QueryFieldNameDNSKey QueryFieldName = "dnskey"
 
 	// ActionLogin denotes the action value for login.
 	ActionLogin QueryAction = "LOGIN"
@@ -111,6 +121,8 @@ const (
 	ActionCreateAuthInfo1 QueryAction = "CREATE-AUTHINFO1"
 	// ActionCreateAuthInfo2 denotes the action value for create AuthInfo2.
 	ActionCreateAuthInfo2 QueryAction = "CREATE-AUTHINFO2"
+	// ActionDeleteAuthInfo1 denotes the action value for delete AuthInfo1.
+	ActionDeleteAuthInfo1 QueryAction = "DELETE-AUTHINFO1"
 	// ActionChangeProvider denotes the action value for change provider.
 	ActionChangeProvider QueryAction = "CHPROV"
 	// ActionQueueRead denotes the action value to read from the registry message queue.
@@ -142,6 +154,36 @@ func (q QueryAction) Normalize() QueryAction {
 	return QueryAction(strings.ToUpper(string(q)))
 }
 
+// KnownQueryFieldNames returns the names of all query fields defined in this package.
+func KnownQueryFieldNames() []QueryFieldName {
+	return []QueryFieldName{
+		QueryFieldNameVersion, QueryFieldNameAction, QueryFieldNameUser, QueryFieldNamePassword, QueryFieldNameDomainIDN, QueryFieldNameDomainACE,
+		QueryFieldNameHolder, QueryFieldNameGeneralRequest, QueryFieldNameAbuseContact, QueryFieldNameNameServer, QueryFieldNameHandle,
+		QueryFieldNameDisconnect, QueryFieldNameAuthInfoHash, QueryFieldNameAuthInfoExpire, QueryFieldNameAuthInfo, QueryFieldNameType,
+		QueryFieldNameName, QueryFieldNameOrganisation, QueryFieldNameAddress, QueryFieldNamePostalCode, QueryFieldNameCity,
+		QueryFieldNameCountryCode, QueryFieldNameEMail, QueryFieldNameMsgID, QueryFieldNameMsgType, QueryFieldNamePhone,
+		QueryFieldNameVerifiedClaim, QueryFieldNameVerificationResult, QueryFieldNameVerificationReference,
+		QueryFieldNameVerificationTimestamp, QueryFieldNameVerificationEvidence, QueryFieldNameVerificationMethod, QueryFieldNameTrustFramework,
+		QueryFieldNameCTID, QueryFieldNameRegAcc, QueryFieldNameRecursive, QueryFieldNameNSEntry, QueryFieldNameDNSKey,
+	}
+}
+
+// KnownActions returns all query actions supported by this package.
+func KnownActions() []QueryAction {
+	return []QueryAction{ActionLogin, ActionLogout, ActionCheck, ActionInfo, ActionCreate, ActionUpdate, ActionChangeHolder, ActionDelete, ActionRestore, ActionTransit, ActionCreateAuthInfo1, ActionCreateAuthInfo2, ActionDeleteAuthInfo1, ActionChangeProvider, ActionQueueRead, ActionQueueDelete}
+}
+
+// IsKnown returns true if the given QueryAction is contained in KnownActions.
+func (q QueryAction) IsKnown() bool {
+	normalized := q.Normalize()
+	for _, action := range KnownActions() {
+		if action == normalized {
+			return true
+		}
+	}
+	return false
+}
+
 // QueryFieldName represents a single data field of a query.
 type QueryFieldName string
 
@@ -180,11 +222,28 @@ func ParseContactType(str string) (ContactType, error) {
 		return ContactTypePerson, nil
 	case "ORG":
 		return ContactTypeOrganisation, nil
+	case "REQUEST":
+		return ContactTypeRequest, nil
 	default:
 		return "", fmt.Errorf("invalid contact type")
 	}
 }
 
+// UnmarshalText implements encoding.TextUnmarshaler and only accepts known contact types.
+func (t *ContactType) UnmarshalText(text []byte) error {
+	contactType, err := ParseContactType(string(text))
+	if err != nil {
+		return fmt.Errorf("%q: %s", text, err.Error())
+	}
+	*t = contactType
+	return nil
+}
+
+// ContactTypes returns all known contact types.
+func ContactTypes() []ContactType {
+	return []ContactType{ContactTypePerson, ContactTypeOrganisation, ContactTypeRequest}
+}
+
 // DenicHandle represents a handle like DENIC-1000006-SOME-CODE
 type DenicHandle struct {
 	ContactCode string
@@ -198,6 +257,21 @@ func (h DenicHandle) String() string {
 	return fmt.Sprintf("DENIC-%d-%s", h.RegAccID, strings.ToUpper(h.ContactCode))
 }
 
+// MarshalText implements encoding.TextMarshaler using the string representation of the handle.
+func (h DenicHandle) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseDenicHandle.
+func (h *DenicHandle) UnmarshalText(text []byte) error {
+	handle, err := ParseDenicHandle(string(text))
+	if err != nil {
+		return fmt.Errorf("%q: %s", text, err.Error())
+	}
+	*h = handle
+	return nil
+}
+
 // IsEmpty returns true when the given denic handle is unset.
 func (h DenicHandle) IsEmpty() bool {
 	return h.RegAccID == 0 && len(h.ContactCode) == 0
@@ -238,10 +312,10 @@ func ParseDenicHandle(str string) (DenicHandle, error) {
 
 // DomainData holds domain information.
 type DomainData struct {
-	HolderHandles         []DenicHandle
-	GeneralRequestHandles []DenicHandle
-	AbuseContactHandles   []DenicHandle
-	NameServers           []string
+	HolderHandles         []DenicHandle `json:"holder" yaml:"holder"`
+	GeneralRequestHandles []DenicHandle `json:"generalRequest" yaml:"generalRequest"`
+	AbuseContactHandles   []DenicHandle `json:"abuseContact" yaml:"abuseContact"`
+	NameServers           []string      `json:"nameServers,omitempty" yaml:"nameServers,omitempty"`
 }
 
 func (domainData *DomainData) PutToQueryFields(fields *QueryFieldList) {
@@ -261,17 +335,17 @@ func (domainData *DomainData) PutToQueryFields(fields *QueryFieldList) {
 
 // ContactData holds information of a contact handle.
 type ContactData struct {
-	Type         ContactType
-	Name         string
-	Organisation string
-	Address      string
-	PostalCode   string
-	City         string
-	CountryCode  string
-	EMail        []string
-	Phone        string
+	Type         ContactType `json:"type" yaml:"type"`
+	Name         string      `json:"name" yaml:"name"`
+	Organisation string      `json:"organisation,omitempty" yaml:"organisation,omitempty"`
+	Address      string      `json:"address" yaml:"address"`
+	PostalCode   string      `json:"postalCode" yaml:"postalCode"`
+	City         string      `json:"city" yaml:"city"`
+	CountryCode  string      `json:"countryCode" yaml:"countryCode"`
+	EMail        []string    `json:"email,omitempty" yaml:"email,omitempty"`
+	Phone        string      `json:"phone,omitempty" yaml:"phone,omitempty"`
 
-	VerificationInformation []VerificationInformation
+	VerificationInformation []VerificationInformation `json:"verificationInformation,omitempty" yaml:"verificationInformation,omitempty"`
 }
 
 func (contactData *ContactData) PutToQueryFields(fields *QueryFieldList) {
@@ -439,19 +513,22 @@ func NewInfoHandleQuery(handle DenicHandle) *Query {
 	return NewQuery(LatestVersion, ActionInfo, fields, nil)
 }
 
+// PutDomainToQueryFields adds the IDN and ACE fields of domain to fields. Names that cannot be converted are added to both fields as given, so the query is rejected by ValidateQuery or the registry instead of being sent with only one of them.
 func PutDomainToQueryFields(fields *QueryFieldList, domain string) {
-	if strings.HasPrefix(strings.ToLower(domain), "xn--") {
-		fields.Add(QueryFieldNameDomainACE, domain)
-		if idn, err := idna.ToUnicode(domain); err == nil {
-			fields.Add(QueryFieldNameDomainIDN, idn)
-		}
-	} else {
-		fields.Add(QueryFieldNameDomainIDN, domain)
-		if ace, err := idna.ToASCII(domain); err == nil {
-			// TODO only add ace string if it differs from idn
-			fields.Add(QueryFieldNameDomainACE, ace)
-		}
+	if d, err := ParseDomain(domain); err == nil {
+		d.PutToQueryFields(fields)
+		return
 	}
+
+	idn, ace := domain, domain
+	if converted, err := idna.ToUnicode(domain); err == nil {
+		idn = converted
+	}
+	if converted, err := idna.ToASCII(domain); err == nil {
+		ace = converted
+	}
+	fields.Add(QueryFieldNameDomainIDN, idn)
+	fields.Add(QueryFieldNameDomainACE, ace)
 }
 
 // NewCreateDomainQuery returns a query to create a domain.
@@ -639,6 +716,8 @@ func ParseQueryKV(str string) (*Query, error) {
 
 // ParseQuery tries to detect the query format (KV or XML) and returns the parsed query.
 func ParseQuery(str string) (*Query, error) {
-	// TODO detect type
+	if DetectFormat(str) == FormatXML {
+		return ParseQueryXML(str)
+	}
 	return ParseQueryKV(str)
 }
@@ -87,6 +87,16 @@ func TestPutDomainToQueryFields(t *testing.T) {
 	require.Len(t, fieldsFromACE, 2)
 	assert.Equal(t, []string{"dönic.de"}, fieldsFromACE.Values(rri.QueryFieldNameDomainIDN))
 	assert.Equal(t, []string{"xn--dnic-5qa.de"}, fieldsFromACE.Values(rri.QueryFieldNameDomainACE))
+
+	// invalid names are added to both fields and reported by ValidateQuery
+	for _, domain := range []string{"xn--zz.de", "-denic.de", "denic"} {
+		fields := rri.NewQueryFieldList()
+		rri.PutDomainToQueryFields(&fields, domain)
+		require.Len(t, fields, 2, domain)
+		assert.Len(t, fields.Values(rri.QueryFieldNameDomainIDN), 1, domain)
+		assert.Len(t, fields.Values(rri.QueryFieldNameDomainACE), 1, domain)
+		assert.NotEmpty(t, rri.ValidateQuery(rri.NewInfoDomainQuery(domain)), domain)
+	}
 }
 
 func TestNewCreateDomainQuery(t *testing.T) {
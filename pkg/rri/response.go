@@ -2,9 +2,12 @@ package rri
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/beevik/etree"
 )
 
 const (
@@ -23,6 +26,18 @@ const (
 	ResponseFieldNameError ResponseFieldName = "ERROR"
 	// ResponseFieldNameWarning denotes the response field name for warning message.
 	ResponseFieldNameWarning ResponseFieldName = "WARNING"
+	// ResponseFieldNameDomain denotes the response field name for the IDN domain name.
+	ResponseFieldNameDomain ResponseFieldName = "Domain"
+	// ResponseFieldNameDomainACE denotes the response field name for the ACE domain name.
+	ResponseFieldNameDomainACE ResponseFieldName = "Domain-Ace"
+	// ResponseFieldNameStatus denotes the response field name for the domain status.
+	ResponseFieldNameStatus ResponseFieldName = "Status"
+	// ResponseFieldNameMsgID denotes the response field name for the id of a queue message.
+	ResponseFieldNameMsgID ResponseFieldName = "MsgID"
+	// ResponseFieldNameMsgType denotes the response field name for the type of a queue message.
+	ResponseFieldNameMsgType ResponseFieldName = "MsgType"
+	// ResponseFieldNameMsgTime denotes the response field name for the creation time of a queue message.
+	ResponseFieldNameMsgTime ResponseFieldName = "MsgTime"
 
 	// ResponseEntityNameHolder denotes the entity name of a holder.
 	ResponseEntityNameHolder ResponseEntityName = "holder"
@@ -188,6 +203,19 @@ func (r *Response) FirstField(fieldName ResponseFieldName) string {
 	return r.fields.FirstValue(fieldName)
 }
 
+// LookupField returns all values defined for a field name. If the response itself does not define the field, the values of the first entity defining it are returned.
+func (r *Response) LookupField(fieldName ResponseFieldName) []string {
+	if values := r.Field(fieldName); len(values) > 0 {
+		return values
+	}
+	for _, entity := range r.entities {
+		if values := entity.Field(fieldName); len(values) > 0 {
+			return values
+		}
+	}
+	return nil
+}
+
 // Entities returns a list of entities contained in this response.
 func (r *Response) Entities() []ResponseEntity {
 	return r.entities
@@ -320,9 +348,112 @@ func ParseBusinessMessageKV(str string) (BusinessMessage, error) {
 	return BusinessMessage{id: id, message: parts[1]}, nil
 }
 
-// ParseResponse tries to detect the response format (KV or XML) and returns the parsed response.
+// ParseResponseXML parses an XML response. Result, STID and messages are read from the transaction element. Data elements are flattened to fields named by their tag or role attribute, verification information is returned as entity.
+func ParseResponseXML(msg string) (*Response, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(msg); err != nil {
+		return nil, fmt.Errorf("malformed xml: %s", err.Error())
+	}
+
+	root := doc.Root()
+	if root == nil || root.Tag != "registry-response" {
+		return nil, fmt.Errorf("registry-response element is missing")
+	}
+
+	transaction := root.SelectElement("transaction")
+	if transaction == nil {
+		return nil, fmt.Errorf("transaction element is missing")
+	}
+
+	fields := NewResponseFieldList()
+	entities := make([]ResponseEntity, 0)
+
+	result := transaction.SelectElement("result")
+	if result == nil {
+		return nil, fmt.Errorf("result element is missing")
+	}
+	fields.Add(ResponseFieldNameResult, strings.TrimSpace(result.Text()))
+
+	if stid := transaction.SelectElement("stid"); stid != nil {
+		fields.Add(ResponseFieldNameSTID, strings.TrimSpace(stid.Text()))
+	}
+
+	for _, msgElement := range transaction.SelectElements("message") {
+		fieldName := ResponseFieldNameInfo
+		switch strings.ToLower(msgElement.SelectAttrValue("level", "info")) {
+		case "warning":
+			fieldName = ResponseFieldNameWarning
+		case "error":
+			fieldName = ResponseFieldNameError
+		}
+
+		var code, text string
+		if codeElement := msgElement.SelectElement("code"); codeElement != nil {
+			code = strings.TrimSpace(codeElement.Text())
+		}
+		if textElement := msgElement.SelectElement("text"); textElement != nil {
+			text = strings.TrimSpace(textElement.Text())
+		}
+
+		if _, err := strconv.ParseInt(code, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid message code %q", code)
+		}
+		fields.Add(fieldName, code+" "+text)
+	}
+
+	if data := transaction.SelectElement("data"); data != nil {
+		for _, dataElement := range data.ChildElements() {
+			putXMLDataFields(dataElement, "", &fields, &entities)
+		}
+	}
+
+	return &Response{fields, entities}, nil
+}
+
+// putXMLDataFields adds all leaf elements below parent to fields. Leaf elements are named by their tag or by the role attribute of the closest element.
+func putXMLDataFields(parent *etree.Element, role string, fields *ResponseFieldList, entities *[]ResponseEntity) {
+	for _, child := range parent.ChildElements() {
+		childRole := child.SelectAttrValue("role", role)
+
+		if strings.EqualFold(child.Tag, string(QueryEntityVerificationInformation)) {
+			entity := ResponseEntity{ResponseEntityName(QueryEntityVerificationInformation).Normalize(), NewResponseFieldList()}
+			putXMLDataFields(child, "", &entity.fields, entities)
+			*entities = append(*entities, entity)
+			continue
+		}
+
+		if len(child.ChildElements()) > 1 && len(child.SelectAttrValue("role", "")) > 0 {
+			// contacts with role and more than a handle are returned as entity named by the role
+			entity := ResponseEntity{ResponseEntityName(childRole).Normalize(), NewResponseFieldList()}
+			putXMLDataFields(child, "", &entity.fields, entities)
+			if handle := entity.fields.FirstValue(ResponseFieldName(QueryFieldNameHandle)); len(handle) > 0 && !slices.Contains(fields.Values(ResponseFieldName(childRole)), handle) {
+				fields.Add(ResponseFieldName(childRole), handle)
+			}
+			*entities = append(*entities, entity)
+			continue
+		}
+
+		if len(child.ChildElements()) > 0 {
+			putXMLDataFields(child, childRole, fields, entities)
+			continue
+		}
+
+		name := ResponseFieldName(child.Tag)
+		if len(childRole) > 0 {
+			name = ResponseFieldName(childRole)
+		} else if child.Tag == "claim" {
+			// verified claims are wrapped in a verifiedClaims element in XML
+			name = ResponseFieldName(QueryFieldNameVerifiedClaim)
+		}
+		fields.Add(name, strings.TrimSpace(child.Text()))
+	}
+}
+
+// ParseResponse detects the response format (KV or XML) and returns the parsed response.
 func ParseResponse(str string) (*Response, error) {
-	// TODO detect type
+	if DetectFormat(str) == FormatXML {
+		return ParseResponseXML(str)
+	}
 	return ParseResponseKV(str)
 }
 
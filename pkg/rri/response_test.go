@@ -63,6 +63,15 @@ func TestResponseEntity(t *testing.T) {
 	assert.Equal(t, []string{"2019-04-05T10:26:06+02:00"}, entities[0].Field("Changed"))
 }
 
+func TestResponseLookupField(t *testing.T) {
+	response, err := rri.ParseResponse("RESULT: success\nDomain: denic.de\n\n[Holder]\nHandle: DENIC-1000006-DENIC\nName: DENIC eG\n\n[AbuseContact]\nHandle: DENIC-1000006-ABUSE\nEmail: abuse@denic.de\n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"denic.de"}, response.LookupField("Domain"))
+	assert.Equal(t, []string{"DENIC-1000006-DENIC"}, response.LookupField("Handle"))
+	assert.Equal(t, []string{"abuse@denic.de"}, response.LookupField("Email"))
+	assert.Nil(t, response.LookupField("Nserver"))
+}
+
 func TestResponseEntityMultiHolder(t *testing.T) {
 	response, err := rri.ParseResponse("RESULT: success\nINFO: 13000000011 Request was processed in test environment - not valid in real world [testing platform]\nSTID: 8792891a-c366-11eb-bca6-bbfdc472082a\n\nDomain: denic-opstt-29791.de\nDomain-Ace: denic-opstt-29791.de\nNserver: dns1.opsblau.de.\nNserver: dns3.opsblau.de.\nStatus: connect\nAuthInfo2: 2030-01-01T00:00:00+01:00\nRegAccId: DENIC-1000021\nRegAccName: DENIC eG - Operations Test 1000021\nChanged: 2011-08-22T14:39:34+02:00\n\n[Holder]\nHandle: DENIC-1000021-TEST-DAGOBERT\nType: PERSON\nName: Dagobert Duck\nOrganisation: Duck Industries\nAddress: Im Geldspeicher\nCity: Entenhausen\nPostalCode: 64542\nCountryCode: DE\nEmail: dagobert.duck@duck-industries.de\nChanged: 2020-12-23T07:13:04+01:00\n\n[Holder]\nHandle: DENIC-1000021-TEST-DAISY\nType: PERSON\nName: Daisy Duck\nOrganisation: Frauenverein\nAddress: Fliederweg 8\nCity: Entenhausen\nPostalCode: 64548\nCountryCode: DE\nEmail: daisy.duck@enten-netz.de\nChanged: 2020-12-23T07:09:19+01:00\n")
 	require.NoError(t, err)
@@ -124,3 +133,63 @@ func TestParseBusinessMessageKV(t *testing.T) {
 	_, err = rri.ParseBusinessMessageKV("")
 	assert.Error(t, err)
 }
+
+func TestParseResponseXML(t *testing.T) {
+	response, err := rri.ParseResponse(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<registry-response xmlns="http://registry.denic.de/global/5.0" xmlns:tr="http://registry.denic.de/transaction/5.0" xmlns:domain="http://registry.denic.de/domain/5.0" xmlns:dnsentry="http://registry.denic.de/dnsentry/5.0">
+  <tr:transaction>
+    <tr:stid>554c2cd7-0885-11eb-a619-610f86f60bcb</tr:stid>
+    <tr:result>success</tr:result>
+    <tr:message level="warning">
+      <tr:code>83200000001</tr:code>
+      <tr:text>foo bar</tr:text>
+    </tr:message>
+    <tr:data>
+      <domain:infoData>
+        <domain:handle>denic.de</domain:handle>
+        <domain:status>connect</domain:status>
+        <domain:contact role="holder">
+          <contact:handle>DENIC-99989-BSP</contact:handle>
+        </domain:contact>
+        <dnsentry:dnsentry>
+          <dnsentry:owner>denic.de.</dnsentry:owner>
+          <dnsentry:rdata>
+            <dnsentry:nameserver>ns1.denic.de.</dnsentry:nameserver>
+          </dnsentry:rdata>
+        </dnsentry:dnsentry>
+      </domain:infoData>
+    </tr:data>
+  </tr:transaction>
+  <ctid>xml-74ba5119</ctid>
+</registry-response>`)
+	require.NoError(t, err)
+	assert.True(t, response.IsSuccessful())
+	assert.Equal(t, "554c2cd7-0885-11eb-a619-610f86f60bcb", response.STID())
+	assert.Empty(t, response.InfoMessages())
+	require.Len(t, response.WarningMessages(), 1)
+	assert.Equal(t, int64(83200000001), response.WarningMessages()[0].ID())
+	assert.Equal(t, "foo bar", response.WarningMessages()[0].Message())
+	assert.Equal(t, "denic.de", response.FirstField("handle"))
+	assert.Equal(t, "connect", response.FirstField("status"))
+	assert.Equal(t, "DENIC-99989-BSP", response.FirstField("holder"))
+	assert.Equal(t, "ns1.denic.de.", response.FirstField("nameserver"))
+}
+
+func TestParseResponseXMLFailure(t *testing.T) {
+	response, err := rri.ParseResponse(`<registry-response xmlns:tr="http://registry.denic.de/transaction/5.0"><tr:transaction><tr:result>failure</tr:result><tr:message level="error"><tr:code>53400000001</tr:code><tr:text>error</tr:text></tr:message></tr:transaction></registry-response>`)
+	require.NoError(t, err)
+	assert.False(t, response.IsSuccessful())
+	require.Len(t, response.ErrorMessages(), 1)
+	assert.Equal(t, int64(53400000001), response.ErrorMessages()[0].ID())
+
+	_, err = rri.ParseResponse(`<registry-response><tr:transaction></tr:transaction></registry-response>`)
+	assert.Error(t, err)
+
+	_, err = rri.ParseResponse(`<registry-response>`)
+	assert.Error(t, err)
+}
+
+func TestDetectFormat(t *testing.T) {
+	assert.Equal(t, rri.FormatXML, rri.DetectFormat("\n <?xml version=\"1.0\"?><registry-response/>"))
+	assert.Equal(t, rri.FormatKV, rri.DetectFormat("RESULT: success\nINFO: 1000 <foo>"))
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"sync/atomic"
 )
 
 var (
@@ -65,7 +66,7 @@ func (s *Session) GetBool(key string) (bool, bool) {
 type Server struct {
 	listener net.Listener
 	Handler  QueryHandler
-	isClosed bool
+	isClosed atomic.Bool
 }
 
 // NewServer returns a new RRI server for the given TLS config listening on the given port.
@@ -75,12 +76,12 @@ func NewServer(listenAddress string, tlsConfig *tls.Config) (*Server, error) {
 		return nil, err
 	}
 
-	return &Server{listener: listener, isClosed: false, Handler: nil}, nil
+	return &Server{listener: listener, Handler: nil}, nil
 }
 
 // Close gracefully shuts down the server.
 func (srv *Server) Close() error {
-	srv.isClosed = true
+	srv.isClosed.Store(true)
 	return srv.listener.Close()
 }
 
@@ -89,7 +90,7 @@ func (srv *Server) Run() error {
 	for {
 		conn, err := srv.listener.Accept()
 		if err != nil {
-			if srv.isClosed {
+			if srv.isClosed.Load() {
 				return nil
 			}
 			return err
@@ -3,6 +3,7 @@ package rri_test
 import (
 	"crypto/tls"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -22,10 +23,13 @@ func TestServer(t *testing.T) {
 		panic(err)
 	}
 
+	var mutex sync.Mutex
 	queryCount := 0
 	var lastQuery *rri.Query
 
 	server.Handler = func(s *rri.Session, q *rri.Query) (*rri.Response, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
 		queryCount++
 		lastQuery = q
 		return rri.NewResponse(rri.ResultSuccess, nil), nil
@@ -45,6 +49,8 @@ func TestServer(t *testing.T) {
 	// let some time pass for the query to be processed
 	time.Sleep(50 * time.Millisecond)
 
+	mutex.Lock()
+	defer mutex.Unlock()
 	require.Equal(t, 1, queryCount, "expected to receive exactly one query")
 	assert.Equal(t, rri.ActionLogin, lastQuery.Action())
 	assert.Equal(t, "user", lastQuery.FirstField(rri.QueryFieldNameUser))
@@ -0,0 +1,263 @@
+package rri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTransferTimeout      = 5 * time.Minute
+	defaultTransferPollInterval = 10 * time.Second
+)
+
+var (
+	// ErrTransferAborted is returned by TransferDomain if TransferOptions.Confirm rejected the transfer.
+	ErrTransferAborted = errors.New("transfer aborted")
+	// ErrTransferTimeout is returned by TransferDomain if no queue message has been received for the transferred domain in time.
+	ErrTransferTimeout = errors.New("no queue message received for transfer")
+)
+
+// TransferStep denotes the current step of TransferDomain.
+type TransferStep string
+
+const (
+	// TransferStepInfo denotes the INFO query to check the domain before the transfer.
+	TransferStepInfo TransferStep = "info"
+	// TransferStepChangeProvider denotes the CHPROV query that transfers the domain.
+	TransferStepChangeProvider TransferStep = "chprov"
+	// TransferStepWait denotes waiting for the queue message that confirms the transfer.
+	TransferStepWait TransferStep = "wait"
+)
+
+// TransferOptions configures TransferDomain.
+type TransferOptions struct {
+	// MessageType denotes the type of the queue message to wait for. Any message for the domain is accepted if empty.
+	MessageType string
+	// Timeout denotes how long to wait for the queue message. Defaults to 5 minutes. Waiting is skipped if negative.
+	Timeout time.Duration
+	// PollInterval denotes the delay between QUEUE-READ queries. Defaults to 10 seconds.
+	PollInterval time.Duration
+	// DeleteMessage deletes the received queue message from the queue.
+	DeleteMessage bool
+	// DeleteOtherMessages deletes all messages that are not for the transferred domain from the queue. QUEUE-READ always returns the oldest message, so such a message blocks waiting until it is deleted elsewhere otherwise.
+	DeleteOtherMessages bool
+	// OtherMessage is called once for every message that is not for the transferred domain, before it is deleted.
+	OtherMessage func(msg *QueueMessage)
+	// Confirm is called with the INFO response before CHPROV is sent. The transfer is aborted if it returns false.
+	Confirm func(info *Response) bool
+	// Progress is called before every step.
+	Progress func(step TransferStep)
+}
+
+// QueueMessage is a message read from the registry message queue.
+type QueueMessage struct {
+	ID     string
+	Type   string
+	Time   string
+	Domain string
+	// Response denotes the QUEUE-READ response containing the message.
+	Response *Response
+}
+
+// ParseQueueMessage returns the message contained in a QUEUE-READ response. Fields are read from the response itself or from its first entity that denotes a message id.
+func ParseQueueMessage(response *Response) (*QueueMessage, bool) {
+	fields := response.Fields()
+	if len(fields.FirstValue(ResponseFieldNameMsgID)) == 0 {
+		for _, entity := range response.Entities() {
+			if len(entity.FirstField(ResponseFieldNameMsgID)) > 0 {
+				fields = entity.Fields()
+				break
+			}
+		}
+	}
+
+	msg := &QueueMessage{
+		ID:       fields.FirstValue(ResponseFieldNameMsgID),
+		Type:     fields.FirstValue(ResponseFieldNameMsgType),
+		Time:     fields.FirstValue(ResponseFieldNameMsgTime),
+		Domain:   fields.FirstValue(ResponseFieldNameDomain),
+		Response: response,
+	}
+	if len(msg.Domain) == 0 {
+		msg.Domain = fields.FirstValue(ResponseFieldNameDomainACE)
+	}
+	return msg, len(msg.ID) > 0
+}
+
+// TransferResult holds the responses of all steps of TransferDomain.
+type TransferResult struct {
+	// Info denotes the response to the INFO query sent before the transfer.
+	Info *Response
+	// ChangeProvider denotes the response to the CHPROV query.
+	ChangeProvider *Response
+	// Message denotes the queue message that confirmed the transfer. It is nil if waiting is disabled.
+	Message *QueueMessage
+}
+
+// TransferDomain moves a domain to the provider of client using the AuthInfo secret created by the losing provider. Default options are used if opts is omitted.
+//
+// The query is validated and the domain is checked with INFO before CHPROV is sent. Afterwards the queue is polled until a message for the domain arrives or the timeout expires. A canceled context stops waiting. The result contains all responses received so far, also if an error is returned.
+func TransferDomain(ctx context.Context, client *Client, domain, secret string, data DomainData, opts ...TransferOptions) (*TransferResult, error) {
+	if client == nil {
+		return nil, fmt.Errorf("missing client")
+	}
+	if len(opts) > 1 {
+		return nil, fmt.Errorf("too many transfer options")
+	}
+	var options TransferOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	d, err := ParseDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	domain = d.IDN()
+	if len(strings.TrimSpace(secret)) == 0 {
+		return nil, fmt.Errorf("missing auth info secret")
+	}
+
+	query := NewChangeProviderQuery(domain, secret, data)
+	if errs := ValidateQuery(query); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	progress := func(step TransferStep) {
+		if options.Progress != nil {
+			options.Progress(step)
+		}
+	}
+
+	result := &TransferResult{}
+
+	progress(TransferStepInfo)
+	info, err := client.SendQuery(NewInfoDomainQuery(domain))
+	if err != nil {
+		return result, err
+	}
+	result.Info = info
+	if !info.IsSuccessful() {
+		return result, responseError(ActionInfo, info)
+	}
+
+	if options.Confirm != nil && !options.Confirm(info) {
+		return result, ErrTransferAborted
+	}
+
+	progress(TransferStepChangeProvider)
+	chprov, err := client.SendQuery(query)
+	if err != nil {
+		return result, err
+	}
+	result.ChangeProvider = chprov
+	if !chprov.IsSuccessful() {
+		return result, responseError(ActionChangeProvider, chprov)
+	}
+
+	if options.Timeout < 0 {
+		return result, nil
+	}
+
+	progress(TransferStepWait)
+	result.Message, err = waitForQueueMessage(ctx, client, d, options)
+	return result, err
+}
+
+func waitForQueueMessage(ctx context.Context, client *Client, domain Domain, opts TransferOptions) (*QueueMessage, error) {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = defaultTransferTimeout
+	}
+	pollInterval := opts.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultTransferPollInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// blocking denotes a message for another domain at the head of the queue
+	var blocking *QueueMessage
+	for {
+		response, err := client.SendQuery(NewQueueReadQuery(opts.MessageType))
+		if err != nil {
+			return nil, err
+		}
+		if !response.IsSuccessful() {
+			return nil, responseError(ActionQueueRead, response)
+		}
+
+		if msg, ok := ParseQueueMessage(response); ok {
+			if isMessageForDomain(msg, domain) {
+				if opts.DeleteMessage {
+					if err := deleteQueueMessage(client, msg); err != nil {
+						return msg, err
+					}
+				}
+				return msg, nil
+			}
+
+			if blocking == nil || blocking.ID != msg.ID {
+				blocking = msg
+				if opts.OtherMessage != nil {
+					opts.OtherMessage(msg)
+				}
+			}
+			if opts.DeleteOtherMessages {
+				if err := deleteQueueMessage(client, msg); err != nil {
+					return nil, err
+				}
+				blocking = nil
+				// the next message might already be the one for domain
+				continue
+			}
+		} else {
+			blocking = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				if blocking != nil {
+					return nil, fmt.Errorf("%w %s within %s, message %s for %s blocks the queue", ErrTransferTimeout, domain, timeout, blocking.ID, blocking.Domain)
+				}
+				return nil, fmt.Errorf("%w %s within %s", ErrTransferTimeout, domain, timeout)
+			}
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func deleteQueueMessage(client *Client, msg *QueueMessage) error {
+	response, err := client.SendQuery(NewQueueDeleteQuery(msg.ID, msg.Type))
+	if err != nil {
+		return err
+	}
+	if !response.IsSuccessful() {
+		return responseError(ActionQueueDelete, response)
+	}
+	return nil
+}
+
+// isMessageForDomain compares ACE names to match IDN domains regardless of the representation used in the message.
+func isMessageForDomain(msg *QueueMessage, domain Domain) bool {
+	msgDomain, err := ParseDomain(msg.Domain)
+	return err == nil && msgDomain.ACE() == domain.ACE()
+}
+
+// responseError returns the error messages of a failed response as error.
+func responseError(action QueryAction, response *Response) error {
+	messages := make([]string, 0)
+	for _, msg := range response.ErrorMessages() {
+		messages = append(messages, msg.String())
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("%s failed", action)
+	}
+	return fmt.Errorf("%s failed: %s", action, strings.Join(messages, ", "))
+}
@@ -0,0 +1,176 @@
+package rri_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransferTestClient(t *testing.T, server *rri.MockServer) *rri.Client {
+	client, err := rri.NewClient(server.Address(), &rri.ClientConfig{Insecure: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	require.NoError(t, client.Login("DENIC-1000011-TEST", "secret"))
+	return client
+}
+
+func newTransferTestData(t *testing.T) rri.DomainData {
+	holder, err := rri.ParseDenicHandle("DENIC-1000011-HOLDER")
+	require.NoError(t, err)
+	return rri.DomainData{HolderHandles: []rri.DenicHandle{holder}, NameServers: []string{"ns1.denic.de"}}
+}
+
+// transferTestHandler answers INFO and CHPROV and serves QUEUE-READ from queue. Messages are only removed by QUEUE-DELETE and the message for the transferred domain is appended once CHPROV succeeded.
+func transferTestHandler(queue *[]*rri.Response, deleted *[]string) rri.MockQueryHandler {
+	return func(user string, session *rri.Session, query *rri.Query) (*rri.Response, error) {
+		switch query.Action() {
+		case rri.ActionInfo:
+			fields := rri.NewResponseFieldList()
+			fields.Add(rri.ResponseFieldNameDomain, query.FirstField(rri.QueryFieldNameDomainIDN))
+			return rri.NewResponse(rri.ResultSuccess, fields), nil
+
+		case rri.ActionChangeProvider:
+			if query.FirstField(rri.QueryFieldNameAuthInfo) != "auth-secret" {
+				return rri.NewResponseWithError(rri.ResultFailure, nil, rri.NewBusinessMessage(53400000001, "wrong auth info")), nil
+			}
+			*queue = append(*queue, newQueueMessageResponse("4712", rri.ResponseFieldNameDomainACE, "xn--dmin-moa0i.de"))
+			return rri.NewResponse(rri.ResultSuccess, nil), nil
+
+		case rri.ActionQueueRead:
+			if len(*queue) == 0 {
+				return rri.NewResponse(rri.ResultSuccess, nil), nil
+			}
+			return (*queue)[0], nil
+
+		case rri.ActionQueueDelete:
+			msgID := query.FirstField(rri.QueryFieldNameMsgID)
+			*deleted = append(*deleted, msgID)
+			for i, msg := range *queue {
+				if msg.FirstField(rri.ResponseFieldNameMsgID) == msgID {
+					*queue = append((*queue)[:i], (*queue)[i+1:]...)
+					break
+				}
+			}
+		}
+		return rri.NewResponse(rri.ResultSuccess, nil), nil
+	}
+}
+
+func newQueueMessageResponse(msgID string, domainField rri.ResponseFieldName, domain string) *rri.Response {
+	fields := rri.NewResponseFieldList()
+	fields.Add(rri.ResponseFieldNameMsgID, msgID)
+	fields.Add(rri.ResponseFieldNameMsgType, "chprovAuthInfo")
+	fields.Add(domainField, domain)
+	return rri.NewResponse(rri.ResultSuccess, fields)
+}
+
+func TestTransferDomain(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		// unrelated message at the head of the queue
+		queue := []*rri.Response{newQueueMessageResponse("4711", rri.ResponseFieldNameDomain, "other.de")}
+		deleted := make([]string, 0)
+		server.Handler = transferTestHandler(&queue, &deleted)
+
+		client := newTransferTestClient(t, server)
+
+		var steps []rri.TransferStep
+		var others []string
+		result, err := rri.TransferDomain(context.Background(), client, "dömäin.de", "auth-secret", newTransferTestData(t), rri.TransferOptions{
+			PollInterval:        10 * time.Millisecond,
+			DeleteMessage:       true,
+			DeleteOtherMessages: true,
+			OtherMessage:        func(msg *rri.QueueMessage) { others = append(others, msg.ID+" "+msg.Domain) },
+			Progress:            func(step rri.TransferStep) { steps = append(steps, step) },
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []rri.TransferStep{rri.TransferStepInfo, rri.TransferStepChangeProvider, rri.TransferStepWait}, steps)
+		assert.True(t, result.Info.IsSuccessful())
+		assert.True(t, result.ChangeProvider.IsSuccessful())
+		require.NotNil(t, result.Message)
+		assert.Equal(t, "4712", result.Message.ID)
+		assert.Equal(t, "chprovAuthInfo", result.Message.Type)
+		assert.Equal(t, []string{"4711 other.de"}, others)
+		assert.Equal(t, []string{"4711", "4712"}, deleted)
+		assert.Empty(t, queue)
+
+		result, err = rri.TransferDomain(context.Background(), client, "denic.de", "wrong", newTransferTestData(t), rri.TransferOptions{Timeout: -1})
+		assert.EqualError(t, err, "CHPROV failed: 53400000001 wrong auth info")
+		assert.True(t, result.Info.IsSuccessful())
+		assert.False(t, result.ChangeProvider.IsSuccessful())
+
+		result, err = rri.TransferDomain(context.Background(), client, "denic.de", "auth-secret", newTransferTestData(t), rri.TransferOptions{
+			Confirm: func(info *rri.Response) bool { return false },
+		})
+		assert.ErrorIs(t, err, rri.ErrTransferAborted)
+		assert.Nil(t, result.ChangeProvider)
+	})
+}
+
+func TestTransferDomainBlockedQueue(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		queue := []*rri.Response{newQueueMessageResponse("4711", rri.ResponseFieldNameDomain, "other.de")}
+		deleted := make([]string, 0)
+		server.Handler = transferTestHandler(&queue, &deleted)
+
+		client := newTransferTestClient(t, server)
+
+		// other messages are only reported once and not deleted by default
+		var others []string
+		result, err := rri.TransferDomain(context.Background(), client, "dömäin.de", "auth-secret", newTransferTestData(t), rri.TransferOptions{
+			Timeout:      50 * time.Millisecond,
+			PollInterval: 10 * time.Millisecond,
+			OtherMessage: func(msg *rri.QueueMessage) { others = append(others, msg.ID) },
+		})
+		assert.ErrorIs(t, err, rri.ErrTransferTimeout)
+		assert.EqualError(t, err, "no queue message received for transfer dömäin.de within 50ms, message 4711 for other.de blocks the queue")
+		assert.True(t, result.ChangeProvider.IsSuccessful())
+		assert.Nil(t, result.Message)
+		assert.Equal(t, []string{"4711"}, others)
+		assert.Empty(t, deleted)
+		assert.Len(t, queue, 2)
+	})
+}
+
+func TestTransferDomainTimeout(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		client := newTransferTestClient(t, server)
+
+		result, err := rri.TransferDomain(context.Background(), client, "denic.de", "auth-secret", newTransferTestData(t), rri.TransferOptions{
+			Timeout:      50 * time.Millisecond,
+			PollInterval: 10 * time.Millisecond,
+		})
+		assert.ErrorIs(t, err, rri.ErrTransferTimeout)
+		assert.True(t, result.ChangeProvider.IsSuccessful())
+		assert.Nil(t, result.Message)
+	})
+}
+
+func TestTransferDomainValidation(t *testing.T) {
+	rri.MustWithMockServer(func(server *rri.MockServer) {
+		server.AddUser("DENIC-1000011-TEST", "secret")
+
+		client := newTransferTestClient(t, server)
+
+		_, err := rri.TransferDomain(context.Background(), client, "denic.com", "auth-secret", newTransferTestData(t))
+		assert.EqualError(t, err, `"denic.com" is not a .de domain name`)
+
+		_, err = rri.TransferDomain(context.Background(), client, "denic.de", " ", newTransferTestData(t))
+		assert.EqualError(t, err, "missing auth info secret")
+
+		_, err = rri.TransferDomain(context.Background(), client, "denic.de", "auth-secret", rri.DomainData{})
+		assert.EqualError(t, err, "missing holder field")
+
+		_, err = rri.TransferDomain(context.Background(), client, "denic.de", "auth-secret", newTransferTestData(t), rri.TransferOptions{}, rri.TransferOptions{})
+		assert.EqualError(t, err, "too many transfer options")
+	})
+}
@@ -0,0 +1,91 @@
+package rri
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateQuery checks that the action of the query is known and that all fields required for the action are set. All problems are returned instead of only the first.
+func ValidateQuery(q *Query) []error {
+	var errs []error
+
+	has := func(fieldName QueryFieldName) bool {
+		return len(strings.TrimSpace(q.FirstField(fieldName))) > 0
+	}
+	require := func(fieldNames ...QueryFieldName) {
+		for _, fieldName := range fieldNames {
+			if !has(fieldName) {
+				errs = append(errs, fmt.Errorf("missing %s field", fieldName))
+			}
+		}
+	}
+
+	if !has(QueryFieldNameVersion) {
+		errs = append(errs, fmt.Errorf("missing %s field", QueryFieldNameVersion))
+	}
+
+	action := q.Action()
+	if !action.IsKnown() {
+		return append(errs, fmt.Errorf("unknown action '%s'", q.FirstField(QueryFieldNameAction)))
+	}
+
+	if has(QueryFieldNameDomainIDN) {
+		domain, err := ParseDomain(q.FirstField(QueryFieldNameDomainIDN))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s field: %w", QueryFieldNameDomainIDN, err))
+		} else if ace := q.FirstField(QueryFieldNameDomainACE); len(ace) > 0 && !strings.EqualFold(ace, domain.ACE()) {
+			errs = append(errs, fmt.Errorf("%s field %q does not match %s field %q", QueryFieldNameDomainACE, ace, QueryFieldNameDomainIDN, domain.IDN()))
+		}
+	}
+
+	switch action {
+	case ActionLogin:
+		require(QueryFieldNameUser, QueryFieldNamePassword)
+
+	case ActionCheck, ActionInfo:
+		if !has(QueryFieldNameDomainIDN) && !has(QueryFieldNameHandle) && !has(QueryFieldNameRegAcc) {
+			errs = append(errs, fmt.Errorf("missing %s, %s or %s field", QueryFieldNameDomainIDN, QueryFieldNameHandle, QueryFieldNameRegAcc))
+		}
+
+	case ActionCreate, ActionUpdate:
+		switch {
+		case has(QueryFieldNameDomainIDN):
+			require(QueryFieldNameHolder)
+		case has(QueryFieldNameHandle):
+			require(QueryFieldNameType, QueryFieldNameName, QueryFieldNameAddress, QueryFieldNamePostalCode, QueryFieldNameCity, QueryFieldNameCountryCode)
+			if has(QueryFieldNameType) {
+				if _, err := ParseContactType(q.FirstField(QueryFieldNameType)); err != nil {
+					errs = append(errs, fmt.Errorf("invalid %s field: %w", QueryFieldNameType, err))
+				}
+			}
+		default:
+			errs = append(errs, fmt.Errorf("missing %s or %s field", QueryFieldNameDomainIDN, QueryFieldNameHandle))
+		}
+
+	case ActionChangeHolder:
+		require(QueryFieldNameDomainIDN, QueryFieldNameHolder)
+
+	case ActionChangeProvider:
+		require(QueryFieldNameDomainIDN, QueryFieldNameHolder, QueryFieldNameAuthInfo)
+
+	case ActionCreateAuthInfo1:
+		require(QueryFieldNameDomainIDN, QueryFieldNameAuthInfoHash, QueryFieldNameAuthInfoExpire)
+
+	case ActionDelete, ActionRestore, ActionTransit, ActionCreateAuthInfo2, ActionDeleteAuthInfo1:
+		require(QueryFieldNameDomainIDN)
+
+	case ActionQueueDelete:
+		require(QueryFieldNameMsgID)
+	}
+
+	return errs
+}
+
+// ValidateQueryString parses a KV or XML query and validates it. Parse errors are returned as the only error.
+func ValidateQueryString(str string) (*Query, []error) {
+	q, err := ParseQuery(str)
+	if err != nil {
+		return nil, []error{err}
+	}
+	return q, ValidateQuery(q)
+}
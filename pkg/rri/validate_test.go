@@ -0,0 +1,50 @@
+package rri_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateQueryExamples(t *testing.T) {
+	files, err := filepath.Glob("../../examples/*/*/*")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+
+			query, errs := rri.ValidateQueryString(string(data))
+			assert.Empty(t, errs)
+			assert.NotNil(t, query)
+		})
+	}
+}
+
+func TestValidateQuery(t *testing.T) {
+	_, errs := rri.ValidateQueryString("version: 5.0\naction: create\nhandle: DENIC-1000001-MAX\ntype: alien\nname: Max")
+	assert.Len(t, errs, 5)
+
+	_, errs = rri.ValidateQueryString("version: 5.0\naction: chprov\ndomain: denic.de")
+	assert.Len(t, errs, 2)
+
+	_, errs = rri.ValidateQueryString("version: 5.0\naction: info")
+	assert.Len(t, errs, 1)
+
+	_, errs = rri.ValidateQueryString("version: 5.0\naction: foo")
+	assert.EqualError(t, errs[0], "unknown action 'foo'")
+
+	query, errs := rri.ValidateQueryString("version 5.0")
+	assert.Nil(t, query)
+	assert.Len(t, errs, 1)
+
+	_, errs = rri.ValidateQueryString(`<registry-request xmlns="http://registry.denic.de/global/5.0" xmlns:domain="http://registry.denic.de/domain/5.0"><domain:delete/></registry-request>`)
+	assert.Len(t, errs, 1)
+}
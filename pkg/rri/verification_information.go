@@ -10,13 +10,13 @@ const VerificationInformationTimestampFormat = "2006-01-02T15:04:05-07:00"
 
 // VerificationInformation holds verification information.
 type VerificationInformation struct {
-	VerificationTimestamp time.Time
-	VerificationResult    VerificationResult
-	VerificationReference string
-	VerificationEvidence  VerificationEvidence
-	VerificationMethod    VerificationMethod
-	TrustFramework        TrustFramework
-	VerifiedClaim         []VerificationClaim
+	VerificationTimestamp time.Time            `json:"verificationTimestamp" yaml:"verificationTimestamp"`
+	VerificationResult    VerificationResult   `json:"verificationResult" yaml:"verificationResult"`
+	VerificationReference string               `json:"verificationReference,omitempty" yaml:"verificationReference,omitempty"`
+	VerificationEvidence  VerificationEvidence `json:"verificationEvidence,omitempty" yaml:"verificationEvidence,omitempty"`
+	VerificationMethod    VerificationMethod   `json:"verificationMethod,omitempty" yaml:"verificationMethod,omitempty"`
+	TrustFramework        TrustFramework       `json:"trustFramework,omitempty" yaml:"trustFramework,omitempty"`
+	VerifiedClaim         []VerificationClaim  `json:"verifiedClaim,omitempty" yaml:"verifiedClaim,omitempty"`
 }
 
 func (verificationInformation *VerificationInformation) PutToQueryFields(fields *QueryFieldList) {
@@ -43,6 +43,11 @@ const (
 	VerificationResultFailed  VerificationResult = "failed"
 )
 
+// VerificationResults returns all known verification results.
+func VerificationResults() []VerificationResult {
+	return []VerificationResult{VerificationResultSuccess, VerificationResultFailed}
+}
+
 // ParseVerificationResult parses a verification result from string.
 func ParseVerificationResult(s string) (VerificationResult, error) {
 	switch strings.ToLower(s) {
@@ -55,6 +60,16 @@ func ParseVerificationResult(s string) (VerificationResult, error) {
 	}
 }
 
+// UnmarshalText implements encoding.TextUnmarshaler and only accepts known values.
+func (v *VerificationResult) UnmarshalText(text []byte) error {
+	result, err := ParseVerificationResult(string(text))
+	if err != nil {
+		return fmt.Errorf("%q: %s", text, err.Error())
+	}
+	*v = result
+	return nil
+}
+
 type VerificationClaim string
 
 const (
@@ -63,6 +78,11 @@ const (
 	VerificationClaimAddress VerificationClaim = "address"
 )
 
+// VerificationClaims returns all known verification claims.
+func VerificationClaims() []VerificationClaim {
+	return []VerificationClaim{VerificationClaimEMail, VerificationClaimName, VerificationClaimAddress}
+}
+
 // ParseVerificationClaim parses a verification claim from string.
 func ParseVerificationClaim(s string) (VerificationClaim, error) {
 	switch strings.ToLower(s) {
@@ -77,6 +97,16 @@ func ParseVerificationClaim(s string) (VerificationClaim, error) {
 	}
 }
 
+// UnmarshalText implements encoding.TextUnmarshaler and only accepts known values.
+func (v *VerificationClaim) UnmarshalText(text []byte) error {
+	claim, err := ParseVerificationClaim(string(text))
+	if err != nil {
+		return fmt.Errorf("%q: %s", text, err.Error())
+	}
+	*v = claim
+	return nil
+}
+
 type VerificationMethod string
 
 const (
@@ -90,6 +120,11 @@ const (
 	VerificationMethodReachability VerificationMethod = "reachability"
 )
 
+// VerificationMethods returns all known verification methods.
+func VerificationMethods() []VerificationMethod {
+	return []VerificationMethod{VerificationMethodAuth, VerificationMethodEDoc, VerificationMethodDoc, VerificationMethodVDig, VerificationMethodBvr, VerificationMethodPvr, VerificationMethodData, VerificationMethodReachability}
+}
+
 // ParseVerificationMethod parses a verification method from string.
 func ParseVerificationMethod(s string) (VerificationMethod, error) {
 	switch strings.ToLower(s) {
@@ -114,6 +149,16 @@ func ParseVerificationMethod(s string) (VerificationMethod, error) {
 	}
 }
 
+// UnmarshalText implements encoding.TextUnmarshaler and only accepts known values.
+func (v *VerificationMethod) UnmarshalText(text []byte) error {
+	method, err := ParseVerificationMethod(string(text))
+	if err != nil {
+		return fmt.Errorf("%q: %s", text, err.Error())
+	}
+	*v = method
+	return nil
+}
+
 type VerificationEvidence string
 
 const (
@@ -137,6 +182,17 @@ const (
 	VerificationEvidenceAddressDatabase         VerificationEvidence = "address_database"
 )
 
+// VerificationEvidences returns all known verification evidences.
+func VerificationEvidences() []VerificationEvidence {
+	return []VerificationEvidence{
+		VerificationEvidenceIDCard, VerificationEvidencePassport, VerificationEvidencePopulationRegister, VerificationEvidenceResidencePermit,
+		VerificationEvidenceProofOfArrival, VerificationEvidenceDriversLicence, VerificationEvidenceCompanyRegister, VerificationEvidenceCompanyStatement,
+		VerificationEvidenceBankAccount, VerificationEvidenceOnlinePaymentAccount, VerificationEvidenceUtilityAccount, VerificationEvidenceBankStatement,
+		VerificationEvidenceTaxStatement, VerificationEvidenceWrittenAttestation, VerificationEvidenceDigitalAttestation,
+		VerificationEvidencePostalVerTransactionLog, VerificationEvidenceEmailVerTransactionLog, VerificationEvidenceAddressDatabase,
+	}
+}
+
 // ParseVerificationEvidence parses a verification evidence from string.
 func ParseVerificationEvidence(s string) (VerificationEvidence, error) {
 	switch strings.ToLower(s) {
@@ -181,12 +237,27 @@ func ParseVerificationEvidence(s string) (VerificationEvidence, error) {
 	}
 }
 
+// UnmarshalText implements encoding.TextUnmarshaler and only accepts known values.
+func (v *VerificationEvidence) UnmarshalText(text []byte) error {
+	evidence, err := ParseVerificationEvidence(string(text))
+	if err != nil {
+		return fmt.Errorf("%q: %s", text, err.Error())
+	}
+	*v = evidence
+	return nil
+}
+
 type TrustFramework string
 
 const (
 	TrustFrameworkDenic TrustFramework = "de_denic"
 )
 
+// TrustFrameworks returns all known trust frameworks.
+func TrustFrameworks() []TrustFramework {
+	return []TrustFramework{TrustFrameworkDenic}
+}
+
 // ParseTrustFramework parses a trust framework from string.
 func ParseTrustFramework(s string) (TrustFramework, error) {
 	switch strings.ToLower(s) {
@@ -196,3 +267,13 @@ func ParseTrustFramework(s string) (TrustFramework, error) {
 		return "", fmt.Errorf("invalid trust framework")
 	}
 }
+
+// UnmarshalText implements encoding.TextUnmarshaler and only accepts known values.
+func (t *TrustFramework) UnmarshalText(text []byte) error {
+	framework, err := ParseTrustFramework(string(text))
+	if err != nil {
+		return fmt.Errorf("%q: %s", text, err.Error())
+	}
+	*t = framework
+	return nil
+}
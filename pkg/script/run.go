@@ -0,0 +1,271 @@
+package script
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/DENICeG/go-console/v2/commandline"
+	"github.com/DENICeG/go-rriclient/pkg/parser"
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+)
+
+// Executor executes the commands and queries of a script.
+type Executor interface {
+	// ExecCommand executes a command line and returns the last response received for it or nil if no query has been sent.
+	ExecCommand(cmd []string) (*rri.Response, error)
+	// SendQuery sends a raw KV or XML query and returns the response.
+	SendQuery(query string) (*rri.Response, error)
+	// ResolveVariable returns the value of a variable that is neither set by the script nor derived from the last response.
+	ResolveVariable(name string) (string, bool, error)
+}
+
+// Stats summarizes a script run.
+type Stats struct {
+	Commands   int
+	Assertions int
+}
+
+// Run executes all statements of the script in order and writes echo output and executed commands to out. The script stops at the first error or failed assertion.
+//
+// Besides variables assigned with set and for, the following variables are derived from the last response: ${result}, ${stid}, ${messages} with the IDs of all business messages and ${response.FIELD} with all values of a response field separated by spaces. Fields that are not part of the response are looked up in its entities.
+func (s *Script) Run(executor Executor, out io.Writer) (Stats, error) {
+	r := &runner{
+		executor:  executor,
+		out:       out,
+		variables: make(map[string]string),
+	}
+
+	err := r.runBlock(s.statements)
+	return r.stats, err
+}
+
+type runner struct {
+	executor     Executor
+	out          io.Writer
+	variables    map[string]string
+	lastResponse *rri.Response
+	stats        Stats
+}
+
+func (r *runner) runBlock(statements []statement) error {
+	for _, stmt := range statements {
+		if err := r.runStatement(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) runStatement(stmt statement) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("line %d: %w", stmt.lineNumber(), err)
+	}
+
+	switch stmt := stmt.(type) {
+	case commandStatement:
+		cmd, err := r.expandAll(stmt.args)
+		if err != nil {
+			return wrap(err)
+		}
+
+		fmt.Fprintf(r.out, "> %s\n", commandline.GetCommandString(cmd))
+		r.stats.Commands++
+		res, err := r.executor.ExecCommand(cmd)
+		if err != nil {
+			return wrap(err)
+		}
+		if res != nil {
+			// commands like verbose or output do not replace the last response
+			r.lastResponse = res
+		}
+
+	case queryStatement:
+		query, err := r.expand(stmt.query)
+		if err != nil {
+			return wrap(err)
+		}
+
+		fmt.Fprintln(r.out, "> query")
+		r.stats.Commands++
+		res, err := r.executor.SendQuery(query)
+		if err != nil {
+			return wrap(err)
+		}
+		r.lastResponse = res
+
+	case setStatement:
+		values, err := r.expandAll(stmt.args)
+		if err != nil {
+			return wrap(err)
+		}
+		r.variables[stmt.name] = strings.Join(values, " ")
+
+	case echoStatement:
+		values, err := r.expandAll(stmt.args)
+		if err != nil {
+			return wrap(err)
+		}
+		fmt.Fprintln(r.out, strings.Join(values, " "))
+
+	case ifStatement:
+		ok, err := r.evaluate(stmt.cond)
+		if err != nil {
+			return wrap(err)
+		}
+		if ok {
+			return r.runBlock(stmt.then)
+		}
+		return r.runBlock(stmt.otherwise)
+
+	case forStatement:
+		values, err := r.expandAll(stmt.items)
+		if err != nil {
+			return wrap(err)
+		}
+
+		for _, value := range values {
+			// items are separated by whitespace or commas, also within variable values
+			for _, item := range strings.FieldsFunc(value, func(c rune) bool { return c == ',' || c == ' ' || c == '\t' || c == '\n' }) {
+				r.variables[stmt.name] = item
+				if err := r.runBlock(stmt.body); err != nil {
+					return err
+				}
+			}
+		}
+
+	case assertStatement:
+		ok, err := r.evaluate(stmt.cond)
+		if err != nil {
+			return wrap(err)
+		}
+		r.stats.Assertions++
+		if !ok {
+			return wrap(fmt.Errorf("assertion failed: %s", r.describe(stmt.cond)))
+		}
+
+	default:
+		return wrap(fmt.Errorf("unsupported statement %T", stmt))
+	}
+
+	return nil
+}
+
+// evaluate expands both sides of cond and applies the operator.
+func (r *runner) evaluate(cond condition) (bool, error) {
+	lhs, err := r.expand(cond.lhs)
+	if err != nil {
+		return false, err
+	}
+	rhs, err := r.expand(cond.rhs)
+	if err != nil {
+		return false, err
+	}
+
+	var result bool
+	switch cond.operator {
+	case "":
+		result = len(lhs) > 0 && lhs != "false" && lhs != "0"
+	case "==":
+		result = lhs == rhs
+	case "!=":
+		result = lhs != rhs
+	case "contains":
+		result = strings.Contains(lhs, rhs)
+	case "matches":
+		result, err = regexp.MatchString(rhs, lhs)
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression: %w", err)
+		}
+	default:
+		return false, fmt.Errorf("unknown operator '%s'", cond.operator)
+	}
+
+	return result != cond.negate, nil
+}
+
+// describe returns the condition with expanded values for error messages.
+func (r *runner) describe(cond condition) string {
+	parts := make([]string, 0, 4)
+	if cond.negate {
+		parts = append(parts, "not")
+	}
+	for _, str := range []string{cond.lhs, cond.operator, cond.rhs} {
+		if len(str) == 0 {
+			continue
+		}
+		if expanded, err := r.expand(str); err == nil && expanded != str {
+			str = fmt.Sprintf("%s (%q)", str, expanded)
+		}
+		parts = append(parts, str)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (r *runner) expandAll(args []string) ([]string, error) {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		value, err := r.expand(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (r *runner) expand(input string) (string, error) {
+	return parser.ExpandVariables(input, r.resolveVariable)
+}
+
+func (r *runner) resolveVariable(name string) (string, bool, error) {
+	if value, ok := r.variables[name]; ok {
+		return value, true, nil
+	}
+
+	if value, ok := r.responseVariable(name); ok {
+		return value, true, nil
+	}
+
+	return r.executor.ResolveVariable(name)
+}
+
+// responseVariable returns the variables derived from the last response.
+func (r *runner) responseVariable(name string) (string, bool) {
+	switch {
+	case name == "result":
+		if r.lastResponse == nil {
+			return "", true
+		}
+		return string(r.lastResponse.Result()), true
+
+	case name == "stid":
+		if r.lastResponse == nil {
+			return "", true
+		}
+		return r.lastResponse.STID(), true
+
+	case name == "messages":
+		if r.lastResponse == nil {
+			return "", true
+		}
+		ids := make([]string, 0)
+		for _, messages := range [][]rri.BusinessMessage{r.lastResponse.InfoMessages(), r.lastResponse.WarningMessages(), r.lastResponse.ErrorMessages()} {
+			for _, msg := range messages {
+				ids = append(ids, strconv.FormatInt(msg.ID(), 10))
+			}
+		}
+		return strings.Join(ids, " "), true
+
+	case strings.HasPrefix(name, "response."):
+		if r.lastResponse == nil {
+			return "", true
+		}
+		values := r.lastResponse.LookupField(rri.ResponseFieldName(strings.TrimPrefix(name, "response.")))
+		return strings.Join(values, " "), true
+	}
+
+	return "", false
+}
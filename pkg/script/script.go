@@ -0,0 +1,269 @@
+// Package script implements a small line based scripting language on top of the interactive RRI command line.
+//
+// Every line is either a statement or a command line as entered in interactive mode:
+//
+//	# create a contact and a domain and verify the result
+//	create handle DENIC-${regacc}-QA
+//	assert ${result} == success
+//	set holder = ${response.handle}
+//	for domain in qa-1.de qa-2.de
+//	  info domain ${domain}
+//	  if ${result} == failure
+//	    echo ${domain} is free
+//	  else
+//	    assert ${response.holder} != ${holder}
+//	  end
+//	end
+//
+// Supported statements are set, if/else/end, for/end, assert, echo and query/end blocks for raw KV or XML queries.
+package script
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DENICeG/go-console/v2/commandline"
+	"github.com/DENICeG/go-rriclient/pkg/parser"
+)
+
+// Script is a parsed script ready to be run.
+type Script struct {
+	statements []statement
+}
+
+type statement interface {
+	lineNumber() int
+}
+
+type baseStatement struct {
+	line int
+}
+
+func (s baseStatement) lineNumber() int {
+	return s.line
+}
+
+// commandStatement executes a command line.
+type commandStatement struct {
+	baseStatement
+	args []string
+}
+
+// setStatement assigns the joined args to a variable.
+type setStatement struct {
+	baseStatement
+	name string
+	args []string
+}
+
+// ifStatement executes then if cond holds and otherwise else.
+type ifStatement struct {
+	baseStatement
+	cond      condition
+	then      []statement
+	otherwise []statement
+}
+
+// forStatement executes body once for every item with the variable name set to the item.
+type forStatement struct {
+	baseStatement
+	name  string
+	items []string
+	body  []statement
+}
+
+// assertStatement stops the script if cond does not hold.
+type assertStatement struct {
+	baseStatement
+	cond condition
+}
+
+// echoStatement prints the joined args.
+type echoStatement struct {
+	baseStatement
+	args []string
+}
+
+// queryStatement sends a raw query.
+type queryStatement struct {
+	baseStatement
+	query string
+}
+
+// condition compares two values with an operator. A condition without operator holds for every value except empty strings, false and 0.
+type condition struct {
+	negate   bool
+	lhs      string
+	operator string
+	rhs      string
+}
+
+var operators = []string{"==", "!=", "contains", "matches"}
+
+// Parse parses a script. Errors are reported with the line number.
+func Parse(src string) (*Script, error) {
+	p := &scriptParser{lines: strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")}
+
+	statements, terminator, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(terminator) > 0 {
+		return nil, fmt.Errorf("line %d: unexpected %s", p.index, terminator)
+	}
+
+	return &Script{statements}, nil
+}
+
+type scriptParser struct {
+	lines []string
+	// index is the number of consumed lines which is the line number of the last consumed line
+	index int
+}
+
+// parseBlock parses statements until the end of the script or an else or end line which is returned as terminator.
+func (p *scriptParser) parseBlock() ([]statement, string, error) {
+	statements := make([]statement, 0)
+
+	for p.index < len(p.lines) {
+		line := strings.TrimSpace(p.lines[p.index])
+		p.index++
+		lineNumber := p.index
+
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if line == "query" {
+			query, err := p.parseQuery()
+			if err != nil {
+				return nil, "", err
+			}
+			statements = append(statements, queryStatement{baseStatement{lineNumber}, query})
+			continue
+		}
+
+		args, isComplete := commandline.ParseCommand(line)
+		if !isComplete {
+			return nil, "", fmt.Errorf("line %d: unterminated quote or escape sequence", lineNumber)
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		base := baseStatement{lineNumber}
+		switch args[0] {
+		case "else", "end":
+			if len(args) > 1 {
+				return nil, "", fmt.Errorf("line %d: unexpected arguments after %s", lineNumber, args[0])
+			}
+			return statements, args[0], nil
+
+		case "set":
+			if len(args) < 3 || args[2] != "=" {
+				return nil, "", fmt.Errorf("line %d: expected set NAME = VALUE", lineNumber)
+			}
+			if !parser.IsValidVariableName(args[1]) {
+				return nil, "", fmt.Errorf("line %d: invalid variable name '%s'", lineNumber, args[1])
+			}
+			statements = append(statements, setStatement{base, args[1], args[3:]})
+
+		case "if":
+			cond, err := parseCondition(args[1:])
+			if err != nil {
+				return nil, "", fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			stmt := ifStatement{baseStatement: base, cond: cond}
+
+			var terminator string
+			stmt.then, terminator, err = p.parseBlock()
+			if err != nil {
+				return nil, "", err
+			}
+			if terminator == "else" {
+				stmt.otherwise, terminator, err = p.parseBlock()
+				if err != nil {
+					return nil, "", err
+				}
+			}
+			if terminator != "end" {
+				return nil, "", fmt.Errorf("line %d: missing end for if", lineNumber)
+			}
+			statements = append(statements, stmt)
+
+		case "for":
+			if len(args) < 3 || args[2] != "in" {
+				return nil, "", fmt.Errorf("line %d: expected for NAME in ITEMS", lineNumber)
+			}
+			if !parser.IsValidVariableName(args[1]) {
+				return nil, "", fmt.Errorf("line %d: invalid variable name '%s'", lineNumber, args[1])
+			}
+			body, terminator, err := p.parseBlock()
+			if err != nil {
+				return nil, "", err
+			}
+			if terminator != "end" {
+				return nil, "", fmt.Errorf("line %d: missing end for for", lineNumber)
+			}
+			statements = append(statements, forStatement{base, args[1], args[3:], body})
+
+		case "assert":
+			cond, err := parseCondition(args[1:])
+			if err != nil {
+				return nil, "", fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			statements = append(statements, assertStatement{base, cond})
+
+		case "echo":
+			statements = append(statements, echoStatement{base, args[1:]})
+
+		default:
+			statements = append(statements, commandStatement{base, args})
+		}
+	}
+
+	return statements, "", nil
+}
+
+// parseQuery consumes all lines until the next end line and returns them as raw query.
+func (p *scriptParser) parseQuery() (string, error) {
+	start := p.index
+	lines := make([]string, 0)
+
+	for p.index < len(p.lines) {
+		line := p.lines[p.index]
+		p.index++
+
+		if strings.TrimSpace(line) == "end" {
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+
+	return "", fmt.Errorf("line %d: missing end for query", start)
+}
+
+func parseCondition(args []string) (condition, error) {
+	var cond condition
+	if len(args) > 0 && args[0] == "not" {
+		cond.negate = true
+		args = args[1:]
+	}
+
+	switch len(args) {
+	case 1:
+		cond.lhs = args[0]
+		return cond, nil
+
+	case 3:
+		for _, operator := range operators {
+			if args[1] == operator {
+				cond.lhs, cond.operator, cond.rhs = args[0], args[1], args[2]
+				return cond, nil
+			}
+		}
+		return cond, fmt.Errorf("unknown operator '%s', use one of %s", args[1], strings.Join(operators, ", "))
+	}
+
+	return cond, fmt.Errorf("expected condition like VALUE or VALUE OPERATOR VALUE")
+}
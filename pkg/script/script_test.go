@@ -0,0 +1,132 @@
+package script_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DENICeG/go-rriclient/pkg/rri"
+	"github.com/DENICeG/go-rriclient/pkg/script"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExecutor answers info domain commands from a fixed set of registered domains.
+type fakeExecutor struct {
+	domains  map[string]string
+	commands []string
+	queries  []string
+}
+
+func (e *fakeExecutor) ExecCommand(cmd []string) (*rri.Response, error) {
+	e.commands = append(e.commands, strings.Join(cmd, " "))
+
+	switch {
+	case len(cmd) == 3 && cmd[0] == "info" && cmd[1] == "domain":
+		holder, ok := e.domains[cmd[2]]
+		if !ok {
+			return rri.NewResponseWithError(rri.ResultFailure, nil, rri.NewBusinessMessage(53000, "Domain not found")), nil
+		}
+		fields := rri.NewResponseFieldList()
+		fields.Add(rri.ResponseFieldNameSTID, "stid-"+cmd[2])
+		fields.Add("holder", holder)
+		fields.Add("nserver", "ns1.denic.de")
+		fields.Add("nserver", "ns2.denic.de")
+		return rri.NewResponse(rri.ResultSuccess, fields), nil
+
+	case cmd[0] == "verbose":
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unknown command %s", cmd[0])
+}
+
+func (e *fakeExecutor) SendQuery(query string) (*rri.Response, error) {
+	e.queries = append(e.queries, query)
+	return rri.NewResponse(rri.ResultSuccess, nil), nil
+}
+
+func (e *fakeExecutor) ResolveVariable(name string) (string, bool, error) {
+	if name == "regacc" {
+		return "1000022", true, nil
+	}
+	return "", false, nil
+}
+
+func run(t *testing.T, src string) (*fakeExecutor, string, script.Stats, error) {
+	t.Helper()
+
+	s, err := script.Parse(src)
+	require.NoError(t, err)
+
+	executor := &fakeExecutor{domains: map[string]string{"denic.de": "DENIC-1000022-HOLDER"}}
+	var out strings.Builder
+	stats, err := s.Run(executor, &out)
+	return executor, out.String(), stats, err
+}
+
+func TestRun(t *testing.T) {
+	executor, out, stats, err := run(t, `
+# check which domains are registered
+set free =
+for domain in denic.de, free.de
+  info domain ${domain}
+  verbose
+  if ${result} == success
+    assert ${response.holder} == DENIC-${regacc}-HOLDER
+    assert ${response.nserver} contains ns2.denic.de
+    assert ${stid} matches ^stid-
+  else
+    assert ${messages} contains 53000
+    set free = ${free} ${domain}
+  end
+end
+echo "free:${free}"
+
+query
+version: 5.0
+action: info
+domain: ${domain}
+end
+assert not ${result} != success`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"info domain denic.de", "verbose", "info domain free.de", "verbose"}, executor.commands)
+	assert.Equal(t, []string{"version: 5.0\naction: info\ndomain: free.de"}, executor.queries)
+	assert.Contains(t, out, "> info domain denic.de\n")
+	assert.Contains(t, out, "free: free.de\n")
+	assert.Equal(t, script.Stats{Commands: 5, Assertions: 5}, stats)
+}
+
+func TestRunAssertionFailed(t *testing.T) {
+	executor, _, stats, err := run(t, "info domain denic.de\nassert ${response.holder} == DENIC-1000022-OTHER\ninfo domain free.de")
+	assert.EqualError(t, err, `line 2: assertion failed: ${response.holder} ("DENIC-1000022-HOLDER") == DENIC-1000022-OTHER`)
+	assert.Len(t, executor.commands, 1)
+	assert.Equal(t, 1, stats.Assertions)
+
+	_, _, _, err = run(t, "unknown command")
+	assert.EqualError(t, err, "line 1: unknown command unknown")
+
+	_, _, _, err = run(t, "echo ${undefined}")
+	assert.EqualError(t, err, "line 1: undefined variables: undefined")
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"if ${result} == success\necho ok":   "line 1: missing end for if",
+		"for x in a b\necho ${x}\nelse\nend": "line 1: missing end for for",
+		"echo ok\nend":                       "line 2: unexpected end",
+		"set 1x = y":                         "line 1: invalid variable name '1x'",
+		"set x y":                            "line 1: expected set NAME = VALUE",
+		"assert a ~ b":                       "line 1: unknown operator '~', use one of ==, !=, contains, matches",
+		"assert":                             "line 1: expected condition like VALUE or VALUE OPERATOR VALUE",
+		"info domain 'denic.de":              "line 1: unterminated quote or escape sequence",
+		"query\nversion: 5.0":                "line 1: missing end for query",
+	}
+
+	for src, expectedErr := range tests {
+		_, err := script.Parse(src)
+		assert.EqualError(t, err, expectedErr, src)
+	}
+}